@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/intxlog/profiler/db"
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runCompare(args []string) {
+	flags := flag.NewFlagSet(`compare`, flag.ExitOnError)
+	sourceConnDBType := flags.String("sourceDBType", db.DB_CONN_POSTGRES, "Source database type")
+	sourceConnString := flags.String("sourceDB", "", "Source database connection string")
+	targetConnDBType := flags.String("targetDBType", db.DB_CONN_POSTGRES, "Target database type")
+	targetConnString := flags.String("targetDB", "", "Target database connection string")
+	compareDefinitionPath := flags.String("compareDefinition", "", "Path to compare definition JSON file")
+	format := flags.String("format", "table", "Output format, table or json")
+
+	flags.Parse(args)
+
+	sourceCon, err := db.GetDBConnByType(*sourceConnDBType, *sourceConnString)
+	if err != nil {
+		log.Fatal(fmt.Errorf(`error getting source database connection: %v`, err))
+	}
+
+	targetCon, err := db.GetDBConnByType(*targetConnDBType, *targetConnString)
+	if err != nil {
+		log.Fatal(fmt.Errorf(`error getting target database connection: %v`, err))
+	}
+
+	fileData, err := ioutil.ReadFile(*compareDefinitionPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var definition profiler.CompareDefinition
+	err = json.Unmarshal(fileData, &definition)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := profiler.CompareTables(sourceCon, targetCon, definition)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *format {
+	case `table`:
+		err = result.WriteTable(os.Stdout)
+	case `json`:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		err = encoder.Encode(result)
+	default:
+		err = fmt.Errorf(`unknown compare format %v, expected table or json`, *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if result.HasMismatches() {
+		os.Exit(1)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"database/sql"
+	"log/slog"
 )
 
 //Connection type for postgres db
@@ -14,6 +15,13 @@ type DBConn interface {
 	//Returns an active db connection
 	GetConnection() (*sql.DB, error)
 
+	//Sets the logger every SQL statement is debug logged to
+	SetLogger(logger *slog.Logger)
+
+	//Runs fn with a connection whose statements all run in one transaction, committed when fn returns nil
+	//and rolled back otherwise
+	RunInTransaction(fn func(txConn DBConn) error) error
+
 	//Select a single row with the provided selects
 	GetSelectSingle(tableName string, selects []string) (*sql.Rows, error)
 
@@ -38,9 +46,22 @@ type DBConn interface {
 	//Returns a map of column name to sql query string for a sprintf to profile
 	ProfilesByType(columnType string) map[string]string
 
+	//Returns the null and distinct count profiles in the same form as ProfilesByType, only gathered when
+	//the definition asks for them or anomaly detection or expectations need them
+	QualityProfilesByType(columnType string) map[string]string
+
+	//Returns extra profiles in the same form as ProfilesByType that are only gathered when comparing tables
+	ComparisonProfilesByType(columnType string) map[string]string
+
 	//Inserts a row into the table and returns the id of the new row
 	InsertRowAndReturnID(tableName string, values map[string]interface{}) int
 
+	//Inserts multiple rows using multi-row insert statements, each row holds a value for every column in order
+	InsertRows(tableName string, columns []string, rows [][]interface{}) error
+
+	//Bulk loads rows using the database's native copy mechanism, each row holds a value for every column in order
+	CopyRows(tableName string, columns []string, rows [][]interface{}) error
+
 	//Query table with provided where values
 	GetRows(tableName string, wheres map[string]interface{}) (*sql.Rows, error)
 
@@ -48,7 +69,44 @@ type DBConn interface {
 
 	GetRowsSelect(tableName string, selects []string) (*sql.Rows, error)
 
+	//Same as GetRowsSelectWhere with the where in columns also limited to one of their provided ids
+	GetRowsSelectWhereIn(tableName string, selects []string, wheres map[string]interface{}, whereIns map[string][]int) (*sql.Rows, error)
+
+	//Returns the query GetRowsSelect runs for the selects
+	GetSelectQueryString(tableName string, selects []string) string
+
 	GetTableRowCount(tableName string) (int, error)
+
+	//Counts the distinct combinations of values of the columns
+	CountDistinctRows(tableName string, columnNames []string) (int, error)
+
+	//Returns the unqualified names of tables in the schema starting with the prefix, matched case insensitively
+	//an empty schema name uses the current schema
+	GetTableNamesWithPrefix(schemaName string, prefix string) ([]string, error)
+
+	//Creates the schema if it does not already exist
+	CreateSchemaIfNotExists(schemaName string) error
+
+	//Counts the rows where the column value is in the provided values
+	CountRowsWhereIn(tableName string, columnName string, values []interface{}) (int, error)
+
+	//Deletes the rows where the column value is in the provided values and returns the number of rows deleted
+	DeleteRowsWhereIn(tableName string, columnName string, values []interface{}) (int, error)
+
+	//Renames an existing table, the new name is not schema qualified since the table stays in its schema
+	RenameTable(tableName string, newTableName string) error
+
+	//Renames a column on an existing table
+	RenameTableColumn(tableName string, columnName string, newColumnName string) error
+
+	//Returns the catalog definition of every column on the table in ordinal order
+	GetTableColumns(tableName string) ([]DBTableColumn, error)
+
+	//Returns the foreign keys declared on the table
+	GetForeignKeys(tableName string) ([]DBForeignKey, error)
+
+	//Counts the rows whose column values are all set but have no matching row in the referenced table
+	CountOrphanRows(tableName string, columnNames []string, referencedTableName string, referencedColumnNames []string) (int, error)
 }
 
 
@@ -57,6 +115,22 @@ type DBColumnDefinition struct {
 	ColumnType reflect.Type
 }
 
+//Foreign key declared on a table, the columns pair up in order with the referenced columns
+type DBForeignKey struct {
+	ConstraintName        string
+	ColumnNames           []string
+	ReferencedTableName   string
+	ReferencedColumnNames []string
+}
+
+//Column of an existing table as described by the database catalog
+type DBTableColumn struct {
+	ColumnName      string
+	DataType        string
+	IsNullable      bool
+	OrdinalPosition int
+}
+
 func GetDBConnByType(dbType string, dbConnString string) (DBConn, error){
 	if dbConnString == "" {
 		return nil, fmt.Errorf(`database connection string is required`)
@@ -5,23 +5,46 @@ import (
 	"reflect"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresConn struct {
 	dataSourceName string
 	conn           *sql.DB
+	logger         *slog.Logger
+	//set on the connection handed to RunInTransaction, every statement then runs in it
+	tx *sql.Tx
+}
+
+//The statement methods shared by a connection pool and a transaction
+type sqlExecutor interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
 }
 
 //Creates a new postgres connection object
 func NewPostgresConn(dataSourceName string) *PostgresConn {
 	return &PostgresConn{
 		dataSourceName: dataSourceName,
+		logger:         slog.Default(),
 	}
 }
 
+//Sets the logger statements are debug logged to, defaults to slog.Default
+func (p *PostgresConn) SetLogger(logger *slog.Logger) {
+	p.logger = logger
+}
+
+//Debug logs a statement before it is run
+func (p *PostgresConn) logQuery(query string) {
+	p.logger.Debug(`running sql`, `sql`, query)
+}
+
 //Connect to default database
 func (p *PostgresConn) GetConnection() (*sql.DB, error) {
 	if p.conn != nil {
@@ -33,46 +56,90 @@ func (p *PostgresConn) GetConnection() (*sql.DB, error) {
 	return p.conn, err
 }
 
+//Returns the transaction when in one, otherwise the connection pool
+func (p *PostgresConn) getExecutor() (sqlExecutor, error) {
+	if p.tx != nil {
+		return p.tx, nil
+	}
+	return p.GetConnection()
+}
+
+//Runs fn with a connection whose statements all run in one transaction, committed when fn returns nil
+//and rolled back otherwise. Calling it on a connection already in a transaction joins that transaction.
+func (p *PostgresConn) RunInTransaction(fn func(txConn DBConn) error) error {
+	if p.tx != nil {
+		return fn(p)
+	}
+
+	conn, err := p.GetConnection()
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	txConn := &PostgresConn{
+		dataSourceName: p.dataSourceName,
+		conn:           conn,
+		logger:         p.logger,
+		tx:             tx,
+	}
+
+	err = fn(txConn)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 func (p *PostgresConn) GetSelectSingle(tableName string, selects []string) (*sql.Rows, error) {
 	qry := fmt.Sprintf(`select %s from %s limit 1`, p.getConcatSelects(selects), tableName)
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return nil, err
 	}
 	
+	p.logQuery(qry)
 	return conn.Query(qry)
 }
 
 func (p *PostgresConn) GetSelectAllColumnsSingle(tableName string) (*sql.Rows, error) {
 	qry := fmt.Sprintf(`select * from %s limit 1`, tableName)
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return nil, err
 	}
 	
+	p.logQuery(qry)
 	return conn.Query(qry)
 }
 
 func (p *PostgresConn) DoesTableExist(tableName string) (bool, error) {
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return false, err
 	}
-	tableName = strings.ToLower(tableName)
+	//to_regclass returns null for missing tables and may drop the schema from the name
+	//when it is on the search path, so just check for null
 	query := fmt.Sprintf(`select to_regclass('%s')`, tableName)
+	p.logQuery(query)
 	row := conn.QueryRow(query)
 
-	var name string
+	var name sql.NullString
 	err = row.Scan(&name)
 	if err != nil {
 		return false, err
 	}
 
-	return name == tableName, nil
+	return name.Valid, nil
 }
 
 func (p *PostgresConn) CreateTable(tableName string, columns []DBColumnDefinition) error {
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return err
 	}
@@ -95,6 +162,7 @@ func (p *PostgresConn) CreateTable(tableName string, columns []DBColumnDefinitio
 
 	query = fmt.Sprintf(query, tableName, columnQuery)
 
+	p.logQuery(query)
 	_, err = conn.Exec(query)
 	return err
 }
@@ -107,13 +175,14 @@ func (p *PostgresConn) CreateTableIfNotExists(tableName string, columns []DBColu
 }
 
 func (p *PostgresConn) DoesTableColumnExist(tableName string, columnName string) (bool, error) {
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return false, err
 	}
 
 	query := fmt.Sprintf(`select %s from %s limit 1`, columnName, tableName)
 
+	p.logQuery(query)
 	row := conn.QueryRow(query)
 
 	var name interface{}
@@ -126,7 +195,7 @@ func (p *PostgresConn) DoesTableColumnExist(tableName string, columnName string)
 }
 
 func (p *PostgresConn) AddTableColumn(tableName string, column DBColumnDefinition) error {
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return err
 	}
@@ -139,6 +208,33 @@ func (p *PostgresConn) AddTableColumn(tableName string, column DBColumnDefinitio
 	query := `alter table %s add column %s %s;`
 	query = fmt.Sprintf(query, tableName, column.ColumnName, dataType)
 	
+	p.logQuery(query)
+	_, err = conn.Exec(query)
+	return err
+}
+
+func (p *PostgresConn) RenameTable(tableName string, newTableName string) error {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`alter table %s rename to %s;`, tableName, newTableName)
+
+	p.logQuery(query)
+	_, err = conn.Exec(query)
+	return err
+}
+
+func (p *PostgresConn) RenameTableColumn(tableName string, columnName string, newColumnName string) error {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`alter table %s rename column %s to %s;`, tableName, columnName, newColumnName)
+
+	p.logQuery(query)
 	_, err = conn.Exec(query)
 	return err
 }
@@ -164,6 +260,39 @@ func (p *PostgresConn) ProfilesByType(columnType string) map[string]string {
 	return profileColumns
 }
 
+func (p *PostgresConn) QualityProfilesByType(columnType string) map[string]string {
+	profileColumns := map[string]string{}
+	profileColumns["null_count"] = "count(*) - count(%s)"
+	switch columnType {
+	case `JSON`, `XML`:
+		//no equality operator so values can't be counted distinctly
+		break
+	default:
+		profileColumns["distinct_count"] = "count(distinct %s)"
+		break
+	}
+
+	return profileColumns
+}
+
+func (p *PostgresConn) ComparisonProfilesByType(columnType string) map[string]string {
+	profileColumns := map[string]string{}
+	switch columnType {
+	case `INT4`, `NUMERIC`, `INT2`, `INT8`:
+		profileColumns["distinct_count"] = "count(distinct %s)"
+		profileColumns["sum"] = "sum(%s)"
+		break
+	case `JSON`, `XML`:
+		//no equality operator so values can't be counted distinctly
+		break
+	default:
+		profileColumns["distinct_count"] = "count(distinct %s)"
+		break
+	}
+
+	return profileColumns
+}
+
 func (p *PostgresConn) InsertRowAndReturnID(tableName string, values map[string]interface{}) int {
 
 	insertColumns := []string{}
@@ -183,11 +312,12 @@ func (p *PostgresConn) InsertRowAndReturnID(tableName string, values map[string]
 		strings.Join(insertValuePlaceholders, `,`),
 	)
 
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		panic(err)
 	}
 
+	p.logQuery(insertQuery)
 	row := conn.QueryRow(insertQuery, insertValues...)
 	var newID int
 	err = row.Scan(&newID)
@@ -198,14 +328,116 @@ func (p *PostgresConn) InsertRowAndReturnID(tableName string, values map[string]
 	return newID
 }
 
+//Postgres allows at most this many bind parameters in a single statement
+const postgresMaxBindParameters = 65535
+
+func (p *PostgresConn) InsertRows(tableName string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	conn, err := p.getExecutor()
+	if err != nil {
+		return err
+	}
+
+	//split the rows into chunks so we stay under the bind parameter limit
+	rowsPerStatement := postgresMaxBindParameters / len(columns)
+	for start := 0; start < len(rows); start += rowsPerStatement {
+		end := start + rowsPerStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		valueGroups := []string{}
+		insertValues := []interface{}{}
+		idx := 1
+		for _, row := range rows[start:end] {
+			if len(row) != len(columns) {
+				return fmt.Errorf(`row has %d values but %d columns were provided for table %s`, len(row), len(columns), tableName)
+			}
+			placeholders := []string{}
+			for _, val := range row {
+				placeholders = append(placeholders, fmt.Sprintf(`$%d`, idx))
+				insertValues = append(insertValues, val)
+				idx = idx + 1
+			}
+			valueGroups = append(valueGroups, fmt.Sprintf(`(%s)`, strings.Join(placeholders, `,`)))
+		}
+
+		insertQuery := fmt.Sprintf(`insert into %s (%s) values %s`,
+			tableName,
+			strings.Join(columns, `,`),
+			strings.Join(valueGroups, `,`),
+		)
+
+		p.logQuery(insertQuery)
+		_, err = conn.Exec(insertQuery, insertValues...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PostgresConn) CopyRows(tableName string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	//copy has to run in a transaction, joining the current one if there is one
+	return p.RunInTransaction(func(txConn DBConn) error {
+		return txConn.(*PostgresConn).copyRows(tableName, columns, rows)
+	})
+}
+
+func (p *PostgresConn) copyRows(tableName string, columns []string, rows [][]interface{}) error {
+	//not using pq.CopyIn here since it quotes identifiers and our tables are created unquoted
+	copyQuery := fmt.Sprintf(`copy %s (%s) from stdin`, tableName, strings.Join(columns, `,`))
+	p.logQuery(copyQuery)
+	stmt, err := p.tx.Prepare(copyQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf(`row has %d values but %d columns were provided for table %s`, len(row), len(columns), tableName)
+		}
+		copyValues := make([]interface{}, len(row))
+		for idx, val := range row {
+			//copy encodes byte slices as bytea, numerics come back from the driver as bytes so send them as text
+			if bytes, ok := val.([]byte); ok {
+				val = string(bytes)
+			}
+			copyValues[idx] = val
+		}
+		_, err = stmt.Exec(copyValues...)
+		if err != nil {
+			return err
+		}
+	}
+
+	//flush the copy buffer
+	_, err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+
+	return stmt.Close()
+}
+
 func (p *PostgresConn) GetRowsSelect(tableName string, selects []string) (*sql.Rows, error) {
-	query := p.getSelectQueryString(tableName, selects)
+	query := p.GetSelectQueryString(tableName, selects)
 
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		panic(err)
 	}
 	
+	p.logQuery(query)
 	return conn.Query(query)
 }
 
@@ -219,7 +451,7 @@ func (p *PostgresConn) GetRowsSelectWhere(tableName string, selects []string, wh
 		idx = idx + 1
 	}
 
-	query := p.getSelectQueryString(tableName, selects)
+	query := p.GetSelectQueryString(tableName, selects)
 
 	//if we have where claues then add them to our query
 	if len(whereClauses) > 0 {
@@ -229,11 +461,46 @@ func (p *PostgresConn) GetRowsSelectWhere(tableName string, selects []string, wh
 		)
 	}
 
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		panic(err)
 	}
 	
+	p.logQuery(query)
+	return conn.Query(query, whereValues...)
+}
+
+func (p *PostgresConn) GetRowsSelectWhereIn(tableName string, selects []string, wheres map[string]interface{}, whereIns map[string][]int) (*sql.Rows, error) {
+	whereClauses := []string{}
+	whereValues := []interface{}{}
+	for col, val := range wheres {
+		whereValues = append(whereValues, val)
+		whereClauses = append(whereClauses, fmt.Sprintf(`%s=$%d`, col, len(whereValues)))
+	}
+	//ids go in as a single array so there is no limit on how many there are
+	for col, ids := range whereIns {
+		arrayIDs := make([]int64, len(ids))
+		for idx, id := range ids {
+			arrayIDs[idx] = int64(id)
+		}
+		whereValues = append(whereValues, pq.Array(arrayIDs))
+		whereClauses = append(whereClauses, fmt.Sprintf(`%s = any($%d)`, col, len(whereValues)))
+	}
+
+	query := p.GetSelectQueryString(tableName, selects)
+	if len(whereClauses) > 0 {
+		query = fmt.Sprintf(`%s where %s`,
+			query,
+			strings.Join(whereClauses, ` AND `),
+		)
+	}
+
+	conn, err := p.getExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	p.logQuery(query)
 	return conn.Query(query, whereValues...)
 }
 
@@ -241,7 +508,7 @@ func (p *PostgresConn) getConcatSelects(selects []string) string {
 	return strings.Join(selects, `,`)
 }
 
-func (p *PostgresConn) getSelectQueryString(tableName string, selects []string) string {
+func (p *PostgresConn) GetSelectQueryString(tableName string, selects []string) string {
 	return fmt.Sprintf(`select %s from %s`,
 		p.getConcatSelects(selects),
 		tableName,
@@ -267,16 +534,242 @@ func (p *PostgresConn) GetTableRowCount(tableName string) (int, error) {
 	return count, err
 }
 
+func (p *PostgresConn) CountDistinctRows(tableName string, columnNames []string) (int, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return 0, err
+	}
+
+	quotedColumnNames := []string{}
+	for _, columnName := range columnNames {
+		quotedColumnNames = append(quotedColumnNames, fmt.Sprintf(`"%s"`, columnName))
+	}
+
+	query := fmt.Sprintf(`select count(*) from (select distinct %s from %s) distinct_rows`, p.getConcatSelects(quotedColumnNames), tableName)
+
+	var count int
+	p.logQuery(query)
+	err = conn.QueryRow(query).Scan(&count)
+	return count, err
+}
+
+func (p *PostgresConn) GetTableNamesWithPrefix(schemaName string, prefix string) ([]string, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select table_name from information_schema.tables where table_schema = coalesce(nullif($1, ''), current_schema())`
+	p.logQuery(query)
+	rows, err := conn.Query(query, strings.ToLower(schemaName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	//unquoted identifiers are folded to lower case so compare that way
+	prefix = strings.ToLower(prefix)
+	tableNames := []string{}
+	for rows.Next() {
+		var tableName string
+		err = rows.Scan(&tableName)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(strings.ToLower(tableName), prefix) {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+
+	return tableNames, rows.Err()
+}
+
+func (p *PostgresConn) GetTableColumns(tableName string) ([]DBTableColumn, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	//regclass resolves the name the same way the profile queries do, including the search path
+	query := `select a.attname, format_type(a.atttypid, a.atttypmod), not a.attnotnull, a.attnum
+		from pg_attribute a
+		where a.attrelid = $1::regclass and a.attnum > 0 and not a.attisdropped
+		order by a.attnum`
+	p.logQuery(query)
+	rows, err := conn.Query(query, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []DBTableColumn{}
+	for rows.Next() {
+		column := DBTableColumn{}
+		err = rows.Scan(&column.ColumnName, &column.DataType, &column.IsNullable, &column.OrdinalPosition)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func (p *PostgresConn) GetForeignKeys(tableName string) ([]DBForeignKey, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `select con.conname, con.confrelid::regclass::text,
+			array(select a.attname from unnest(con.conkey) with ordinality k(attnum, ord)
+				join pg_attribute a on a.attrelid = con.conrelid and a.attnum = k.attnum order by k.ord),
+			array(select a.attname from unnest(con.confkey) with ordinality k(attnum, ord)
+				join pg_attribute a on a.attrelid = con.confrelid and a.attnum = k.attnum order by k.ord)
+		from pg_constraint con
+		where con.contype = 'f' and con.conrelid = $1::regclass
+		order by con.conname`
+	p.logQuery(query)
+	rows, err := conn.Query(query, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	foreignKeys := []DBForeignKey{}
+	for rows.Next() {
+		foreignKey := DBForeignKey{}
+		err = rows.Scan(&foreignKey.ConstraintName, &foreignKey.ReferencedTableName, pq.Array(&foreignKey.ColumnNames), pq.Array(&foreignKey.ReferencedColumnNames))
+		if err != nil {
+			return nil, err
+		}
+		foreignKeys = append(foreignKeys, foreignKey)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func (p *PostgresConn) CountOrphanRows(tableName string, columnNames []string, referencedTableName string, referencedColumnNames []string) (int, error) {
+	if len(columnNames) == 0 || len(columnNames) != len(referencedColumnNames) {
+		return 0, fmt.Errorf(`relationship from %s to %s needs the same number of columns on each side`, tableName, referencedTableName)
+	}
+
+	conn, err := p.getExecutor()
+	if err != nil {
+		return 0, err
+	}
+
+	//rows with a null in the key are not orphans, same as a foreign key with match simple
+	notNulls := []string{}
+	joins := []string{}
+	for idx, columnName := range columnNames {
+		notNulls = append(notNulls, fmt.Sprintf(`child."%s" is not null`, columnName))
+		joins = append(joins, fmt.Sprintf(`parent."%s" = child."%s"`, referencedColumnNames[idx], columnName))
+	}
+
+	query := fmt.Sprintf(`select count(*) from %s child where %s and not exists (select 1 from %s parent where %s)`,
+		tableName,
+		strings.Join(notNulls, ` and `),
+		referencedTableName,
+		strings.Join(joins, ` and `),
+	)
+
+	var count int
+	p.logQuery(query)
+	err = conn.QueryRow(query).Scan(&count)
+	return count, err
+}
+
+func (p *PostgresConn) CreateSchemaIfNotExists(schemaName string) error {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`create schema if not exists %s;`, schemaName)
+	p.logQuery(query)
+	_, err = conn.Exec(query)
+	return err
+}
+
+func (p *PostgresConn) CountRowsWhereIn(tableName string, columnName string, values []interface{}) (int, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, chunk := range p.chunkValues(values) {
+		query := fmt.Sprintf(`select count(*) from %s where %s in (%s)`, tableName, columnName, p.getPlaceholders(len(chunk)))
+
+		var count int
+		p.logQuery(query)
+		err = conn.QueryRow(query, chunk...).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+		total = total + count
+	}
+
+	return total, nil
+}
+
+func (p *PostgresConn) DeleteRowsWhereIn(tableName string, columnName string, values []interface{}) (int, error) {
+	conn, err := p.getExecutor()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, chunk := range p.chunkValues(values) {
+		query := fmt.Sprintf(`delete from %s where %s in (%s)`, tableName, columnName, p.getPlaceholders(len(chunk)))
+
+		p.logQuery(query)
+		result, err := conn.Exec(query, chunk...)
+		if err != nil {
+			return 0, err
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total = total + int(deleted)
+	}
+
+	return total, nil
+}
+
+//Splits the values into chunks that fit under the bind parameter limit
+func (p *PostgresConn) chunkValues(values []interface{}) [][]interface{} {
+	chunks := [][]interface{}{}
+	for start := 0; start < len(values); start += postgresMaxBindParameters {
+		end := start + postgresMaxBindParameters
+		if end > len(values) {
+			end = len(values)
+		}
+		chunks = append(chunks, values[start:end])
+	}
+	return chunks
+}
+
+//Returns a comma separated list of $n placeholders
+func (p *PostgresConn) getPlaceholders(count int) string {
+	placeholders := []string{}
+	for idx := 1; idx <= count; idx++ {
+		placeholders = append(placeholders, fmt.Sprintf(`$%d`, idx))
+	}
+	return strings.Join(placeholders, `,`)
+}
+
 func (p *PostgresConn) dbExists(dbName string) (bool, error) {
-	conn, err := p.GetConnection()
+	conn, err := p.getExecutor()
 	if err != nil {
 		return false, err
 	}
 
-	row := conn.QueryRow(
-		`select datname from pg_catalog.pg_database where datname = $1;`,
-		dbName,
-	)
+	query := `select datname from pg_catalog.pg_database where datname = $1;`
+	p.logQuery(query)
+	row := conn.QueryRow(query, dbName)
 
 	var name string
 	err = row.Scan(&name)
@@ -294,8 +787,15 @@ func (p *PostgresConn) convertTypeToSQLType(dataType reflect.Type) (string, erro
 	switch dataType.Kind(){
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return `int`, nil
+	case reflect.Bool:
+		return `boolean`, nil
+	case reflect.Float32, reflect.Float64:
+		return `double precision`, nil
 	case reflect.String:
 		return `text`, nil
+	case reflect.Ptr:
+		//pointers are used for nullable values so use the type they point to
+		return p.convertTypeToSQLType(dataType.Elem())
 	case reflect.Struct:
 		if isSameStructType(dataType, time.Time{}) {
 			return `timestamptz`, nil
@@ -306,7 +806,7 @@ func (p *PostgresConn) convertTypeToSQLType(dataType reflect.Type) (string, erro
 			return `numeric`, nil
 		}	
 	default:
-		fmt.Printf("\nunable to find a sql type for %v", dataType.Kind())
+		p.logger.Debug(`unable to find a sql type`, `kind`, dataType.Kind().String())
 	}
 	return ``, fmt.Errorf(`no defined sql type for reflect type of %v`, dataType)
 }
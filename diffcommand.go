@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func runDiff(args []string) {
+	flags := flag.NewFlagSet(`diff`, flag.ExitOnError)
+	store := addStoreFlags(flags)
+
+	fromID := flags.Int("from", 0, "profile_record_id of the earlier run")
+	toID := flags.Int("to", 0, "profile_record_id of the later run")
+	format := flags.String("format", "table", "Output format, table, json or markdown")
+
+	flags.Parse(args)
+
+	if *fromID == 0 || *toID == 0 {
+		log.Fatal(fmt.Errorf(`both the from and to profile record ids are required`))
+	}
+
+	profileStore, err := store.getProfileStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	diff, err := profileStore.DiffProfiles(*fromID, *toID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *format {
+	case `table`:
+		err = diff.WriteTable(os.Stdout)
+	case `json`:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		err = encoder.Encode(diff)
+	case `markdown`:
+		err = diff.WriteMarkdown(os.Stdout)
+	default:
+		err = fmt.Errorf(`unknown diff format %v, expected table, json or markdown`, *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
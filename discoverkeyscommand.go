@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/intxlog/profiler/db"
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runDiscoverKeys(args []string) {
+	flags := flag.NewFlagSet(`discover-keys`, flag.ExitOnError)
+	targetConnDBType := flags.String("targetDBType", db.DB_CONN_POSTGRES, "Target database type")
+	targetConnString := flags.String("targetDB", "", "Target database connection string")
+	store := addStoreFlags(flags)
+
+	tables := flags.String("tables", "", "Comma separated list of tables to search for candidate keys")
+	maxKeyColumns := flags.Int("maxKeyColumns", profiler.DEFAULT_MAX_KEY_COLUMNS, "Most columns in a composite candidate key")
+	maxCombinations := flags.Int("maxCombinations", profiler.DEFAULT_MAX_KEY_COMBINATIONS, "Most composite column combinations to query per table")
+	format := flags.String("format", "table", "Output format, table or json")
+
+	flags.Parse(args)
+
+	tableNames := []string{}
+	for _, tableName := range strings.Split(*tables, `,`) {
+		if strings.TrimSpace(tableName) != `` {
+			tableNames = append(tableNames, strings.TrimSpace(tableName))
+		}
+	}
+	if len(tableNames) == 0 {
+		log.Fatal(fmt.Errorf(`at least one table is required`))
+	}
+
+	targetCon, err := db.GetDBConnByType(*targetConnDBType, *targetConnString)
+	if err != nil {
+		log.Fatal(fmt.Errorf(`error getting target database connection: %v`, err))
+	}
+
+	profileCon, err := store.getProfileDBConn()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p := profiler.NewProfilerWithOptions(targetCon, profileCon, store.getProfilerOptions())
+	result, err := p.DiscoverCandidateKeys(tableNames, profiler.KeyDiscoveryOptions{
+		MaxKeyColumns:   *maxKeyColumns,
+		MaxCombinations: *maxCombinations,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *format {
+	case `table`:
+		for _, table := range result.Tables {
+			fmt.Printf("%s (%d rows, %d combinations tried)\n", table.TableName, table.RowCount, table.CombinationsTried)
+			if len(table.CandidateKeys) == 0 {
+				fmt.Println("  no candidate keys found")
+			}
+			for _, key := range table.CandidateKeys {
+				fmt.Printf("  (%s)\n", strings.Join(key, `, `))
+			}
+			if table.LimitReached {
+				fmt.Println("  combination limit reached, larger keys may exist")
+			}
+		}
+	case `json`:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		err = encoder.Encode(result)
+	default:
+		err = fmt.Errorf(`unknown discover-keys format %v, expected table or json`, *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
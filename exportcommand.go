@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet(`export`, flag.ExitOnError)
+	store := addStoreFlags(flags)
+
+	outputFile := flags.String("outputFile", "", "Path to write the long format CSV to, defaults to stdout")
+	outputDir := flags.String("outputDir", "", "Directory to write one CSV per table to instead of a single long format file")
+	from := flags.String("from", "", "Only include runs on or after this date, such as 2020-01-31 or an RFC 3339 time")
+	to := flags.String("to", "", "Only include runs on or before this date, such as 2020-01-31 or an RFC 3339 time")
+	since := flags.String("since", "", "Only include runs newer than this duration, such as 720h or 30d")
+	tables := flags.String("tables", "", "Comma separated list of tables to include, defaults to every table")
+	metrics := flags.String("metrics", "", "Comma separated list of metrics to include, such as row_count,null_count, defaults to every metric")
+
+	flags.Parse(args)
+
+	options := profiler.ExportOptions{
+		Tables:  splitList(*tables),
+		Metrics: splitList(*metrics),
+	}
+
+	if *since != `` && *from != `` {
+		log.Fatal(fmt.Errorf(`-since and -from both set the start of the export, use one of them`))
+	}
+
+	var err error
+	if *from != `` {
+		options.Since, err = parseExportDate(*from)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *since != `` {
+		duration, err := profiler.ParseDuration(*since)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Since = time.Now().Add(-duration)
+	}
+	if *to != `` {
+		options.Until, err = parseExportDate(*to)
+		if err != nil {
+			log.Fatal(err)
+		}
+		//a plain date includes the whole day
+		if !strings.Contains(*to, `T`) {
+			options.Until = options.Until.AddDate(0, 0, 1)
+		}
+	}
+
+	profileStore, err := store.getProfileStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := profileStore.ExportHistory(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *outputDir != `` {
+		tableRows := profiler.GroupExportRowsByTable(rows)
+		for tableName, rows := range tableRows {
+			path := filepath.Join(*outputDir, strings.Replace(tableName, string(filepath.Separator), `_`, -1)+`.csv`)
+			err = writeExportFile(path, func(file *os.File) error {
+				return profiler.WriteTableExportCSV(file, rows)
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("Exported %d metric values of %d tables to %s\n", len(rows), len(tableRows), *outputDir)
+		return
+	}
+
+	if *outputFile == `` {
+		err = profiler.WriteExportCSV(os.Stdout, rows)
+	} else {
+		err = writeExportFile(*outputFile, func(file *os.File) error {
+			return profiler.WriteExportCSV(file, rows)
+		})
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+//Parses a date as 2006-01-02 in local time or as an RFC 3339 time
+func parseExportDate(value string) (time.Time, error) {
+	date, err := time.ParseInLocation(`2006-01-02`, value, time.Local)
+	if err == nil {
+		return date, nil
+	}
+	date, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return date, fmt.Errorf(`invalid date %s, expected 2006-01-02 or an RFC 3339 time`, value)
+	}
+	return date, nil
+}
+
+//Splits a comma separated flag value, ignoring empty entries
+func splitList(value string) []string {
+	values := []string{}
+	for _, entry := range strings.Split(value, `,`) {
+		if strings.TrimSpace(entry) != `` {
+			values = append(values, strings.TrimSpace(entry))
+		}
+	}
+	return values
+}
+
+func writeExportFile(path string, write func(file *os.File) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return write(file)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+//Flags for the format and level of log output
+type loggingFlags struct {
+	logFormat *string
+	logLevel  *string
+}
+
+func addLoggingFlags(flags *flag.FlagSet) *loggingFlags {
+	return &loggingFlags{
+		logFormat: flags.String("log-format", "text", "Log output format, text or json"),
+		logLevel:  flags.String("log-level", "info", "Lowest level to log, debug, info, warn or error. Debug logs every SQL statement"),
+	}
+}
+
+//Builds the logger and makes it the default so the standard log package writes through it too
+func (l *loggingFlags) setupLogger() (*slog.Logger, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(*l.logLevel)))
+	if err != nil {
+		return nil, fmt.Errorf(`unknown log level %v, expected debug, info, warn or error`, *l.logLevel)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch *l.logFormat {
+	case `text`:
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case `json`:
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return nil, fmt.Errorf(`unknown log format %v, expected text or json`, *l.logFormat)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger, nil
+}
@@ -6,69 +6,380 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/intxlog/profiler/db"
+	"github.com/intxlog/profiler/notifier"
 	"github.com/intxlog/profiler/profiler"
 )
 
 func main() {
-	run()
+	//the profile command is the default so existing flag only usage keeps working
+	command := `profile`
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], `-`) {
+		command = args[0]
+		args = args[1:]
+	}
+
+	switch command {
+	case `profile`:
+		run(args)
+	case `prune`:
+		runPrune(args)
+	case `migrate-naming`:
+		runMigrateNaming(args)
+	case `diff`:
+		runDiff(args)
+	case `compare`:
+		runCompare(args)
+	case `discover-keys`:
+		runDiscoverKeys(args)
+	case `report`:
+		runReport(args)
+	case `serve`:
+		runServe(args)
+	case `export`:
+		runExport(args)
+	default:
+		log.Fatal(fmt.Errorf(`unknown command %v, expected one of profile, prune, migrate-naming, diff, compare, discover-keys, report, serve, export`, command))
+	}
 }
 
-func run() {
-	log.Println("Preparing profiler")
-	targetConnDBType := flag.String("targetDBType", db.DB_CONN_POSTGRES, "Target database type")
-	targetConnString := flag.String("targetDB", "", "Target database connection string")
+func run(args []string) {
+	flags := flag.NewFlagSet(`profile`, flag.ExitOnError)
+	targetConnDBType := flags.String("targetDBType", db.DB_CONN_POSTGRES, "Target database type")
+	targetConnString := flags.String("targetDB", "", "Target database connection string")
 
-	profileConnDBType := flag.String("profileDBType", db.DB_CONN_POSTGRES, "Profile database type")
-	profileConnString := flag.String("profileDB", "", "Profile store database connection string")
+	store := addStoreFlags(flags)
+	tracing := addTracingFlags(flags)
+	logging := addLoggingFlags(flags)
 
-	profileDefinitionPath := flag.String("profileDefinition", "", "Path to profile definition JSON file")
+	profileDefinitionPath := flags.String("profileDefinition", "", "Path to profile definition JSON file")
 
-	usePascalCase := flag.Bool("usePascalCase", false, "Use pascal case for table and column naming in profile database")
+	insertBatchSize := flags.Int("insertBatchSize", 0, "Number of profile rows to buffer per table before writing them in one statement, 0 writes each row individually")
+	useCopy := flags.Bool("useCopy", false, "Write buffered profile rows using copy instead of multi-row inserts")
 
-	flag.Parse()
+	writeBaselinePath := flags.String("writeBaseline", "", "Path to write the run's results to as a baseline JSON file")
+	checkBaselinePath := flags.String("checkBaseline", "", "Path to a baseline JSON file to check the run's results against")
+	baselineTolerancesPath := flags.String("baselineTolerances", "", "Path to a JSON file of tolerances for the baseline check")
+
+	output := flags.String("output", "", "Write the run's results in this format, json, defaults to json when there is no profile database")
+	outputFile := flags.String("outputFile", "", "Path to write the run's results to, defaults to stdout")
+	markdownFile := flags.String("markdownFile", "", "Path to write a Markdown summary of the run to, compared with the previous run when there is a profile database")
+	promFile := flags.String("promFile", "", "Path to write the run's metrics to as a node_exporter textfile collector .prom file")
+	influxFile := flags.String("influxFile", "", "Path to write the run's metrics to in the InfluxDB line protocol")
+	junitFile := flags.String("junitFile", "", "Path to write a JUnit XML report of the run's checks to, for CI systems")
+	notifierConfigPath := flags.String("notifierConfig", "", "Path to a notifier config JSON file of webhooks to post run failures, failed checks and anomalies to")
+
+	flags.Parse(args)
+
+	logger, err := logging.setupLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Preparing profiler")
 
 	targetCon, err := db.GetDBConnByType(*targetConnDBType, *targetConnString)
 	if err != nil {
 		log.Fatal(fmt.Errorf(`error getting target database connection: %v`, err))
 	}
 
-	profileCon, err := db.GetDBConnByType(*profileConnDBType, *profileConnString)
+	//without a profile database the results are only written out
+	profileCon, err := store.getOptionalProfileDBConn()
 	if err != nil {
-		log.Fatal(fmt.Errorf(`error getting profile database connection: %v`, err))
+		log.Fatal(err)
+	}
+	if profileCon == nil && *output == `` {
+		*output = `json`
 	}
 
-	options := profiler.ProfilerOptions{
-		UsePascalCase: *usePascalCase,
+	if *output != `` && *output != `json` {
+		log.Fatal(fmt.Errorf(`unknown output format %v, expected json`, *output))
 	}
 
-	//Read in the profile definition file
-	fileData, err := ioutil.ReadFile(*profileDefinitionPath)
+	options := store.getProfilerOptions()
+	options.InsertBatchSize = *insertBatchSize
+	options.UseCopy = *useCopy
+	options.Logger = logger
+
+	tracerProvider, shutdownTracing, err := tracing.getTracerProvider()
 	if err != nil {
 		log.Fatal(err)
 	}
+	if tracerProvider != nil {
+		options.TracerProvider = tracerProvider
+	}
 
-	var profile profiler.ProfileDefinition
-	err = json.Unmarshal(fileData, &profile)
+	profile, err := readProfileDefinition(*profileDefinitionPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	runNotifier, err := loadNotifier(*notifierConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if profileCon == nil && profile.AnomalyDetection != nil {
+		log.Println("Anomaly detection needs the history in a profile database, skipping it")
+	}
+
+	//load the baseline up front so a bad file fails before profiling
+	var baseline *profiler.Baseline
+	baselineOptions := profiler.BaselineCheckOptions{}
+	if *checkBaselinePath != `` {
+		baseline, err = profiler.LoadBaseline(*checkBaselinePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *baselineTolerancesPath != `` {
+		tolerancesData, err := ioutil.ReadFile(*baselineTolerancesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = json.Unmarshal(tolerancesData, &baselineOptions)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Printf("Starting profile...\n")
 	start := time.Now()
 
 	p := profiler.NewProfilerWithOptions(targetCon, profileCon, options)
 
-	err = p.RunProfile(profile)
+	result, err := p.RunProfileWithResults(profile)
+
+	//flush the spans now since a failed run exits without running deferred calls
+	shutdownTracing()
+
+	//the textfile is written on failure too so the failed run shows up in Prometheus
+	if *promFile != `` {
+		promErr := profiler.WritePrometheusTextfile(*promFile, result, err)
+		if promErr != nil {
+			log.Println(promErr)
+		}
+	}
+
+	//the JUnit report is written on failure too so CI shows the error
+	if *junitFile != `` {
+		junitErr := writeRunJUnit(result, err, *junitFile)
+		if junitErr != nil {
+			log.Println(junitErr)
+		}
+	}
 
 	if err != nil {
+		notifyRun(runNotifier, result, err)
 		log.Fatal(err)
 	} else {
 		log.Println("Success")
 	}
 
+	//notify before the sinks below since any of them can exit on an error
+	notifyRun(runNotifier, result, nil)
+
+	for _, anomaly := range result.Anomalies {
+		log.Printf("Anomaly in %s: %v is outside the expected %v\n", formatAnomalyMetric(anomaly), anomaly.Value, anomaly.ExpectedValue)
+	}
+
+	for _, change := range result.SchemaChanges {
+		log.Printf("Schema change in %s: %s\n", change.TableName, formatSchemaChange(change))
+	}
+
+	for _, relationship := range result.Relationships {
+		if relationship.OrphanCount > 0 {
+			log.Printf("Orphan rows in %s: %d rows of (%s) have no match in %s (%s)\n",
+				relationship.TableName,
+				relationship.OrphanCount,
+				strings.Join(relationship.Columns, `, `),
+				relationship.ReferencedTable,
+				strings.Join(relationship.ReferencedColumns, `, `),
+			)
+		}
+	}
+
+	staleTables := result.StaleTables()
+	for _, stale := range staleTables {
+		log.Printf("Stale table %s: %s\n", stale.TableName, formatStaleness(stale))
+	}
+
+	failedExpectations := result.FailedExpectations()
+	for _, failed := range failedExpectations {
+		log.Printf("Failed expectation on %s: %s (%s)\n", failed.TableName, failed.Expectation, failed.Message)
+	}
+
+	if *writeBaselinePath != `` {
+		err = profiler.NewBaseline(result).Save(*writeBaselinePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Baseline written to %s\n", *writeBaselinePath)
+	}
+
+	baselinePassed := true
+	if baseline != nil {
+		baselineCheck := baseline.Check(result, baselineOptions)
+		baselinePassed = baselineCheck.Passed()
+		if !baselinePassed {
+			log.Printf("Run does not match the baseline %s\n", *checkBaselinePath)
+			baselineCheck.WriteTable(os.Stderr)
+		}
+	}
+
+	if *output == `json` {
+		err = writeRunResultJSON(result, *outputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *influxFile != `` {
+		err = profiler.WriteInfluxFile(*influxFile, result)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *markdownFile != `` {
+		err = writeRunMarkdown(p, result, *markdownFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	end := time.Now()
 	log.Printf("Finished... time taken: %v\n", end.Sub(start))
+
+	if len(failedExpectations) > 0 {
+		log.Printf("%d of %d expectations failed\n", len(failedExpectations), len(result.ExpectationResults))
+	}
+	if len(failedExpectations) > 0 || len(staleTables) > 0 || !baselinePassed {
+		os.Exit(1)
+	}
+}
+
+func formatStaleness(stale profiler.FreshnessResult) string {
+	maxStaleness := time.Duration(*stale.MaxStalenessSeconds * float64(time.Second))
+	if stale.LagSeconds == nil {
+		return fmt.Sprintf(`no rows with a %s value, allowed staleness is %v`, stale.ColumnName, maxStaleness)
+	}
+	lag := time.Duration(*stale.LagSeconds * float64(time.Second)).Round(time.Second)
+	return fmt.Sprintf(`newest %s is %v old, allowed staleness is %v`, stale.ColumnName, lag, maxStaleness)
+}
+
+//Builds the notifier from its config file, returns nil when no path is given
+func loadNotifier(path string) (notifier.Notifier, error) {
+	if path == `` {
+		return nil, nil
+	}
+
+	config, err := notifier.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return notifier.NewNotifier(config)
+}
+
+//Sends the run's problems, if it had any, a failed notification is logged so it doesn't hide the run's outcome
+func notifyRun(runNotifier notifier.Notifier, result *profiler.RunResult, runErr error) {
+	if runNotifier == nil {
+		return
+	}
+
+	event, ok := notifier.NewRunEvent(result, runErr)
+	if !ok {
+		return
+	}
+
+	err := runNotifier.Notify(event)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+//Reads in the profile definition file
+func readProfileDefinition(path string) (profiler.ProfileDefinition, error) {
+	var profile profiler.ProfileDefinition
+
+	fileData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return profile, err
+	}
+
+	err = json.Unmarshal(fileData, &profile)
+	return profile, err
+}
+
+//Writes the run results as indented JSON to the file, or stdout when no path is given
+func writeRunResultJSON(result *profiler.RunResult, path string) error {
+	if path == `` {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent(``, `  `)
+		return encoder.Encode(result)
+	}
+
+	fileData, err := json.MarshalIndent(result, ``, `  `)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, fileData, 0644)
+}
+
+//Writes the Markdown summary of the run, diffed against the previous run when there is a profile store
+func writeRunMarkdown(p *profiler.Profiler, result *profiler.RunResult, path string) error {
+	var diff *profiler.ProfileDiff
+	if profileStore := p.GetProfileStore(); profileStore != nil {
+		var err error
+		diff, err = profileStore.DiffWithPreviousRun(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return profiler.WriteRunMarkdown(file, result, diff)
+}
+
+//Writes the JUnit XML report of the run, the run may be nil when it failed before starting
+func writeRunJUnit(result *profiler.RunResult, runErr error, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return profiler.WriteJUnit(file, result, runErr)
+}
+
+//Returns table.column.metric for column metrics and table.metric for table metrics
+func formatAnomalyMetric(anomaly profiler.Anomaly) string {
+	if anomaly.ColumnName == `` {
+		return fmt.Sprintf(`%s.%s`, anomaly.TableName, anomaly.MetricName)
+	}
+	return fmt.Sprintf(`%s.%s.%s`, anomaly.TableName, anomaly.ColumnName, anomaly.MetricName)
+}
+
+func formatSchemaChange(change profiler.SchemaChange) string {
+	switch change.ChangeType {
+	case profiler.SCHEMA_CHANGE_ADDED:
+		return fmt.Sprintf(`column %s %s added`, change.ColumnName, change.ColumnType)
+	case profiler.SCHEMA_CHANGE_DROPPED:
+		return fmt.Sprintf(`column %s %s dropped`, change.PreviousColumnName, change.PreviousColumnType)
+	case profiler.SCHEMA_CHANGE_POSSIBLE_RENAME:
+		return fmt.Sprintf(`column %s may have been renamed to %s`, change.PreviousColumnName, change.ColumnName)
+	case profiler.SCHEMA_CHANGE_RETYPED:
+		return fmt.Sprintf(`column %s changed from %s to %s`, change.ColumnName, change.PreviousColumnType, change.ColumnType)
+	default:
+		return fmt.Sprintf(`column %s %s`, change.ColumnName, change.ChangeType)
+	}
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runMigrateNaming(args []string) {
+	flags := flag.NewFlagSet(`migrate-naming`, flag.ExitOnError)
+	profileDB := addProfileDBFlags(flags)
+
+	to := flags.String("to", "", "Naming convention to migrate the profile store to, pascal or snake")
+	dryRun := flags.Bool("dryRun", false, "Report the renames without changing anything")
+
+	flags.Parse(args)
+
+	var toPascalCase bool
+	switch *to {
+	case `pascal`:
+		toPascalCase = true
+	case `snake`:
+		toPascalCase = false
+	default:
+		log.Fatal(fmt.Errorf(`the to flag must be pascal or snake, got %v`, *to))
+	}
+
+	profileCon, err := profileDB.getProfileDBConn()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	//not scaffolding here, that would create empty tables in one of the conventions
+	profileStore := profiler.NewProfileStore(profileCon)
+	profileStore.StoreSchema = *profileDB.storeSchema
+	profileStore.StoreTablePrefix = *profileDB.storeTablePrefix
+
+	result, err := profileStore.MigrateNamingConvention(toPascalCase, *dryRun)
+	if result != nil {
+		action := `Renamed`
+		if result.DryRun {
+			action = `Would rename`
+		}
+		for _, tableRename := range result.TableRenames {
+			for _, columnRename := range tableRename.ColumnRenames {
+				log.Printf("%s column %s.%s to %s\n", action, tableRename.TableName, columnRename.ColumnName, columnRename.NewColumnName)
+			}
+			log.Printf("%s table %s to %s\n", action, tableRename.TableName, tableRename.NewTableName)
+		}
+		for _, column := range result.UnmappedColumns {
+			log.Printf("Left unmapped column %s as is\n", column)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Success")
+}
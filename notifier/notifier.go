@@ -0,0 +1,151 @@
+//Package notifier sends profile run problems to external systems such as incident tooling
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+//Reasons a run is notified about
+const REASON_RUN_FAILED = `run_failed`
+const REASON_CHECKS_FAILED = `checks_failed`
+const REASON_ANOMALIES = `anomalies`
+
+//Event is the payload sent for a run that failed or had problems
+type Event struct {
+	//Every reason that applies to the run, REASON_RUN_FAILED, REASON_CHECKS_FAILED or REASON_ANOMALIES
+	Reasons               []string                      `json:"Reasons"`
+	Summary               string                        `json:"Summary"`
+	ProfileRecordID       int                           `json:"ProfileRecordID"`
+	Time                  time.Time                     `json:"Time"`
+	Error                 string                        `json:"Error,omitempty"`
+	FailedExpectations    []profiler.ExpectationResult  `json:"FailedExpectations"`
+	StaleTables           []profiler.FreshnessResult    `json:"StaleTables"`
+	OrphanedRelationships []profiler.RelationshipResult `json:"OrphanedRelationships"`
+	Anomalies             []profiler.Anomaly            `json:"Anomalies"`
+}
+
+//HasReason returns true when the reason applies to the event
+func (e Event) HasReason(reason string) bool {
+	for _, eventReason := range e.Reasons {
+		if eventReason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+//Notifier sends an event somewhere
+type Notifier interface {
+	Notify(event Event) error
+}
+
+//NewRunEvent builds the event for a run, the run may be nil when it failed before starting.
+//Returns false when the run succeeded without failed checks or anomalies.
+func NewRunEvent(run *profiler.RunResult, runErr error) (Event, bool) {
+	event := Event{
+		Reasons:               []string{},
+		Time:                  time.Now(),
+		FailedExpectations:    []profiler.ExpectationResult{},
+		StaleTables:           []profiler.FreshnessResult{},
+		OrphanedRelationships: []profiler.RelationshipResult{},
+		Anomalies:             []profiler.Anomaly{},
+	}
+	summary := []string{}
+
+	if runErr != nil {
+		event.Reasons = append(event.Reasons, REASON_RUN_FAILED)
+		event.Error = runErr.Error()
+		summary = append(summary, fmt.Sprintf(`run failed: %v`, runErr))
+	}
+
+	if run != nil {
+		event.ProfileRecordID = run.ProfileRecordID
+		event.Time = run.StartTime
+		event.FailedExpectations = run.FailedExpectations()
+		event.StaleTables = run.StaleTables()
+		for _, relationship := range run.Relationships {
+			if relationship.OrphanCount > 0 {
+				event.OrphanedRelationships = append(event.OrphanedRelationships, relationship)
+			}
+		}
+		event.Anomalies = run.Anomalies
+	}
+
+	if len(event.FailedExpectations) > 0 || len(event.StaleTables) > 0 || len(event.OrphanedRelationships) > 0 {
+		event.Reasons = append(event.Reasons, REASON_CHECKS_FAILED)
+	}
+	if len(event.FailedExpectations) > 0 {
+		summary = append(summary, fmt.Sprintf(`%d failed expectations`, len(event.FailedExpectations)))
+	}
+	if len(event.StaleTables) > 0 {
+		summary = append(summary, fmt.Sprintf(`%d stale tables`, len(event.StaleTables)))
+	}
+	if len(event.OrphanedRelationships) > 0 {
+		summary = append(summary, fmt.Sprintf(`%d relationships with orphan rows`, len(event.OrphanedRelationships)))
+	}
+	if len(event.Anomalies) > 0 {
+		event.Reasons = append(event.Reasons, REASON_ANOMALIES)
+		summary = append(summary, fmt.Sprintf(`%d anomalies`, len(event.Anomalies)))
+	}
+
+	event.Summary = fmt.Sprintf(`Profile run %d: %s`, event.ProfileRecordID, strings.Join(summary, `, `))
+
+	return event, len(event.Reasons) > 0
+}
+
+//MultiNotifier sends each event to every notifier, one failing doesn't stop the others
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(event Event) error {
+	errs := []string{}
+	for _, notifier := range m {
+		err := notifier.Notify(event)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf(`error sending notifications: %s`, strings.Join(errs, `; `))
+	}
+	return nil
+}
+
+//Config lists the places to notify, read from a JSON file
+type Config struct {
+	Webhooks []WebhookConfig `json:"Webhooks"`
+}
+
+//LoadConfig reads a notifier config JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(fileData, &config)
+	if err != nil {
+		return config, fmt.Errorf(`error reading notifier config %s: %v`, path, err)
+	}
+	return config, nil
+}
+
+//NewNotifier builds a notifier sending to everything in the config
+func NewNotifier(config Config) (Notifier, error) {
+	notifiers := MultiNotifier{}
+	for _, webhookConfig := range config.Webhooks {
+		webhook, err := NewWebhookNotifier(webhookConfig)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, webhook)
+	}
+	return notifiers, nil
+}
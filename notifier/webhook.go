@@ -0,0 +1,229 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+//Webhook defaults used when the config leaves them empty
+const DEFAULT_WEBHOOK_MAX_RETRIES = 3
+const DEFAULT_WEBHOOK_BACKOFF = time.Second
+const DEFAULT_WEBHOOK_TIMEOUT = 10 * time.Second
+const DEFAULT_WEBHOOK_CONTENT_TYPE = `application/json`
+const DEFAULT_WEBHOOK_SIGNATURE_HEADER = `X-Profiler-Signature`
+
+//WebhookConfig configures a webhook, durations are strings such as 500ms or 30s
+type WebhookConfig struct {
+	URL     string            `json:"URL"`
+	Headers map[string]string `json:"Headers"`
+	//Text template for the request body, executed with the Event. Defaults to the event as JSON.
+	//The json function encodes a value, such as {{json .Summary}}.
+	Template     string `json:"Template"`
+	TemplateFile string `json:"TemplateFile"`
+	ContentType  string `json:"ContentType"`
+	//Reasons to notify for, defaults to every reason
+	Reasons []string `json:"Reasons"`
+	//Key to sign the body with using HMAC SHA256, SecretEnv names an environment variable holding it instead
+	Secret          string `json:"Secret"`
+	SecretEnv       string `json:"SecretEnv"`
+	SignatureHeader string `json:"SignatureHeader"`
+	//Retries after the first attempt, -1 disables retries
+	MaxRetries int `json:"MaxRetries"`
+	//Wait before the first retry, doubled for each retry after it
+	Backoff string `json:"Backoff"`
+	Timeout string `json:"Timeout"`
+}
+
+//WebhookNotifier posts events to a URL.
+//Network errors, 429 and 5xx responses are retried with exponential backoff.
+type WebhookNotifier struct {
+	URL             string
+	Headers         map[string]string
+	Template        *template.Template
+	ContentType     string
+	Reasons         []string
+	Secret          []byte
+	SignatureHeader string
+	MaxRetries      int
+	Backoff         time.Duration
+	Client          *http.Client
+}
+
+//NewWebhookNotifier builds a webhook notifier from its config, filling in the defaults
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	if config.URL == `` {
+		return nil, fmt.Errorf(`webhook URL is required`)
+	}
+
+	w := &WebhookNotifier{
+		URL:             config.URL,
+		Headers:         config.Headers,
+		ContentType:     config.ContentType,
+		Reasons:         config.Reasons,
+		Secret:          []byte(config.Secret),
+		SignatureHeader: config.SignatureHeader,
+		MaxRetries:      config.MaxRetries,
+		Backoff:         DEFAULT_WEBHOOK_BACKOFF,
+		Client:          &http.Client{Timeout: DEFAULT_WEBHOOK_TIMEOUT},
+	}
+
+	if w.ContentType == `` {
+		w.ContentType = DEFAULT_WEBHOOK_CONTENT_TYPE
+	}
+	if w.SignatureHeader == `` {
+		w.SignatureHeader = DEFAULT_WEBHOOK_SIGNATURE_HEADER
+	}
+	if w.MaxRetries == 0 {
+		w.MaxRetries = DEFAULT_WEBHOOK_MAX_RETRIES
+	} else if w.MaxRetries < 0 {
+		w.MaxRetries = 0
+	}
+
+	if config.SecretEnv != `` {
+		secret := os.Getenv(config.SecretEnv)
+		if secret == `` {
+			return nil, fmt.Errorf(`webhook secret environment variable %s is not set`, config.SecretEnv)
+		}
+		w.Secret = []byte(secret)
+	}
+
+	if config.Backoff != `` {
+		backoff, err := profiler.ParseDuration(config.Backoff)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid webhook backoff: %v`, err)
+		}
+		w.Backoff = backoff
+	}
+	if config.Timeout != `` {
+		timeout, err := profiler.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid webhook timeout: %v`, err)
+		}
+		w.Client.Timeout = timeout
+	}
+
+	templateText := config.Template
+	if config.TemplateFile != `` {
+		fileData, err := ioutil.ReadFile(config.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		templateText = string(fileData)
+	}
+	if templateText != `` {
+		tmpl, err := template.New(`webhook`).Funcs(template.FuncMap{
+			`json`: toJSON,
+		}).Parse(templateText)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid webhook template: %v`, err)
+		}
+		w.Template = tmpl
+	}
+
+	return w, nil
+}
+
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+//Notify posts the event unless none of its reasons are ones the webhook is set up for
+func (w *WebhookNotifier) Notify(event Event) error {
+	if !w.wantsEvent(event) {
+		return nil
+	}
+
+	body, err := w.getBody(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.MaxRetries {
+			return fmt.Errorf(`error posting to webhook %s after %d attempts: %v`, w.URL, attempt+1, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *WebhookNotifier) wantsEvent(event Event) bool {
+	if len(w.Reasons) == 0 {
+		return true
+	}
+	for _, reason := range w.Reasons {
+		if event.HasReason(reason) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *WebhookNotifier) getBody(event Event) ([]byte, error) {
+	if w.Template == nil {
+		return json.Marshal(event)
+	}
+
+	b := &bytes.Buffer{}
+	err := w.Template.Execute(b, event)
+	if err != nil {
+		return nil, fmt.Errorf(`error executing webhook template: %v`, err)
+	}
+	return b.Bytes(), nil
+}
+
+//Posts the body once, returns true when a failure is worth retrying
+func (w *WebhookNotifier) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set(`Content-Type`, w.ContentType)
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(w.Secret) > 0 {
+		req.Header.Set(w.SignatureHeader, `sha256=`+sign(w.Secret, body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	//drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf(`unexpected status %s`, resp.Status)
+}
+
+//Returns the hex encoded HMAC SHA256 of the body
+func sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,256 @@
+package notifier
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   string
+	}{
+		{
+			//RFC 4231 test case 2
+			name:   "rfc 4231",
+			secret: `Jefe`,
+			body:   `what do ya want for nothing?`,
+			want:   `5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843`,
+		},
+		{
+			name:   "empty body",
+			secret: `key`,
+			body:   ``,
+			want:   `5d5d139563c95b5967b9bd9a8c9b233a9dedb45072794cd232dc1b74832607d0`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sign([]byte(test.secret), []byte(test.body)); got != test.want {
+				t.Errorf("signature = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+//Serves the statuses in order, repeating the last one, and records every request
+type webhookTestServer struct {
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+	mux      sync.Mutex
+}
+
+func (s *webhookTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, string(body))
+
+	idx := len(s.requests) - 1
+	if idx >= len(s.statuses) {
+		idx = len(s.statuses) - 1
+	}
+	w.WriteHeader(s.statuses[idx])
+}
+
+func (s *webhookTestServer) attempts() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.requests)
+}
+
+func TestWebhookNotifyRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxRetries   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, wantAttempts: 1},
+		{name: "no content is success", statuses: []int{http.StatusNoContent}, wantAttempts: 1},
+		{name: "server errors are retried", statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, wantAttempts: 3},
+		{name: "too many requests is retried", statuses: []int{http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 2},
+		{name: "client errors are not retried", statuses: []int{http.StatusBadRequest, http.StatusOK}, wantAttempts: 1, wantErr: true},
+		{name: "retries run out", statuses: []int{http.StatusInternalServerError}, maxRetries: 2, wantAttempts: 3, wantErr: true},
+		{name: "retries disabled", statuses: []int{http.StatusInternalServerError}, maxRetries: -1, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &webhookTestServer{statuses: test.statuses}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			webhook, err := NewWebhookNotifier(WebhookConfig{
+				URL:        server.URL,
+				MaxRetries: test.maxRetries,
+				Backoff:    `1ms`,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = webhook.Notify(Event{Reasons: []string{REASON_RUN_FAILED}})
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want an error %v", err, test.wantErr)
+			}
+			if handler.attempts() != test.wantAttempts {
+				t.Errorf("attempts = %d, want %d", handler.attempts(), test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifyUnreachableIsRetried(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{URL: url, MaxRetries: 1, Backoff: `1ms`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = webhook.Notify(Event{})
+	if err == nil || !strings.Contains(err.Error(), `after 2 attempts`) {
+		t.Errorf("error = %v, want a failure after 2 attempts", err)
+	}
+}
+
+func TestWebhookNotifyRequest(t *testing.T) {
+	handler := &webhookTestServer{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	webhook, err := NewWebhookNotifier(WebhookConfig{
+		URL:         server.URL,
+		Headers:     map[string]string{`Authorization`: `Bearer token`},
+		Template:    `{"text": {{json .Summary}}}`,
+		ContentType: `application/vnd.test+json`,
+		Secret:      `Jefe`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = webhook.Notify(Event{Reasons: []string{REASON_ANOMALIES}, Summary: `say "hi"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if handler.attempts() != 1 {
+		t.Fatalf("attempts = %d, want 1", handler.attempts())
+	}
+	request := handler.requests[0]
+	body := handler.bodies[0]
+	if request.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", request.Method)
+	}
+	if body != `{"text": "say \"hi\""}` {
+		t.Errorf("body = %s", body)
+	}
+
+	wantHeaders := map[string]string{
+		`Content-Type`:                   `application/vnd.test+json`,
+		`Authorization`:                  `Bearer token`,
+		DEFAULT_WEBHOOK_SIGNATURE_HEADER: `sha256=` + sign([]byte(`Jefe`), []byte(body)),
+	}
+	for name, want := range wantHeaders {
+		if got := request.Header.Get(name); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestWebhookNotifyReasons(t *testing.T) {
+	tests := []struct {
+		name        string
+		reasons     []string
+		eventReason string
+		wantPosted  bool
+	}{
+		{name: "every reason by default", eventReason: REASON_ANOMALIES, wantPosted: true},
+		{name: "matching reason", reasons: []string{REASON_RUN_FAILED, REASON_ANOMALIES}, eventReason: REASON_ANOMALIES, wantPosted: true},
+		{name: "other reason", reasons: []string{REASON_RUN_FAILED}, eventReason: REASON_ANOMALIES, wantPosted: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &webhookTestServer{statuses: []int{http.StatusOK}}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			webhook, err := NewWebhookNotifier(WebhookConfig{URL: server.URL, Reasons: test.reasons})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = webhook.Notify(Event{Reasons: []string{test.eventReason}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if posted := handler.attempts() > 0; posted != test.wantPosted {
+				t.Errorf("posted = %v, want %v", posted, test.wantPosted)
+			}
+		})
+	}
+}
+
+func TestNewWebhookNotifier(t *testing.T) {
+	webhook, err := NewWebhookNotifier(WebhookConfig{URL: `http://localhost`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhook.MaxRetries != DEFAULT_WEBHOOK_MAX_RETRIES || webhook.Backoff != DEFAULT_WEBHOOK_BACKOFF || webhook.Client.Timeout != DEFAULT_WEBHOOK_TIMEOUT {
+		t.Errorf("defaults = %d retries, %v backoff, %v timeout", webhook.MaxRetries, webhook.Backoff, webhook.Client.Timeout)
+	}
+	if webhook.ContentType != DEFAULT_WEBHOOK_CONTENT_TYPE || webhook.SignatureHeader != DEFAULT_WEBHOOK_SIGNATURE_HEADER {
+		t.Errorf("defaults = %s content type, %s signature header", webhook.ContentType, webhook.SignatureHeader)
+	}
+
+	webhook, err = NewWebhookNotifier(WebhookConfig{URL: `http://localhost`, Backoff: `250ms`, Timeout: `1m`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhook.Backoff != 250*time.Millisecond || webhook.Client.Timeout != time.Minute {
+		t.Errorf("backoff = %v, timeout = %v", webhook.Backoff, webhook.Client.Timeout)
+	}
+
+	t.Setenv(`PROFILER_TEST_WEBHOOK_SECRET`, `from env`)
+	webhook, err = NewWebhookNotifier(WebhookConfig{URL: `http://localhost`, Secret: `ignored`, SecretEnv: `PROFILER_TEST_WEBHOOK_SECRET`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(webhook.Secret) != `from env` {
+		t.Errorf("secret = %s, want the environment variable", webhook.Secret)
+	}
+
+	invalid := []struct {
+		name   string
+		config WebhookConfig
+	}{
+		{name: "missing url", config: WebhookConfig{}},
+		{name: "bad backoff", config: WebhookConfig{URL: `http://localhost`, Backoff: `soon`}},
+		{name: "bad timeout", config: WebhookConfig{URL: `http://localhost`, Timeout: `later`}},
+		{name: "bad template", config: WebhookConfig{URL: `http://localhost`, Template: `{{.Summary`}},
+		{name: "unset secret variable", config: WebhookConfig{URL: `http://localhost`, SecretEnv: `PROFILER_TEST_WEBHOOK_SECRET_UNSET`}},
+		{name: "missing template file", config: WebhookConfig{URL: `http://localhost`, TemplateFile: `does/not/exist.tmpl`}},
+	}
+	for _, test := range invalid {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewWebhookNotifier(test.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
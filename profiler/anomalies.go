@@ -0,0 +1,277 @@
+package profiler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+//Anomaly detection methods
+//stddev compares against the rolling mean and standard deviation
+const ANOMALY_METHOD_STDDEV = `stddev`
+//mad compares against the rolling median and median absolute deviation, which is less affected by past outliers
+const ANOMALY_METHOD_MAD = `mad`
+
+const DEFAULT_ANOMALY_WINDOW = 10
+const DEFAULT_ANOMALY_SENSITIVITY = 3.0
+//Metrics with less history than this are not checked
+const MIN_ANOMALY_HISTORY = 3
+
+//Table level metric name used for row counts
+const ROW_COUNT_METRIC_NAME = `row_count`
+
+//Scales the median absolute deviation to be comparable with a standard deviation
+const madScaleFactor = 1.4826
+
+//Anomaly is a metric value from a run that is an outlier compared to its history.
+//Score is how many spreads the value is from the expected value, it is nil when the
+//history never changed so any difference is an outlier.
+type Anomaly struct {
+	TableName     string   `json:"TableName"`
+	ColumnName    string   `json:"ColumnName"`
+	MetricName    string   `json:"MetricName"`
+	Value         float64  `json:"Value"`
+	ExpectedValue float64  `json:"ExpectedValue"`
+	Spread        float64  `json:"Spread"`
+	Score         *float64 `json:"Score"`
+	Method        string   `json:"Method"`
+	HistorySize   int      `json:"HistorySize"`
+}
+
+//Returns the settings for the table with the defaults filled in, an unknown method is an error
+func (d AnomalyDetectionDefinition) getTableSettings(tableName string) (AnomalySettings, error) {
+	settings := d.AnomalySettings
+	if override, ok := d.Tables[tableName]; ok {
+		if override.Method != `` {
+			settings.Method = override.Method
+		}
+		if override.Window > 0 {
+			settings.Window = override.Window
+		}
+		if override.Sensitivity > 0 {
+			settings.Sensitivity = override.Sensitivity
+		}
+	}
+
+	if settings.Method == `` {
+		settings.Method = ANOMALY_METHOD_STDDEV
+	}
+	if settings.Window <= 0 {
+		settings.Window = DEFAULT_ANOMALY_WINDOW
+	}
+	if settings.Sensitivity <= 0 {
+		settings.Sensitivity = DEFAULT_ANOMALY_SENSITIVITY
+	}
+
+	if settings.Method != ANOMALY_METHOD_STDDEV && settings.Method != ANOMALY_METHOD_MAD {
+		return settings, fmt.Errorf(`unknown anomaly detection method "%s", use %s or %s`, settings.Method, ANOMALY_METHOD_STDDEV, ANOMALY_METHOD_MAD)
+	}
+	return settings, nil
+}
+
+//Checks the settings of every table in the definition so a bad method is caught before profiling
+func validateAnomalyDetection(profile ProfileDefinition) error {
+	if profile.AnomalyDetection == nil {
+		return nil
+	}
+
+	tableNames := []string{``}
+	for tableName := range profile.AnomalyDetection.Tables {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		_, err := profile.AnomalyDetection.getTableSettings(tableName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Compares every numeric metric of the run against the same metric in previous runs,
+//adds any outliers to the run and records them in the store
+func (p *Profiler) detectAnomalies(run *RunResult, definition AnomalyDetectionDefinition) error {
+	//there is no history to compare against without a store
+	if !p.hasProfileStore() {
+		return nil
+	}
+
+	//work out how far back we need to look
+	maxWindow := 0
+	for tableName := range run.Tables {
+		settings, err := definition.getTableSettings(tableName)
+		if err != nil {
+			return err
+		}
+		if settings.Window > maxWindow {
+			maxWindow = settings.Window
+		}
+	}
+
+	history, err := p.profileStore.getRecentProfileRunResults(run.ProfileRecordID, maxWindow)
+	if err != nil {
+		return err
+	}
+
+	tableNames := []string{}
+	for tableName := range run.Tables {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		settings, err := definition.getTableSettings(tableName)
+		if err != nil {
+			return err
+		}
+
+		//history is newest first so the window is the front of the slice
+		tableHistory := []*TableProfileResult{}
+		for _, runResults := range history {
+			if len(tableHistory) >= settings.Window {
+				break
+			}
+			if result, ok := runResults[tableName]; ok {
+				tableHistory = append(tableHistory, result)
+			}
+		}
+
+		current := getNumericMetrics(run.Tables[tableName])
+		previous := map[metricKey][]float64{}
+		for _, result := range tableHistory {
+			for key, value := range getNumericMetrics(result) {
+				previous[key] = append(previous[key], value)
+			}
+		}
+
+		for key, value := range current {
+			anomaly, isAnomaly := checkForAnomaly(value, previous[key], settings)
+			if !isAnomaly {
+				continue
+			}
+			anomaly.TableName = tableName
+			anomaly.ColumnName = key.columnName
+			anomaly.MetricName = key.metricName
+
+			err := p.profileStore.RecordAnomaly(run.ProfileRecordID, anomaly)
+			if err != nil {
+				return err
+			}
+			run.Anomalies = append(run.Anomalies, anomaly)
+		}
+	}
+
+	sort.Slice(run.Anomalies, func(i, j int) bool {
+		a, b := run.Anomalies[i], run.Anomalies[j]
+		if a.TableName != b.TableName {
+			return a.TableName < b.TableName
+		}
+		if a.ColumnName != b.ColumnName {
+			return a.ColumnName < b.ColumnName
+		}
+		return a.MetricName < b.MetricName
+	})
+
+	return nil
+}
+
+//Identifies a metric within a table
+type metricKey struct {
+	columnName string
+	metricName string
+}
+
+//Returns the numeric metrics of a table result, the row count and other table metrics, column metrics and custom column values
+func getNumericMetrics(result *TableProfileResult) map[metricKey]float64 {
+	metrics := map[metricKey]float64{}
+	if result.RowCount != nil {
+		metrics[metricKey{metricName: ROW_COUNT_METRIC_NAME}] = float64(*result.RowCount)
+	}
+	for metricName, value := range result.Metrics {
+		if value.IsNumeric() {
+			metrics[metricKey{metricName: metricName}] = value.Number
+		}
+	}
+	for columnName, column := range result.Columns {
+		for metricName, value := range column.Metrics {
+			if value.IsNumeric() {
+				metrics[metricKey{columnName, metricName}] = value.Number
+			}
+		}
+	}
+	for columnName, column := range result.CustomColumns {
+		if column.Value.IsNumeric() {
+			metrics[metricKey{columnName, CUSTOM_COLUMN_METRIC_NAME}] = column.Value.Number
+		}
+	}
+	return metrics
+}
+
+//Checks the value against its history, returns false if there is not enough history or the value is within range
+func checkForAnomaly(value float64, history []float64, settings AnomalySettings) (Anomaly, bool) {
+	anomaly := Anomaly{
+		Value:       value,
+		Method:      settings.Method,
+		HistorySize: len(history),
+	}
+	if len(history) < MIN_ANOMALY_HISTORY {
+		return anomaly, false
+	}
+
+	switch settings.Method {
+	case ANOMALY_METHOD_MAD:
+		center := median(history)
+		deviations := []float64{}
+		for _, point := range history {
+			deviations = append(deviations, math.Abs(point-center))
+		}
+		anomaly.ExpectedValue = center
+		anomaly.Spread = median(deviations) * madScaleFactor
+	default:
+		anomaly.ExpectedValue = mean(history)
+		anomaly.Spread = standardDeviation(history)
+	}
+
+	difference := value - anomaly.ExpectedValue
+	if anomaly.Spread == 0 {
+		//the history never moved so any change stands out
+		return anomaly, difference != 0
+	}
+
+	score := difference / anomaly.Spread
+	anomaly.Score = &score
+	return anomaly, math.Abs(score) > settings.Sensitivity
+}
+
+func mean(values []float64) float64 {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	return total / float64(len(values))
+}
+
+//Sample standard deviation
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	center := mean(values)
+	total := 0.0
+	for _, value := range values {
+		total += (value - center) * (value - center)
+	}
+	return math.Sqrt(total / float64(len(values)-1))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,283 @@
+package profiler
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckForAnomaly(t *testing.T) {
+	stddev := AnomalySettings{Method: ANOMALY_METHOD_STDDEV, Sensitivity: 3}
+	mad := AnomalySettings{Method: ANOMALY_METHOD_MAD, Sensitivity: 3}
+
+	tests := []struct {
+		name         string
+		value        float64
+		history      []float64
+		settings     AnomalySettings
+		wantAnomaly  bool
+		wantExpected float64
+		wantSpread   float64
+		wantNilScore bool
+	}{
+		{
+			name:         "not enough history",
+			value:        1000,
+			history:      []float64{10, 12},
+			settings:     stddev,
+			wantAnomaly:  false,
+			wantNilScore: true,
+		},
+		{
+			name:         "stddev within range",
+			value:        14,
+			history:      []float64{10, 12, 14},
+			settings:     stddev,
+			wantAnomaly:  false,
+			wantExpected: 12,
+			wantSpread:   2,
+		},
+		{
+			name:         "stddev outlier",
+			value:        30,
+			history:      []float64{10, 12, 14},
+			settings:     stddev,
+			wantAnomaly:  true,
+			wantExpected: 12,
+			wantSpread:   2,
+		},
+		{
+			name:         "stddev outlier below",
+			value:        0,
+			history:      []float64{10, 12, 14},
+			settings:     stddev,
+			wantAnomaly:  true,
+			wantExpected: 12,
+			wantSpread:   2,
+		},
+		{
+			name:         "stddev hides outlier behind past outlier",
+			value:        50,
+			history:      []float64{10, 10, 11, 12, 100},
+			settings:     stddev,
+			wantAnomaly:  false,
+			wantExpected: 28.6,
+			wantSpread:   standardDeviation([]float64{10, 10, 11, 12, 100}),
+		},
+		{
+			name:         "mad ignores past outlier",
+			value:        20,
+			history:      []float64{10, 10, 11, 12, 100},
+			settings:     mad,
+			wantAnomaly:  true,
+			wantExpected: 11,
+			wantSpread:   madScaleFactor,
+		},
+		{
+			name:         "mad within range",
+			value:        13,
+			history:      []float64{10, 10, 11, 12, 100},
+			settings:     mad,
+			wantAnomaly:  false,
+			wantExpected: 11,
+			wantSpread:   madScaleFactor,
+		},
+		{
+			name:         "flat history unchanged",
+			value:        5,
+			history:      []float64{5, 5, 5},
+			settings:     stddev,
+			wantAnomaly:  false,
+			wantExpected: 5,
+			wantNilScore: true,
+		},
+		{
+			name:         "flat history changed",
+			value:        6,
+			history:      []float64{5, 5, 5},
+			settings:     mad,
+			wantAnomaly:  true,
+			wantExpected: 5,
+			wantNilScore: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			anomaly, isAnomaly := checkForAnomaly(test.value, test.history, test.settings)
+			if isAnomaly != test.wantAnomaly {
+				t.Errorf("anomaly = %v, want %v", isAnomaly, test.wantAnomaly)
+			}
+			if anomaly.HistorySize != len(test.history) {
+				t.Errorf("history size = %d, want %d", anomaly.HistorySize, len(test.history))
+			}
+			if !closeTo(anomaly.ExpectedValue, test.wantExpected) {
+				t.Errorf("expected value = %v, want %v", anomaly.ExpectedValue, test.wantExpected)
+			}
+			if !closeTo(anomaly.Spread, test.wantSpread) {
+				t.Errorf("spread = %v, want %v", anomaly.Spread, test.wantSpread)
+			}
+			if (anomaly.Score == nil) != test.wantNilScore {
+				t.Fatalf("score = %v, want nil %v", anomaly.Score, test.wantNilScore)
+			}
+			if anomaly.Score != nil {
+				wantScore := (test.value - test.wantExpected) / test.wantSpread
+				if !closeTo(*anomaly.Score, wantScore) {
+					t.Errorf("score = %v, want %v", *anomaly.Score, wantScore)
+				}
+			}
+		})
+	}
+}
+
+func TestAnomalyStatistics(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		wantMean   float64
+		wantMedian float64
+		wantStddev float64
+	}{
+		{name: "single value", values: []float64{4}, wantMean: 4, wantMedian: 4, wantStddev: 0},
+		{name: "odd count", values: []float64{3, 1, 2}, wantMean: 2, wantMedian: 2, wantStddev: 1},
+		{name: "even count", values: []float64{4, 1, 3, 2}, wantMean: 2.5, wantMedian: 2.5, wantStddev: math.Sqrt(5.0 / 3)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mean(test.values); !closeTo(got, test.wantMean) {
+				t.Errorf("mean = %v, want %v", got, test.wantMean)
+			}
+			if got := median(test.values); !closeTo(got, test.wantMedian) {
+				t.Errorf("median = %v, want %v", got, test.wantMedian)
+			}
+			if got := standardDeviation(test.values); !closeTo(got, test.wantStddev) {
+				t.Errorf("standard deviation = %v, want %v", got, test.wantStddev)
+			}
+		})
+	}
+}
+
+func TestMedianLeavesInputUnsorted(t *testing.T) {
+	values := []float64{3, 1, 2}
+	median(values)
+	if values[0] != 3 || values[1] != 1 || values[2] != 2 {
+		t.Errorf("median sorted its input: %v", values)
+	}
+}
+
+func TestGetTableSettings(t *testing.T) {
+	definition := AnomalyDetectionDefinition{
+		AnomalySettings: AnomalySettings{Window: 5},
+		Tables: map[string]AnomalySettings{
+			`orders`: {Method: ANOMALY_METHOD_MAD, Sensitivity: 2},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		tableName string
+		want      AnomalySettings
+	}{
+		{
+			name:      "defaults fill the gaps",
+			tableName: `customers`,
+			want:      AnomalySettings{Method: ANOMALY_METHOD_STDDEV, Window: 5, Sensitivity: DEFAULT_ANOMALY_SENSITIVITY},
+		},
+		{
+			name:      "table override",
+			tableName: `orders`,
+			want:      AnomalySettings{Method: ANOMALY_METHOD_MAD, Window: 5, Sensitivity: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := definition.getTableSettings(test.tableName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("settings = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	empty, err := AnomalyDetectionDefinition{}.getTableSettings(`orders`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.Window != DEFAULT_ANOMALY_WINDOW {
+		t.Errorf("window = %d, want %d", empty.Window, DEFAULT_ANOMALY_WINDOW)
+	}
+}
+
+func TestValidateAnomalyDetection(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition *AnomalyDetectionDefinition
+		wantErr    bool
+	}{
+		{name: "no anomaly detection", definition: nil},
+		{name: "default method", definition: &AnomalyDetectionDefinition{}},
+		{name: "known methods", definition: &AnomalyDetectionDefinition{
+			AnomalySettings: AnomalySettings{Method: ANOMALY_METHOD_MAD},
+			Tables:          map[string]AnomalySettings{`orders`: {Method: ANOMALY_METHOD_STDDEV}},
+		}},
+		{name: "method is case sensitive", definition: &AnomalyDetectionDefinition{AnomalySettings: AnomalySettings{Method: `MAD`}}, wantErr: true},
+		{name: "typo in table override", definition: &AnomalyDetectionDefinition{
+			Tables: map[string]AnomalySettings{`orders`: {Method: `stdev`}},
+		}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateAnomalyDetection(ProfileDefinition{AnomalyDetection: test.definition})
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want an error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetNumericMetrics(t *testing.T) {
+	rowCount := 42
+	result := &TableProfileResult{
+		RowCount: &rowCount,
+		Metrics: map[string]MetricValue{
+			FRESHNESS_LAG_METRIC_NAME: NewMetricValue(30.0),
+		},
+		Columns: map[string]*ColumnProfileResult{
+			`email`: {
+				Metrics: map[string]MetricValue{
+					`null_count`: NewMetricValue(3),
+					`maximum`:    NewMetricValue(`zed@example.com`),
+				},
+			},
+		},
+		CustomColumns: map[string]*CustomColumnProfileResult{
+			`long_names`: {Value: NewMetricValue(7)},
+			`empty`:      {Value: NewMetricValue(nil)},
+		},
+	}
+
+	want := map[metricKey]float64{
+		{metricName: ROW_COUNT_METRIC_NAME}:       42,
+		{metricName: FRESHNESS_LAG_METRIC_NAME}:   30,
+		{`email`, `null_count`}:                   3,
+		{`long_names`, CUSTOM_COLUMN_METRIC_NAME}: 7,
+	}
+
+	got := getNumericMetrics(result)
+	if len(got) != len(want) {
+		t.Fatalf("metrics = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%+v = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func closeTo(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
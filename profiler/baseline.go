@@ -0,0 +1,213 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+	"time"
+)
+
+//Baseline is a snapshot of a run's results that later runs can be checked against
+type Baseline struct {
+	CreatedAt time.Time                      `json:"CreatedAt"`
+	Tables    map[string]*TableProfileResult `json:"Tables"`
+}
+
+//BaselineCheckOptions are the allowed differences from a baseline, keyed by metric name,
+//row_count for row counts and value for custom columns.
+//Tables holds per table overrides keyed by table name.
+type BaselineCheckOptions struct {
+	Tolerances map[string]CompareTolerance            `json:"Tolerances"`
+	Tables     map[string]map[string]CompareTolerance `json:"Tables"`
+}
+
+//BaselineCheckResult is the outcome of checking a run against a baseline
+type BaselineCheckResult struct {
+	Tables []BaselineTableCheck `json:"Tables"`
+}
+
+//BaselineTableCheck is the outcome for one table, a table missing from either side has a status of
+//DIFF_STATUS_ADDED or DIFF_STATUS_REMOVED and no further checks.
+//Mismatches hold the baseline value as the source and the run value as the target.
+type BaselineTableCheck struct {
+	TableName      string               `json:"TableName"`
+	Status         string               `json:"Status"`
+	AddedColumns   []string             `json:"AddedColumns"`
+	RemovedColumns []string             `json:"RemovedColumns"`
+	Mismatches     []ComparisonMismatch `json:"Mismatches"`
+}
+
+//NewBaseline snapshots the results of a run
+func NewBaseline(run *RunResult) *Baseline {
+	return &Baseline{
+		CreatedAt: run.StartTime,
+		Tables:    run.Tables,
+	}
+}
+
+//LoadBaseline reads a baseline JSON file
+func LoadBaseline(path string) (*Baseline, error) {
+	fileData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := &Baseline{}
+	err = json.Unmarshal(fileData, baseline)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid baseline file %s: %v`, path, err)
+	}
+	return baseline, nil
+}
+
+//Save writes the baseline as a JSON file
+func (b *Baseline) Save(path string) error {
+	fileData, err := json.MarshalIndent(b, ``, `  `)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, fileData, 0644)
+}
+
+//Check compares the results of a run with the baseline
+func (b *Baseline) Check(run *RunResult, options BaselineCheckOptions) *BaselineCheckResult {
+	tableNames := map[string]bool{}
+	for tableName := range b.Tables {
+		tableNames[tableName] = true
+	}
+	for tableName := range run.Tables {
+		tableNames[tableName] = true
+	}
+
+	result := &BaselineCheckResult{
+		Tables: []BaselineTableCheck{},
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		result.Tables = append(result.Tables, checkBaselineTable(tableName, b.Tables[tableName], run.Tables[tableName], options.getTableTolerances(tableName)))
+	}
+	return result
+}
+
+//Merges the table tolerances over the tolerances for every table
+func (o BaselineCheckOptions) getTableTolerances(tableName string) map[string]CompareTolerance {
+	tolerances := map[string]CompareTolerance{}
+	for metricName, tolerance := range o.Tolerances {
+		tolerances[metricName] = tolerance
+	}
+	for metricName, tolerance := range o.Tables[tableName] {
+		tolerances[metricName] = tolerance
+	}
+	return tolerances
+}
+
+func checkBaselineTable(tableName string, baseline *TableProfileResult, current *TableProfileResult, tolerances map[string]CompareTolerance) BaselineTableCheck {
+	check := BaselineTableCheck{
+		TableName:      tableName,
+		AddedColumns:   []string{},
+		RemovedColumns: []string{},
+		Mismatches:     []ComparisonMismatch{},
+	}
+
+	switch {
+	case baseline == nil:
+		check.Status = DIFF_STATUS_ADDED
+		return check
+	case current == nil:
+		check.Status = DIFF_STATUS_REMOVED
+		return check
+	}
+
+	if baseline.RowCount != nil || current.RowCount != nil {
+		if mismatch, ok := compareMetricValues(``, ROW_COUNT_METRIC_NAME, getOptionalRowCountValue(baseline.RowCount), getOptionalRowCountValue(current.RowCount), tolerances); !ok {
+			check.Mismatches = append(check.Mismatches, mismatch)
+		}
+	}
+
+	baselineMetrics := getResultMetrics(baseline)
+	currentMetrics := getResultMetrics(current)
+
+	for _, column := range getSortedResultColumnKeys(baselineMetrics, currentMetrics) {
+		baselineColumn, inBaseline := baselineMetrics[column]
+		currentColumn, inCurrent := currentMetrics[column]
+		switch {
+		case !inBaseline:
+			check.AddedColumns = append(check.AddedColumns, column.columnName)
+			continue
+		case !inCurrent:
+			check.RemovedColumns = append(check.RemovedColumns, column.columnName)
+			continue
+		}
+
+		metricNames := map[string]bool{}
+		for metricName := range baselineColumn {
+			metricNames[metricName] = true
+		}
+		for metricName := range currentColumn {
+			metricNames[metricName] = true
+		}
+		for _, metricName := range getSortedKeys(metricNames) {
+			if mismatch, ok := compareMetricValues(column.columnName, metricName, baselineColumn[metricName], currentColumn[metricName], tolerances); !ok {
+				check.Mismatches = append(check.Mismatches, mismatch)
+			}
+		}
+	}
+
+	check.Status = DIFF_STATUS_UNCHANGED
+	if len(check.AddedColumns) > 0 || len(check.RemovedColumns) > 0 || len(check.Mismatches) > 0 {
+		check.Status = DIFF_STATUS_CHANGED
+	}
+	return check
+}
+
+func getOptionalRowCountValue(rowCount *int) MetricValue {
+	if rowCount == nil {
+		return MetricValue{}
+	}
+	return NewMetricValue(*rowCount)
+}
+
+//Returns true if every table matched the baseline
+func (c *BaselineCheckResult) Passed() bool {
+	for _, table := range c.Tables {
+		if table.Status != DIFF_STATUS_UNCHANGED {
+			return false
+		}
+	}
+	return true
+}
+
+//WriteTable writes the differences from the baseline as human readable aligned text
+func (c *BaselineCheckResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TABLE\tCOLUMN\tMETRIC\tBASELINE\tCURRENT\tABS DIFF\tREL DIFF")
+	for _, table := range c.Tables {
+		switch table.Status {
+		case DIFF_STATUS_ADDED:
+			fmt.Fprintf(tw, "%s\t\t(table not in baseline)\t\t\t\t\n", table.TableName)
+		case DIFF_STATUS_REMOVED:
+			fmt.Fprintf(tw, "%s\t\t(table missing from run)\t\t\t\t\n", table.TableName)
+		}
+		for _, columnName := range table.AddedColumns {
+			fmt.Fprintf(tw, "%s\t%s\t(column not in baseline)\t\t\t\t\n", table.TableName, columnName)
+		}
+		for _, columnName := range table.RemovedColumns {
+			fmt.Fprintf(tw, "%s\t%s\t(column missing from run)\t\t\t\t\n", table.TableName, columnName)
+		}
+		for _, mismatch := range table.Mismatches {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				table.TableName,
+				mismatch.ColumnName,
+				mismatch.MetricName,
+				mismatch.Source.String(),
+				mismatch.Target.String(),
+				formatOptionalFloat(mismatch.AbsoluteDifference),
+				formatOptionalPercent(mismatch.RelativeDifference),
+			)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,188 @@
+package profiler
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//The run the baseline is taken from, the tests vary the values their tolerances apply to
+func newBaselineTestRun(rowCount int, maximum float64, customValue float64) *RunResult {
+	return &RunResult{
+		StartTime: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Tables: map[string]*TableProfileResult{
+			`orders`: newTestTableResult(`orders`, rowCount,
+				map[string]map[string]MetricValue{`total`: {`maximum`: NewMetricValue(maximum), `minimum`: NewMetricValue(0)}},
+				map[string]MetricValue{`long_notes`: NewMetricValue(customValue)},
+			),
+		},
+	}
+}
+
+func TestBaselineCheck(t *testing.T) {
+	baseline := NewBaseline(newBaselineTestRun(1000, 50, 2))
+
+	tests := []struct {
+		name           string
+		run            *RunResult
+		options        BaselineCheckOptions
+		wantPassed     bool
+		wantMismatches []string
+	}{
+		{
+			name:       "identical",
+			run:        newBaselineTestRun(1000, 50, 2),
+			wantPassed: true,
+		},
+		{
+			name:           "exact match required by default",
+			run:            newBaselineTestRun(1001, 50, 2),
+			wantMismatches: []string{`.row_count`},
+		},
+		{
+			name: "row count within relative tolerance",
+			run:  newBaselineTestRun(1040, 50, 2),
+			options: BaselineCheckOptions{
+				Tolerances: map[string]CompareTolerance{ROW_COUNT_METRIC_NAME: {Relative: 0.05}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "row count outside relative tolerance",
+			run:  newBaselineTestRun(1060, 50, 2),
+			options: BaselineCheckOptions{
+				Tolerances: map[string]CompareTolerance{ROW_COUNT_METRIC_NAME: {Relative: 0.05}},
+			},
+			wantMismatches: []string{`.row_count`},
+		},
+		{
+			name: "metric and custom column tolerances",
+			run:  newBaselineTestRun(1000, 55, 3),
+			options: BaselineCheckOptions{
+				Tolerances: map[string]CompareTolerance{
+					`maximum`:                 {Absolute: 5},
+					CUSTOM_COLUMN_METRIC_NAME: {Absolute: 1},
+				},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "table override replaces the global tolerance",
+			run:  newBaselineTestRun(1000, 55, 2),
+			options: BaselineCheckOptions{
+				Tolerances: map[string]CompareTolerance{`maximum`: {Absolute: 5}},
+				Tables: map[string]map[string]CompareTolerance{
+					`orders`: {`maximum`: {Absolute: 1}},
+				},
+			},
+			wantMismatches: []string{`total.maximum`},
+		},
+		{
+			name: "override for another table is ignored",
+			run:  newBaselineTestRun(1000, 55, 5),
+			options: BaselineCheckOptions{
+				Tolerances: map[string]CompareTolerance{`maximum`: {Absolute: 5}},
+				Tables: map[string]map[string]CompareTolerance{
+					`customers`: {`maximum`: {Absolute: 1}},
+				},
+			},
+			wantMismatches: []string{`long_notes.value`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := baseline.Check(test.run, test.options)
+			if result.Passed() != test.wantPassed {
+				t.Errorf("passed = %v, want %v", result.Passed(), test.wantPassed)
+			}
+			if len(result.Tables) != 1 {
+				t.Fatalf("checked %d tables, want 1", len(result.Tables))
+			}
+
+			mismatches := []string{}
+			for _, mismatch := range result.Tables[0].Mismatches {
+				mismatches = append(mismatches, mismatch.ColumnName+`.`+mismatch.MetricName)
+			}
+			if strings.Join(mismatches, `,`) != strings.Join(test.wantMismatches, `,`) {
+				t.Errorf("mismatches = %v, want %v", mismatches, test.wantMismatches)
+			}
+		})
+	}
+}
+
+func TestBaselineCheckTablesAndColumns(t *testing.T) {
+	baseline := NewBaseline(newBaselineTestRun(1000, 50, 2))
+	baseline.Tables[`legacy`] = &TableProfileResult{TableName: `legacy`}
+
+	run := newBaselineTestRun(1000, 50, 2)
+	run.Tables[`invoices`] = &TableProfileResult{TableName: `invoices`}
+	delete(run.Tables[`orders`].Columns, `total`)
+	run.Tables[`orders`].Columns[`discount`] = &ColumnProfileResult{ColumnName: `discount`, Metrics: map[string]MetricValue{}}
+
+	result := baseline.Check(run, BaselineCheckOptions{})
+	if result.Passed() {
+		t.Error("check passed with added and removed tables")
+	}
+
+	statuses := map[string]string{}
+	for _, table := range result.Tables {
+		statuses[table.TableName] = table.Status
+	}
+	wantStatuses := map[string]string{
+		`invoices`: DIFF_STATUS_ADDED,
+		`legacy`:   DIFF_STATUS_REMOVED,
+		`orders`:   DIFF_STATUS_CHANGED,
+	}
+	for tableName, status := range wantStatuses {
+		if statuses[tableName] != status {
+			t.Errorf("%s status = %s, want %s", tableName, statuses[tableName], status)
+		}
+	}
+
+	for _, table := range result.Tables {
+		if table.TableName != `orders` {
+			continue
+		}
+		if strings.Join(table.AddedColumns, `,`) != `discount` || strings.Join(table.RemovedColumns, `,`) != `total` {
+			t.Errorf("added %v removed %v, want [discount] and [total]", table.AddedColumns, table.RemovedColumns)
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := result.WriteTable(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`(table not in baseline)`, `(table missing from run)`, `(column not in baseline)`, `(column missing from run)`} {
+		if !strings.Contains(buffer.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buffer.String())
+		}
+	}
+}
+
+func TestBaselineSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `baseline.json`)
+	baseline := NewBaseline(newBaselineTestRun(1000, 50, 2))
+
+	err := baseline.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.CreatedAt.Equal(baseline.CreatedAt) {
+		t.Errorf("created at = %v, want %v", loaded.CreatedAt, baseline.CreatedAt)
+	}
+
+	//a saved baseline must match the run it was taken from
+	result := loaded.Check(newBaselineTestRun(1000, 50, 2), BaselineCheckOptions{})
+	if !result.Passed() {
+		t.Errorf("loaded baseline does not match its run: %+v", result.Tables)
+	}
+}
@@ -0,0 +1,242 @@
+package profiler
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+//Defaults for candidate key discovery
+const DEFAULT_MAX_KEY_COLUMNS = 3
+const DEFAULT_MAX_KEY_COMBINATIONS = 100
+
+//KeyDiscoveryOptions limits how much work candidate key discovery does per table.
+//MaxKeyColumns is the most columns in a composite key and MaxCombinations is the most
+//composite combinations queried, zero values use the defaults.
+type KeyDiscoveryOptions struct {
+	MaxKeyColumns   int
+	MaxCombinations int
+}
+
+//KeyDiscoveryResult holds the candidate keys found in each table
+type KeyDiscoveryResult struct {
+	DiscoveredAt time.Time           `json:"DiscoveredAt"`
+	Tables       []TableKeyDiscovery `json:"Tables"`
+}
+
+//TableKeyDiscovery is the candidate keys of a table, smallest first.
+//Composite keys never contain a smaller key. LimitReached is set when combinations were left untried.
+type TableKeyDiscovery struct {
+	TableName         string     `json:"TableName"`
+	RowCount          int        `json:"RowCount"`
+	CandidateKeys     [][]string `json:"CandidateKeys"`
+	CombinationsTried int        `json:"CombinationsTried"`
+	LimitReached      bool       `json:"LimitReached"`
+}
+
+//Column considered for a key along with its number of distinct values
+type keyColumn struct {
+	name          string
+	distinctCount int
+}
+
+//DiscoverCandidateKeys finds the columns and small column combinations that uniquely identify rows,
+//with no nulls, in each table and records them in the store when there is one. Discovery is not a
+//profile run, so it adds nothing to the run history read by diff, report, export or anomaly detection.
+func (p *Profiler) DiscoverCandidateKeys(tableNames []string, options KeyDiscoveryOptions) (*KeyDiscoveryResult, error) {
+	if options.MaxKeyColumns <= 0 {
+		options.MaxKeyColumns = DEFAULT_MAX_KEY_COLUMNS
+	}
+	if options.MaxCombinations <= 0 {
+		options.MaxCombinations = DEFAULT_MAX_KEY_COMBINATIONS
+	}
+
+	result := &KeyDiscoveryResult{
+		DiscoveredAt: time.Now(),
+		Tables:       []TableKeyDiscovery{},
+	}
+	for _, tableName := range tableNames {
+		discovery, err := p.discoverTableCandidateKeys(tableName, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.hasProfileStore() {
+			for _, key := range discovery.CandidateKeys {
+				err = p.profileStore.RecordCandidateKey(tableName, result.DiscoveredAt, key)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		result.Tables = append(result.Tables, discovery)
+	}
+
+	return result, p.flushProfileStore()
+}
+
+func (p *Profiler) discoverTableCandidateKeys(tableName string, options KeyDiscoveryOptions) (TableKeyDiscovery, error) {
+	discovery := TableKeyDiscovery{
+		TableName:     tableName,
+		CandidateKeys: [][]string{},
+	}
+
+	rowCount, err := p.targetDBConn.GetTableRowCount(tableName)
+	if err != nil {
+		return discovery, err
+	}
+	discovery.RowCount = rowCount
+
+	//every column is unique in an empty table so there is nothing to learn
+	if rowCount == 0 {
+		return discovery, nil
+	}
+
+	columns, err := p.getKeyColumns(tableName)
+	if err != nil {
+		return discovery, err
+	}
+
+	//single column keys, the rest can only be part of a composite key
+	compositeColumns := []keyColumn{}
+	for _, column := range columns {
+		if column.distinctCount == rowCount {
+			discovery.CandidateKeys = append(discovery.CandidateKeys, []string{column.name})
+		} else if column.distinctCount > 1 {
+			compositeColumns = append(compositeColumns, column)
+		}
+	}
+
+	//try the most selective columns first so the limit is spent on likely keys
+	sort.SliceStable(compositeColumns, func(i, j int) bool {
+		return compositeColumns[i].distinctCount > compositeColumns[j].distinctCount
+	})
+
+	for size := 2; size <= options.MaxKeyColumns && size <= len(compositeColumns); size++ {
+		for _, combination := range getKeyColumnCombinations(compositeColumns, size) {
+			if containsCandidateKey(discovery.CandidateKeys, combination) || !canBeKey(combination, rowCount) {
+				continue
+			}
+			if discovery.CombinationsTried >= options.MaxCombinations {
+				discovery.LimitReached = true
+				return discovery, nil
+			}
+			discovery.CombinationsTried++
+
+			columnNames := getKeyColumnNames(combination)
+			distinctCount, err := p.targetDBConn.CountDistinctRows(tableName, columnNames)
+			if err != nil {
+				return discovery, err
+			}
+			if distinctCount == rowCount {
+				discovery.CandidateKeys = append(discovery.CandidateKeys, columnNames)
+			}
+		}
+	}
+
+	return discovery, nil
+}
+
+//Returns the columns of the table without nulls along with their distinct counts
+func (p *Profiler) getKeyColumns(tableName string) ([]keyColumn, error) {
+	rows, err := p.targetDBConn.GetSelectAllColumnsSingle(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columnsData, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []keyColumn{}
+	for _, columnData := range columnsData {
+		nullCountProfile, hasNullCount := p.targetDBConn.QualityProfilesByType(columnData.DatabaseTypeName())[`null_count`]
+		distinctCountProfile, hasDistinctCount := p.targetDBConn.ComparisonProfilesByType(columnData.DatabaseTypeName())[`distinct_count`]
+		//types that can't be counted can't be keys
+		if !hasNullCount || !hasDistinctCount {
+			continue
+		}
+
+		profiles := map[string]string{
+			`null_count`:     nullCountProfile,
+			`distinct_count`: distinctCountProfile,
+		}
+		metrics, err := getComparedColumnMetrics(p.targetDBConn, tableName, columnData.Name(), profiles)
+		if err != nil {
+			return nil, err
+		}
+
+		if metrics[`null_count`].Number > 0 {
+			continue
+		}
+		columns = append(columns, keyColumn{
+			name:          columnData.Name(),
+			distinctCount: int(metrics[`distinct_count`].Number),
+		})
+	}
+
+	return columns, nil
+}
+
+//Returns every combination of the columns of the size, keeping the column order
+func getKeyColumnCombinations(columns []keyColumn, size int) [][]keyColumn {
+	combinations := [][]keyColumn{}
+	var build func(start int, combination []keyColumn)
+	build = func(start int, combination []keyColumn) {
+		if len(combination) == size {
+			combinations = append(combinations, append([]keyColumn{}, combination...))
+			return
+		}
+		for idx := start; idx < len(columns); idx++ {
+			build(idx+1, append(combination, columns[idx]))
+		}
+	}
+	build(0, []keyColumn{})
+	return combinations
+}
+
+//A combination can only be unique if the product of its distinct counts covers every row
+func canBeKey(combination []keyColumn, rowCount int) bool {
+	combinations := 1.0
+	for _, column := range combination {
+		combinations = combinations * float64(column.distinctCount)
+	}
+	return combinations >= float64(rowCount)
+}
+
+//Returns true if a key already found is a subset of the combination, so the combination is not minimal
+func containsCandidateKey(keys [][]string, combination []keyColumn) bool {
+	names := map[string]bool{}
+	for _, column := range combination {
+		names[column.name] = true
+	}
+
+	for _, key := range keys {
+		containsKey := true
+		for _, columnName := range key {
+			if !names[columnName] {
+				containsKey = false
+				break
+			}
+		}
+		if containsKey {
+			return true
+		}
+	}
+	return false
+}
+
+func getKeyColumnNames(combination []keyColumn) []string {
+	columnNames := []string{}
+	for _, column := range combination {
+		columnNames = append(columnNames, column.name)
+	}
+	return columnNames
+}
+
+//Joins the column names of a key for storage
+func formatCandidateKey(columnNames []string) string {
+	return strings.Join(columnNames, `,`)
+}
@@ -0,0 +1,332 @@
+package profiler
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//CompareDefinition lists the tables to compare between a source and target database
+type CompareDefinition struct {
+	Tables []CompareTableDefinition `json:"Tables"`
+	//Allowed differences keyed by metric name, row_count for row counts, apply to every table
+	Tolerances map[string]CompareTolerance `json:"Tolerances"`
+}
+
+//CompareTableDefinition is a table to compare, the target table name defaults to the source table name
+//and every column of the source table is compared when no columns are listed
+type CompareTableDefinition struct {
+	TableName       string   `json:"TableName"`
+	TargetTableName string   `json:"TargetTableName"`
+	Columns         []string `json:"Columns"`
+	//Per table overrides of the definition tolerances
+	Tolerances map[string]CompareTolerance `json:"Tolerances"`
+}
+
+//CompareTolerance is how far a target value may be from the source value and still match.
+//A value matches when it is within either tolerance, the zero value requires an exact match.
+//Timestamp differences are measured in seconds.
+type CompareTolerance struct {
+	Absolute float64 `json:"Absolute"`
+	Relative float64 `json:"Relative"`
+}
+
+//ComparisonResult holds the comparison of every table in the definition
+type ComparisonResult struct {
+	Tables []TableComparison `json:"Tables"`
+}
+
+//TableComparison is the comparison of one table, MissingColumns are source columns the target table lacks
+type TableComparison struct {
+	TableName       string               `json:"TableName"`
+	TargetTableName string               `json:"TargetTableName"`
+	SourceRowCount  int                  `json:"SourceRowCount"`
+	TargetRowCount  int                  `json:"TargetRowCount"`
+	MissingColumns  []string             `json:"MissingColumns"`
+	Mismatches      []ComparisonMismatch `json:"Mismatches"`
+}
+
+//ComparisonMismatch is a metric whose values differ by more than its tolerance.
+//The column name is empty for the row count.
+type ComparisonMismatch struct {
+	ColumnName         string      `json:"ColumnName"`
+	MetricName         string      `json:"MetricName"`
+	Source             MetricValue `json:"Source"`
+	Target             MetricValue `json:"Target"`
+	AbsoluteDifference *float64    `json:"AbsoluteDifference"`
+	RelativeDifference *float64    `json:"RelativeDifference"`
+}
+
+//Returns true if any compared table has a mismatch or missing column
+func (c *ComparisonResult) HasMismatches() bool {
+	for _, table := range c.Tables {
+		if len(table.Mismatches) > 0 || len(table.MissingColumns) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//CompareTables profiles each table in both databases with identical column profiles and reports the differences.
+//The profile queries are built from the source column types so both sides run the same sql.
+func CompareTables(sourceDBConn db.DBConn, targetDBConn db.DBConn, definition CompareDefinition) (*ComparisonResult, error) {
+	result := &ComparisonResult{
+		Tables: []TableComparison{},
+	}
+
+	for _, table := range definition.Tables {
+		comparison, err := compareTable(sourceDBConn, targetDBConn, table, definition.getTableTolerances(table))
+		if err != nil {
+			return nil, err
+		}
+		result.Tables = append(result.Tables, comparison)
+	}
+
+	return result, nil
+}
+
+//Merges the table tolerances over the definition tolerances
+func (d CompareDefinition) getTableTolerances(table CompareTableDefinition) map[string]CompareTolerance {
+	tolerances := map[string]CompareTolerance{}
+	for metricName, tolerance := range d.Tolerances {
+		tolerances[metricName] = tolerance
+	}
+	for metricName, tolerance := range table.Tolerances {
+		tolerances[metricName] = tolerance
+	}
+	return tolerances
+}
+
+func compareTable(sourceDBConn db.DBConn, targetDBConn db.DBConn, table CompareTableDefinition, tolerances map[string]CompareTolerance) (TableComparison, error) {
+	targetTableName := table.TargetTableName
+	if targetTableName == `` {
+		targetTableName = table.TableName
+	}
+
+	comparison := TableComparison{
+		TableName:       table.TableName,
+		TargetTableName: targetTableName,
+		MissingColumns:  []string{},
+		Mismatches:      []ComparisonMismatch{},
+	}
+
+	sourceRowCount, err := sourceDBConn.GetTableRowCount(table.TableName)
+	if err != nil {
+		return comparison, err
+	}
+	targetRowCount, err := targetDBConn.GetTableRowCount(targetTableName)
+	if err != nil {
+		return comparison, err
+	}
+	comparison.SourceRowCount = sourceRowCount
+	comparison.TargetRowCount = targetRowCount
+
+	if mismatch, ok := compareMetricValues(``, ROW_COUNT_METRIC_NAME, NewMetricValue(sourceRowCount), NewMetricValue(targetRowCount), tolerances); !ok {
+		comparison.Mismatches = append(comparison.Mismatches, mismatch)
+	}
+
+	sourceColumns, err := getComparedColumnTypes(sourceDBConn, table.TableName, table.Columns)
+	if err != nil {
+		return comparison, err
+	}
+	targetColumns, err := getComparedColumnTypes(targetDBConn, targetTableName, nil)
+	if err != nil {
+		return comparison, err
+	}
+
+	for _, columnName := range getSortedColumnNames(sourceColumns) {
+		targetColumnName, ok := findColumnName(targetColumns, columnName)
+		if !ok {
+			comparison.MissingColumns = append(comparison.MissingColumns, columnName)
+			continue
+		}
+
+		profiles := sourceDBConn.ProfilesByType(sourceColumns[columnName])
+		for metricName, profile := range sourceDBConn.ComparisonProfilesByType(sourceColumns[columnName]) {
+			profiles[metricName] = profile
+		}
+
+		sourceMetrics, err := getComparedColumnMetrics(sourceDBConn, table.TableName, columnName, profiles)
+		if err != nil {
+			return comparison, err
+		}
+		targetMetrics, err := getComparedColumnMetrics(targetDBConn, targetTableName, targetColumnName, profiles)
+		if err != nil {
+			return comparison, err
+		}
+
+		metricNames := map[string]bool{}
+		for metricName := range profiles {
+			metricNames[metricName] = true
+		}
+		for _, metricName := range getSortedKeys(metricNames) {
+			if mismatch, ok := compareMetricValues(columnName, metricName, sourceMetrics[metricName], targetMetrics[metricName], tolerances); !ok {
+				comparison.Mismatches = append(comparison.Mismatches, mismatch)
+			}
+		}
+	}
+
+	return comparison, nil
+}
+
+//Returns a map of column name, as the driver returns it, to database type name, all columns are returned when none are listed
+func getComparedColumnTypes(dbConn db.DBConn, tableName string, columns []string) (map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	if len(columns) > 0 {
+		rows, err = dbConn.GetSelectSingle(tableName, columns)
+	} else {
+		rows, err = dbConn.GetSelectAllColumnsSingle(tableName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnsData, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	columnTypes := map[string]string{}
+	for _, columnData := range columnsData {
+		columnTypes[columnData.Name()] = columnData.DatabaseTypeName()
+	}
+	return columnTypes, nil
+}
+
+//Returns the name of the column in the map, an exact match is preferred over one that only differs in case.
+//The name is kept as is since it is quoted in the profile queries.
+func findColumnName(columnTypes map[string]string, columnName string) (string, bool) {
+	if _, ok := columnTypes[columnName]; ok {
+		return columnName, true
+	}
+	for _, name := range getSortedColumnNames(columnTypes) {
+		if strings.EqualFold(name, columnName) {
+			return name, true
+		}
+	}
+	return ``, false
+}
+
+func getSortedColumnNames(columnTypes map[string]string) []string {
+	columnNames := []string{}
+	for columnName := range columnTypes {
+		columnNames = append(columnNames, columnName)
+	}
+	sort.Strings(columnNames)
+	return columnNames
+}
+
+//Runs every profile for the column in a single query and returns the values by metric name
+func getComparedColumnMetrics(dbConn db.DBConn, tableName string, columnName string, profiles map[string]string) (map[string]MetricValue, error) {
+	columnNameEscaped := fmt.Sprintf(`"%s"`, columnName)
+	metricNames := []string{}
+	profileSelects := []string{}
+	for metricName, profile := range profiles {
+		metricNames = append(metricNames, metricName)
+		profileSelects = append(profileSelects, fmt.Sprintf(`%s as "%s"`, fmt.Sprintf(profile, columnNameEscaped), metricName))
+	}
+
+	rows, err := dbConn.GetRowsSelect(tableName, profileSelects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(profileSelects))
+	valuePointers := make([]interface{}, len(profileSelects))
+	for idx := range values {
+		valuePointers[idx] = &values[idx]
+	}
+
+	if rows.Next() {
+		err = rows.Scan(valuePointers...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metrics := map[string]MetricValue{}
+	for idx, metricName := range metricNames {
+		metrics[metricName] = NewMetricValue(values[idx])
+	}
+	return metrics, rows.Err()
+}
+
+//Compares the source and target values of a metric, returns false with the mismatch when they differ
+//by more than the tolerance for the metric
+func compareMetricValues(columnName string, metricName string, source MetricValue, target MetricValue, tolerances map[string]CompareTolerance) (ComparisonMismatch, bool) {
+	mismatch := ComparisonMismatch{
+		ColumnName: columnName,
+		MetricName: metricName,
+		Source:     source,
+		Target:     target,
+	}
+
+	var sourceNumber, targetNumber float64
+	switch {
+	case source.IsNumeric() && target.IsNumeric():
+		sourceNumber, targetNumber = source.Number, target.Number
+	case source.Kind == METRIC_KIND_TIMESTAMP && target.Kind == METRIC_KIND_TIMESTAMP:
+		sourceNumber, targetNumber = float64(source.Time.UnixNano())/1e9, float64(target.Time.UnixNano())/1e9
+	default:
+		return mismatch, source.Equal(target)
+	}
+
+	absolute := math.Abs(targetNumber - sourceNumber)
+	mismatch.AbsoluteDifference = &absolute
+	if sourceNumber != 0 {
+		relative := absolute / math.Abs(sourceNumber)
+		mismatch.RelativeDifference = &relative
+	}
+
+	tolerance := tolerances[metricName]
+	withinAbsolute := absolute <= tolerance.Absolute
+	withinRelative := mismatch.RelativeDifference != nil && *mismatch.RelativeDifference <= tolerance.Relative
+	return mismatch, withinAbsolute || withinRelative
+}
+
+//WriteTable writes the comparison as human readable aligned text
+func (c *ComparisonResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "TABLE\tTARGET TABLE\tSOURCE ROWS\tTARGET ROWS\tMISMATCHES")
+	for _, table := range c.Tables {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n",
+			table.TableName,
+			table.TargetTableName,
+			table.SourceRowCount,
+			table.TargetRowCount,
+			len(table.Mismatches)+len(table.MissingColumns),
+		)
+	}
+
+	if c.HasMismatches() {
+		fmt.Fprintln(tw, "\nTABLE\tCOLUMN\tMETRIC\tSOURCE\tTARGET\tABS DIFF\tREL DIFF")
+		for _, table := range c.Tables {
+			for _, columnName := range table.MissingColumns {
+				fmt.Fprintf(tw, "%s\t%s\t(missing in target)\t\t\t\t\n", table.TableName, columnName)
+			}
+			for _, mismatch := range table.Mismatches {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					table.TableName,
+					mismatch.ColumnName,
+					mismatch.MetricName,
+					mismatch.Source.String(),
+					mismatch.Target.String(),
+					formatOptionalFloat(mismatch.AbsoluteDifference),
+					formatOptionalPercent(mismatch.RelativeDifference),
+				)
+			}
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,98 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareMetricValues(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		source       MetricValue
+		target       MetricValue
+		tolerance    CompareTolerance
+		wantMatch    bool
+		wantAbsolute *float64
+		wantRelative *float64
+	}{
+		{name: "exact match", source: NewMetricValue(10), target: NewMetricValue(10), wantMatch: true, wantAbsolute: floatPointer(0), wantRelative: floatPointer(0)},
+		{name: "no tolerance", source: NewMetricValue(10), target: NewMetricValue(11), wantMatch: false, wantAbsolute: floatPointer(1), wantRelative: floatPointer(0.1)},
+		{name: "within absolute", source: NewMetricValue(10), target: NewMetricValue(12), tolerance: CompareTolerance{Absolute: 2}, wantMatch: true, wantAbsolute: floatPointer(2), wantRelative: floatPointer(0.2)},
+		{name: "outside absolute", source: NewMetricValue(10), target: NewMetricValue(7), tolerance: CompareTolerance{Absolute: 2}, wantMatch: false, wantAbsolute: floatPointer(3), wantRelative: floatPointer(0.3)},
+		{name: "within relative", source: NewMetricValue(200), target: NewMetricValue(190), tolerance: CompareTolerance{Relative: 0.05}, wantMatch: true, wantAbsolute: floatPointer(10), wantRelative: floatPointer(0.05)},
+		{name: "outside relative", source: NewMetricValue(200), target: NewMetricValue(189), tolerance: CompareTolerance{Relative: 0.05}, wantMatch: false, wantAbsolute: floatPointer(11), wantRelative: floatPointer(0.055)},
+		{name: "either tolerance", source: NewMetricValue(200), target: NewMetricValue(150), tolerance: CompareTolerance{Absolute: 50, Relative: 0.01}, wantMatch: true, wantAbsolute: floatPointer(50), wantRelative: floatPointer(0.25)},
+		{name: "relative from zero", source: NewMetricValue(0), target: NewMetricValue(1), tolerance: CompareTolerance{Relative: 10}, wantMatch: false, wantAbsolute: floatPointer(1)},
+		{name: "negative source", source: NewMetricValue(-100), target: NewMetricValue(-95), tolerance: CompareTolerance{Relative: 0.05}, wantMatch: true, wantAbsolute: floatPointer(5), wantRelative: floatPointer(0.05)},
+		{name: "timestamps in seconds", source: NewMetricValue(timestamp), target: NewMetricValue(timestamp.Add(30 * time.Second)), tolerance: CompareTolerance{Absolute: 60}, wantMatch: true, wantAbsolute: floatPointer(30), wantRelative: floatPointer(30 / float64(timestamp.Unix()))},
+		{name: "timestamps too far apart", source: NewMetricValue(timestamp), target: NewMetricValue(timestamp.Add(time.Hour)), tolerance: CompareTolerance{Absolute: 60}, wantMatch: false, wantAbsolute: floatPointer(3600), wantRelative: floatPointer(3600 / float64(timestamp.Unix()))},
+		{name: "same text", source: NewMetricValue(`a`), target: NewMetricValue(`a`), tolerance: CompareTolerance{Absolute: 1}, wantMatch: true},
+		{name: "different text", source: NewMetricValue(`a`), target: NewMetricValue(`b`), tolerance: CompareTolerance{Absolute: 1}, wantMatch: false},
+		{name: "null and number", source: MetricValue{}, target: NewMetricValue(0), tolerance: CompareTolerance{Absolute: 1}, wantMatch: false},
+		{name: "both null", source: MetricValue{}, target: MetricValue{}, wantMatch: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tolerances := map[string]CompareTolerance{`maximum`: test.tolerance}
+			mismatch, match := compareMetricValues(`total`, `maximum`, test.source, test.target, tolerances)
+			if match != test.wantMatch {
+				t.Errorf("match = %v, want %v", match, test.wantMatch)
+			}
+			if mismatch.ColumnName != `total` || mismatch.MetricName != `maximum` {
+				t.Errorf("mismatch is for %s %s", mismatch.ColumnName, mismatch.MetricName)
+			}
+			if !mismatch.Source.Equal(test.source) || !mismatch.Target.Equal(test.target) {
+				t.Errorf("mismatch values = %v -> %v", mismatch.Source, mismatch.Target)
+			}
+			if !equalFloatPointers(mismatch.AbsoluteDifference, test.wantAbsolute) {
+				t.Errorf("absolute difference = %v, want %v", formatOptionalFloat(mismatch.AbsoluteDifference), formatOptionalFloat(test.wantAbsolute))
+			}
+			if !equalFloatPointers(mismatch.RelativeDifference, test.wantRelative) {
+				t.Errorf("relative difference = %v, want %v", formatOptionalFloat(mismatch.RelativeDifference), formatOptionalFloat(test.wantRelative))
+			}
+		})
+	}
+}
+
+func TestCompareMetricValuesUsesMetricTolerance(t *testing.T) {
+	tolerances := map[string]CompareTolerance{`minimum`: {Absolute: 100}}
+	_, match := compareMetricValues(`total`, `maximum`, NewMetricValue(1), NewMetricValue(2), tolerances)
+	if match {
+		t.Error("a tolerance for another metric was used")
+	}
+}
+
+func TestFindColumnName(t *testing.T) {
+	columnTypes := map[string]string{
+		`id`:        `INT4`,
+		`CreatedAt`: `TIMESTAMP`,
+		`Email`:     `TEXT`,
+		`email`:     `TEXT`,
+	}
+
+	tests := []struct {
+		columnName string
+		want       string
+		wantOK     bool
+	}{
+		{columnName: `id`, want: `id`, wantOK: true},
+		{columnName: `CreatedAt`, want: `CreatedAt`, wantOK: true},
+		{columnName: `createdat`, want: `CreatedAt`, wantOK: true},
+		{columnName: `Email`, want: `Email`, wantOK: true},
+		{columnName: `email`, want: `email`, wantOK: true},
+		{columnName: `EMAIL`, want: `Email`, wantOK: true},
+		{columnName: `missing`, wantOK: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.columnName, func(t *testing.T) {
+			got, ok := findColumnName(columnTypes, test.columnName)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("column = %s %v, want %s %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
@@ -5,4 +5,16 @@ const PROFILE_RECORD_ID = `profile_record_id`
 const TABLE_CUSTOM_COLUMN_NAME_ID = `table_column_name_id`
 const TABLE_CUSTOM_COLUMN_NAMES = `table_custom_column_names`
 const TABLE_CUSTOM_COLUMN_PROFILE_PREFIX = `table_custom_column_profiles_`
-const TABLE_COLUMN_PROFILE_PREFIX = `table_column_profiles_`
\ No newline at end of file
+const TABLE_COLUMN_PROFILE_PREFIX = `table_column_profiles_`
+
+//Profile store layouts
+//wide stores one row per column profile in a table per database type
+const STORE_LAYOUT_WIDE = `wide`
+//long stores one row per metric with a typed value column
+const STORE_LAYOUT_LONG = `long`
+
+const METRIC_NAME = `metric_name`
+const NUMERIC_VALUE = `numeric_value`
+const TEXT_VALUE = `text_value`
+const TIMESTAMP_VALUE = `timestamp_value`
+const CUSTOM_COLUMN_METRIC_NAME = `value`
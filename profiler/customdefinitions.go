@@ -3,15 +3,51 @@ package profiler
 type ProfileDefinition struct {
 	FullProfileTables   []string          `json:"FullProfileTables"`
 	CustomProfileTables []TableDefinition `json:"CustomProfileTables"`
+	AnomalyDetection    *AnomalyDetectionDefinition `json:"AnomalyDetection"`
+	//Gathers the null and distinct counts of every profiled column, as shown by the report
+	QualityProfiles     bool              `json:"QualityProfiles"`
 }
 
 type TableDefinition struct {
 	TableName     string                 `json:"TableName"`
 	Columns       []string               `json:"Columns"`
 	CustomColumns []CustomColumnDefition `json:"CustomColumns"`
+	//Data quality assertions checked against the metrics gathered for this table
+	Expectations  []string               `json:"Expectations"`
+	//Timestamp column whose newest value shows how fresh the table is
+	FreshnessColumn string               `json:"FreshnessColumn"`
+	//Oldest the newest row can be before the table is stale, such as 6h or 1d
+	MaxStaleness  string                 `json:"MaxStaleness"`
+	//Foreign keys the database doesn't declare, checked for orphan rows along with the declared ones
+	Relationships []RelationshipDefinition `json:"Relationships"`
+}
+
+//A logical foreign key, the columns pair up in order with the referenced columns
+type RelationshipDefinition struct {
+	Name              string   `json:"Name"`
+	Columns           []string `json:"Columns"`
+	ReferencedTable   string   `json:"ReferencedTable"`
+	ReferencedColumns []string `json:"ReferencedColumns"`
 }
 
 type CustomColumnDefition struct {
 	ColumnName       string `json:"ColumnName"`
 	ColumnDefinition string `json:"ColumnDefinition"`
 }
+
+//Enables anomaly detection after each run, the settings here apply to every table
+//unless overridden for the table in Tables
+type AnomalyDetectionDefinition struct {
+	AnomalySettings
+	Tables map[string]AnomalySettings `json:"Tables"`
+}
+
+//Zero values fall back to the defaults
+type AnomalySettings struct {
+	//ANOMALY_METHOD_STDDEV (default) or ANOMALY_METHOD_MAD
+	Method string `json:"Method"`
+	//Number of previous runs to compare against
+	Window int `json:"Window"`
+	//How many standard deviations, or scaled median absolute deviations, a value can be from the center before it is flagged
+	Sensitivity float64 `json:"Sensitivity"`
+}
@@ -0,0 +1,314 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+//Status of a table between the two runs of a diff
+const DIFF_STATUS_ADDED = `added`
+const DIFF_STATUS_REMOVED = `removed`
+const DIFF_STATUS_CHANGED = `changed`
+const DIFF_STATUS_UNCHANGED = `unchanged`
+
+//ProfileDiff is the difference between two profile runs
+type ProfileDiff struct {
+	FromProfileRecordID int         `json:"FromProfileRecordID"`
+	ToProfileRecordID   int         `json:"ToProfileRecordID"`
+	Tables              []TableDiff `json:"Tables"`
+}
+
+//TableDiff is the difference of a single table between two runs.
+//Row counts are nil when the table had no row count in that run.
+type TableDiff struct {
+	TableName      string       `json:"TableName"`
+	Status         string       `json:"Status"`
+	FromRowCount   *int         `json:"FromRowCount"`
+	ToRowCount     *int         `json:"ToRowCount"`
+	RowCountDelta  *int         `json:"RowCountDelta"`
+	AddedColumns   []string     `json:"AddedColumns"`
+	RemovedColumns []string     `json:"RemovedColumns"`
+	MetricChanges  []MetricDiff `json:"MetricChanges"`
+}
+
+//MetricDiff is a metric whose value changed between two runs, table level metrics have an empty column name.
+//The differences are only set for numeric values, the relative difference is also left
+//unset when the old value is zero.
+type MetricDiff struct {
+	ColumnName         string      `json:"ColumnName"`
+	MetricName         string      `json:"MetricName"`
+	IsCustomColumn     bool        `json:"IsCustomColumn"`
+	From               MetricValue `json:"From"`
+	To                 MetricValue `json:"To"`
+	AbsoluteDifference *float64    `json:"AbsoluteDifference"`
+	RelativeDifference *float64    `json:"RelativeDifference"`
+}
+
+//DiffProfiles compares two stored profile runs
+func (p *ProfileStore) DiffProfiles(fromProfileRecordID int, toProfileRecordID int) (*ProfileDiff, error) {
+	fromResults, err := p.GetProfileRunResults(fromProfileRecordID)
+	if err != nil {
+		return nil, err
+	}
+
+	toResults, err := p.GetProfileRunResults(toProfileRecordID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffTableProfileResults(fromResults, toResults)
+	diff.FromProfileRecordID = fromProfileRecordID
+	diff.ToProfileRecordID = toProfileRecordID
+	return diff, nil
+}
+
+//DiffTableProfileResults compares two sets of table results keyed by table name
+func DiffTableProfileResults(fromResults map[string]*TableProfileResult, toResults map[string]*TableProfileResult) *ProfileDiff {
+	tableNames := map[string]bool{}
+	for tableName := range fromResults {
+		tableNames[tableName] = true
+	}
+	for tableName := range toResults {
+		tableNames[tableName] = true
+	}
+
+	diff := &ProfileDiff{
+		Tables: []TableDiff{},
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		diff.Tables = append(diff.Tables, diffTableProfileResult(tableName, fromResults[tableName], toResults[tableName]))
+	}
+
+	return diff
+}
+
+func diffTableProfileResult(tableName string, from *TableProfileResult, to *TableProfileResult) TableDiff {
+	tableDiff := TableDiff{
+		TableName:      tableName,
+		AddedColumns:   []string{},
+		RemovedColumns: []string{},
+		MetricChanges:  []MetricDiff{},
+	}
+
+	switch {
+	case from == nil:
+		tableDiff.Status = DIFF_STATUS_ADDED
+		tableDiff.ToRowCount = to.RowCount
+		return tableDiff
+	case to == nil:
+		tableDiff.Status = DIFF_STATUS_REMOVED
+		tableDiff.FromRowCount = from.RowCount
+		return tableDiff
+	}
+
+	tableDiff.FromRowCount = from.RowCount
+	tableDiff.ToRowCount = to.RowCount
+	if from.RowCount != nil && to.RowCount != nil {
+		delta := *to.RowCount - *from.RowCount
+		tableDiff.RowCountDelta = &delta
+	}
+
+	//table level metrics are listed before the column metrics
+	tableMetricNames := map[string]bool{}
+	for metricName := range from.Metrics {
+		tableMetricNames[metricName] = true
+	}
+	for metricName := range to.Metrics {
+		tableMetricNames[metricName] = true
+	}
+	for _, metricName := range getSortedKeys(tableMetricNames) {
+		fromValue := from.Metrics[metricName]
+		toValue := to.Metrics[metricName]
+		if fromValue.Equal(toValue) {
+			continue
+		}
+		tableDiff.MetricChanges = append(tableDiff.MetricChanges, newMetricDiff(``, metricName, false, fromValue, toValue))
+	}
+
+	fromMetrics := getResultMetrics(from)
+	toMetrics := getResultMetrics(to)
+
+	for _, column := range getSortedResultColumnKeys(fromMetrics, toMetrics) {
+		fromColumn, inFrom := fromMetrics[column]
+		toColumn, inTo := toMetrics[column]
+		switch {
+		case !inFrom:
+			tableDiff.AddedColumns = append(tableDiff.AddedColumns, column.columnName)
+			continue
+		case !inTo:
+			tableDiff.RemovedColumns = append(tableDiff.RemovedColumns, column.columnName)
+			continue
+		}
+
+		metricNames := map[string]bool{}
+		for metricName := range fromColumn {
+			metricNames[metricName] = true
+		}
+		for metricName := range toColumn {
+			metricNames[metricName] = true
+		}
+		for _, metricName := range getSortedKeys(metricNames) {
+			fromValue := fromColumn[metricName]
+			toValue := toColumn[metricName]
+			if fromValue.Equal(toValue) {
+				continue
+			}
+			tableDiff.MetricChanges = append(tableDiff.MetricChanges, newMetricDiff(column.columnName, metricName, column.isCustom, fromValue, toValue))
+		}
+	}
+
+	tableDiff.Status = DIFF_STATUS_UNCHANGED
+	hasRowCountChange := tableDiff.RowCountDelta != nil && *tableDiff.RowCountDelta != 0
+	if hasRowCountChange || len(tableDiff.AddedColumns) > 0 || len(tableDiff.RemovedColumns) > 0 || len(tableDiff.MetricChanges) > 0 {
+		tableDiff.Status = DIFF_STATUS_CHANGED
+	}
+
+	return tableDiff
+}
+
+func newMetricDiff(columnName string, metricName string, isCustom bool, from MetricValue, to MetricValue) MetricDiff {
+	metricDiff := MetricDiff{
+		ColumnName:     columnName,
+		MetricName:     metricName,
+		IsCustomColumn: isCustom,
+		From:           from,
+		To:             to,
+	}
+
+	if from.IsNumeric() && to.IsNumeric() {
+		absolute := to.Number - from.Number
+		metricDiff.AbsoluteDifference = &absolute
+		if from.Number != 0 {
+			relative := absolute / math.Abs(from.Number)
+			metricDiff.RelativeDifference = &relative
+		}
+	}
+
+	return metricDiff
+}
+
+//Identifies a default or custom column of a table result, a custom column may share its name with a default one
+type resultColumnKey struct {
+	columnName string
+	isCustom   bool
+}
+
+//Flattens the default and custom columns of a table result into one map of column to metrics
+func getResultMetrics(result *TableProfileResult) map[resultColumnKey]map[string]MetricValue {
+	columns := map[resultColumnKey]map[string]MetricValue{}
+	for columnName, column := range result.Columns {
+		columns[resultColumnKey{columnName: columnName}] = column.Metrics
+	}
+	for columnName, column := range result.CustomColumns {
+		columns[resultColumnKey{columnName: columnName, isCustom: true}] = map[string]MetricValue{
+			CUSTOM_COLUMN_METRIC_NAME: column.Value,
+		}
+	}
+	return columns
+}
+
+//Returns the columns in any of the results sorted by name, a default column before a custom one of the same name
+func getSortedResultColumnKeys(results ...map[resultColumnKey]map[string]MetricValue) []resultColumnKey {
+	keySet := map[resultColumnKey]bool{}
+	for _, columns := range results {
+		for key := range columns {
+			keySet[key] = true
+		}
+	}
+
+	keys := []resultColumnKey{}
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].columnName != keys[j].columnName {
+			return keys[i].columnName < keys[j].columnName
+		}
+		return !keys[i].isCustom && keys[j].isCustom
+	})
+	return keys
+}
+
+//WriteTable writes the diff as human readable aligned text
+func (d *ProfileDiff) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Profile %d -> %d\n\n", d.FromProfileRecordID, d.ToProfileRecordID)
+	fmt.Fprintln(tw, "TABLE\tSTATUS\tFROM ROWS\tTO ROWS\tDELTA")
+	for _, table := range d.Tables {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			table.TableName,
+			table.Status,
+			formatOptionalInt(table.FromRowCount),
+			formatOptionalInt(table.ToRowCount),
+			formatOptionalInt(table.RowCountDelta),
+		)
+	}
+
+	hasColumnChanges := false
+	for _, table := range d.Tables {
+		if len(table.AddedColumns) > 0 || len(table.RemovedColumns) > 0 || len(table.MetricChanges) > 0 {
+			hasColumnChanges = true
+			break
+		}
+	}
+
+	if hasColumnChanges {
+		fmt.Fprintln(tw, "\nTABLE\tCOLUMN\tMETRIC\tFROM\tTO\tABS DIFF\tREL DIFF")
+		for _, table := range d.Tables {
+			for _, columnName := range table.AddedColumns {
+				fmt.Fprintf(tw, "%s\t%s\t(column added)\t\t\t\t\n", table.TableName, columnName)
+			}
+			for _, columnName := range table.RemovedColumns {
+				fmt.Fprintf(tw, "%s\t%s\t(column removed)\t\t\t\t\n", table.TableName, columnName)
+			}
+			for _, change := range table.MetricChanges {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					table.TableName,
+					change.ColumnName,
+					change.MetricName,
+					change.From.String(),
+					change.To.String(),
+					formatOptionalFloat(change.AbsoluteDifference),
+					formatOptionalPercent(change.RelativeDifference),
+				)
+			}
+		}
+	}
+
+	return tw.Flush()
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return `-`
+	}
+	return fmt.Sprintf(`%d`, *value)
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return `-`
+	}
+	return fmt.Sprintf(`%g`, *value)
+}
+
+func formatOptionalPercent(value *float64) string {
+	if value == nil {
+		return `-`
+	}
+	return fmt.Sprintf(`%+.2f%%`, *value*100)
+}
+
+//Returns the keys of a string set in sorted order
+func getSortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
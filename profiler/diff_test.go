@@ -0,0 +1,218 @@
+package profiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+//Builds a table result from the metrics of its default columns and the values of its custom columns,
+//shared by the tests of everything that reads table results
+func newTestTableResult(tableName string, rowCount int, columns map[string]map[string]MetricValue, customValues map[string]MetricValue) *TableProfileResult {
+	result := &TableProfileResult{
+		TableName:     tableName,
+		RowCount:      &rowCount,
+		Columns:       map[string]*ColumnProfileResult{},
+		CustomColumns: map[string]*CustomColumnProfileResult{},
+	}
+	for columnName, metrics := range columns {
+		result.Columns[columnName] = &ColumnProfileResult{ColumnName: columnName, Metrics: metrics}
+	}
+	for columnName, value := range customValues {
+		result.CustomColumns[columnName] = &CustomColumnProfileResult{ColumnName: columnName, Value: value}
+	}
+	return result
+}
+
+func TestDiffTableProfileResults(t *testing.T) {
+	from := map[string]*TableProfileResult{
+		`orders`: newTestTableResult(`orders`, 100, map[string]map[string]MetricValue{
+			`total`:  {`maximum`: NewMetricValue(50), `minimum`: NewMetricValue(0)},
+			`status`: {`maximum`: NewMetricValue(`pending`)},
+			`notes`:  {`null_count`: NewMetricValue(1)},
+		}, map[string]MetricValue{`long_notes`: NewMetricValue(0)}),
+		`customers`: newTestTableResult(`customers`, 10, nil, nil),
+		`legacy`:    newTestTableResult(`legacy`, 5, nil, nil),
+	}
+	to := map[string]*TableProfileResult{
+		`orders`: newTestTableResult(`orders`, 150, map[string]map[string]MetricValue{
+			`total`:    {`maximum`: NewMetricValue(75), `minimum`: NewMetricValue(0)},
+			`status`:   {`maximum`: NewMetricValue(`shipped`)},
+			`discount`: {`maximum`: NewMetricValue(5)},
+		}, map[string]MetricValue{`long_notes`: NewMetricValue(2)}),
+		`customers`: newTestTableResult(`customers`, 10, nil, nil),
+		`invoices`:  newTestTableResult(`invoices`, 3, nil, nil),
+	}
+	from[`orders`].Metrics = map[string]MetricValue{FRESHNESS_LAG_METRIC_NAME: NewMetricValue(60)}
+	to[`orders`].Metrics = map[string]MetricValue{FRESHNESS_LAG_METRIC_NAME: NewMetricValue(30)}
+
+	diff := DiffTableProfileResults(from, to)
+
+	tableNames := []string{}
+	statuses := map[string]string{}
+	tables := map[string]TableDiff{}
+	for _, table := range diff.Tables {
+		tableNames = append(tableNames, table.TableName)
+		statuses[table.TableName] = table.Status
+		tables[table.TableName] = table
+	}
+	if strings.Join(tableNames, `,`) != `customers,invoices,legacy,orders` {
+		t.Errorf("tables = %v, want them sorted by name", tableNames)
+	}
+
+	wantStatuses := map[string]string{
+		`customers`: DIFF_STATUS_UNCHANGED,
+		`invoices`:  DIFF_STATUS_ADDED,
+		`legacy`:    DIFF_STATUS_REMOVED,
+		`orders`:    DIFF_STATUS_CHANGED,
+	}
+	for tableName, status := range wantStatuses {
+		if statuses[tableName] != status {
+			t.Errorf("%s status = %s, want %s", tableName, statuses[tableName], status)
+		}
+	}
+
+	if table := tables[`invoices`]; table.FromRowCount != nil || table.ToRowCount == nil || *table.ToRowCount != 3 {
+		t.Errorf("added table row counts = %v -> %v", table.FromRowCount, table.ToRowCount)
+	}
+	if table := tables[`legacy`]; table.ToRowCount != nil || table.FromRowCount == nil || *table.FromRowCount != 5 {
+		t.Errorf("removed table row counts = %v -> %v", table.FromRowCount, table.ToRowCount)
+	}
+
+	orders := tables[`orders`]
+	if orders.RowCountDelta == nil || *orders.RowCountDelta != 50 {
+		t.Errorf("row count delta = %v, want 50", orders.RowCountDelta)
+	}
+	if strings.Join(orders.AddedColumns, `,`) != `discount` {
+		t.Errorf("added columns = %v, want [discount]", orders.AddedColumns)
+	}
+	if strings.Join(orders.RemovedColumns, `,`) != `notes` {
+		t.Errorf("removed columns = %v, want [notes]", orders.RemovedColumns)
+	}
+
+	wantChanges := []struct {
+		columnName string
+		metricName string
+		isCustom   bool
+		absolute   *float64
+		relative   *float64
+	}{
+		{``, FRESHNESS_LAG_METRIC_NAME, false, floatPointer(-30), floatPointer(-0.5)},
+		{`long_notes`, CUSTOM_COLUMN_METRIC_NAME, true, floatPointer(2), nil},
+		{`status`, `maximum`, false, nil, nil},
+		{`total`, `maximum`, false, floatPointer(25), floatPointer(0.5)},
+	}
+	if len(orders.MetricChanges) != len(wantChanges) {
+		t.Fatalf("metric changes = %+v, want %d", orders.MetricChanges, len(wantChanges))
+	}
+	for idx, want := range wantChanges {
+		got := orders.MetricChanges[idx]
+		if got.ColumnName != want.columnName || got.MetricName != want.metricName || got.IsCustomColumn != want.isCustom {
+			t.Errorf("change %d = %s %s custom %v, want %s %s custom %v", idx, got.ColumnName, got.MetricName, got.IsCustomColumn, want.columnName, want.metricName, want.isCustom)
+		}
+		if !equalFloatPointers(got.AbsoluteDifference, want.absolute) {
+			t.Errorf("change %d absolute difference = %v, want %v", idx, formatOptionalFloat(got.AbsoluteDifference), formatOptionalFloat(want.absolute))
+		}
+		if !equalFloatPointers(got.RelativeDifference, want.relative) {
+			t.Errorf("change %d relative difference = %v, want %v", idx, formatOptionalFloat(got.RelativeDifference), formatOptionalFloat(want.relative))
+		}
+	}
+}
+
+func TestNewMetricDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         MetricValue
+		to           MetricValue
+		wantAbsolute *float64
+		wantRelative *float64
+	}{
+		{name: "increase", from: NewMetricValue(4), to: NewMetricValue(5), wantAbsolute: floatPointer(1), wantRelative: floatPointer(0.25)},
+		{name: "negative base", from: NewMetricValue(-4), to: NewMetricValue(-2), wantAbsolute: floatPointer(2), wantRelative: floatPointer(0.5)},
+		{name: "from zero", from: NewMetricValue(0), to: NewMetricValue(3), wantAbsolute: floatPointer(3)},
+		{name: "from null", from: MetricValue{}, to: NewMetricValue(3)},
+		{name: "text", from: NewMetricValue(`a`), to: NewMetricValue(`b`)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := newMetricDiff(`column`, `metric`, false, test.from, test.to)
+			if !equalFloatPointers(got.AbsoluteDifference, test.wantAbsolute) {
+				t.Errorf("absolute difference = %v, want %v", formatOptionalFloat(got.AbsoluteDifference), formatOptionalFloat(test.wantAbsolute))
+			}
+			if !equalFloatPointers(got.RelativeDifference, test.wantRelative) {
+				t.Errorf("relative difference = %v, want %v", formatOptionalFloat(got.RelativeDifference), formatOptionalFloat(test.wantRelative))
+			}
+		})
+	}
+}
+
+func TestProfileDiffWriteTable(t *testing.T) {
+	from := map[string]*TableProfileResult{
+		`orders`: newTestTableResult(`orders`, 100, map[string]map[string]MetricValue{
+			`total`: {`maximum`: NewMetricValue(50)},
+		}, nil),
+	}
+	to := map[string]*TableProfileResult{
+		`orders`: newTestTableResult(`orders`, 150, map[string]map[string]MetricValue{
+			`total`: {`maximum`: NewMetricValue(75)},
+		}, nil),
+	}
+	diff := DiffTableProfileResults(from, to)
+	diff.FromProfileRecordID = 1
+	diff.ToProfileRecordID = 2
+
+	var buffer bytes.Buffer
+	err := diff.WriteTable(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+	for _, want := range []string{`Profile 1 -> 2`, `orders  changed  100        150      50`, `+50.00%`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output is missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+func equalFloatPointers(a *float64, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return closeTo(*a, *b)
+}
+
+func TestCustomColumnSharingADefaultColumnName(t *testing.T) {
+	newResult := func(maximum float64, customValue float64) *TableProfileResult {
+		return newTestTableResult(`orders`, 10,
+			map[string]map[string]MetricValue{`total`: {`maximum`: NewMetricValue(maximum)}},
+			map[string]MetricValue{`total`: NewMetricValue(customValue)},
+		)
+	}
+
+	columns := getResultMetrics(newResult(5, 1))
+	keys := getSortedResultColumnKeys(columns)
+	if len(keys) != 2 || keys[0] != (resultColumnKey{columnName: `total`}) || keys[1] != (resultColumnKey{columnName: `total`, isCustom: true}) {
+		t.Fatalf("columns = %+v, want the default total before the custom one", keys)
+	}
+
+	//both columns are diffed instead of the custom one hiding the default one
+	diff := diffTableProfileResult(`orders`, newResult(5, 1), newResult(6, 2))
+	if len(diff.MetricChanges) != 2 {
+		t.Fatalf("metric changes = %+v", diff.MetricChanges)
+	}
+	if change := diff.MetricChanges[0]; change.IsCustomColumn || change.MetricName != `maximum` {
+		t.Errorf("first change = %+v, want the default column maximum", change)
+	}
+	if change := diff.MetricChanges[1]; !change.IsCustomColumn || change.MetricName != CUSTOM_COLUMN_METRIC_NAME {
+		t.Errorf("second change = %+v, want the custom column value", change)
+	}
+	if len(diff.AddedColumns) != 0 || len(diff.RemovedColumns) != 0 {
+		t.Errorf("added %v and removed %v columns", diff.AddedColumns, diff.RemovedColumns)
+	}
+}
@@ -0,0 +1,31 @@
+package profiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ParseDuration parses a duration the same as time.ParseDuration but also accepts
+//whole day and week units such as 1d or 2w, which are common for profile history
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	unitLengths := map[string]time.Duration{
+		`d`: 24 * time.Hour,
+		`w`: 7 * 24 * time.Hour,
+	}
+	for unit, length := range unitLengths {
+		if !strings.HasSuffix(value, unit) {
+			continue
+		}
+		count, err := strconv.ParseFloat(strings.TrimSuffix(value, unit), 64)
+		if err != nil {
+			return 0, fmt.Errorf(`invalid duration %v`, value)
+		}
+		return time.Duration(count * float64(length)), nil
+	}
+
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,47 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: `1d`, want: 24 * time.Hour},
+		{value: `2w`, want: 14 * 24 * time.Hour},
+		{value: `1.5d`, want: 36 * time.Hour},
+		{value: ` 30d `, want: 30 * 24 * time.Hour},
+		{value: `90m`, want: 90 * time.Minute},
+		{value: `1h30m`, want: 90 * time.Minute},
+		{value: `0s`, want: 0},
+		{value: `-1d`, want: -24 * time.Hour},
+		{value: ``, wantErr: true},
+		{value: `d`, wantErr: true},
+		{value: `oned`, wantErr: true},
+		{value: `1d12h`, wantErr: true},
+		{value: `10`, wantErr: true},
+		{value: `1y`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseDuration(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("duration = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package profiler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Derived column metric, the null count divided by the row count
+const NULL_RATIO_METRIC_NAME = `null_ratio`
+
+//ExpectationResult is the outcome of a single expectation against the metrics of a run
+type ExpectationResult struct {
+	TableName     string      `json:"TableName"`
+	Expectation   string      `json:"Expectation"`
+	Passed        bool        `json:"Passed"`
+	ObservedValue MetricValue `json:"ObservedValue"`
+	Message       string      `json:"Message"`
+}
+
+//A parsed expectation, exactly one of the comparisons is used
+type expectation struct {
+	source     string
+	tableName  string
+	columnName string
+	metricName string
+	isCustom   bool
+	//compare the metric to a number
+	operator  string
+	threshold float64
+	//or check a timestamp metric is within this long of the run time
+	within time.Duration
+}
+
+//Parses every expectation in the definition so bad syntax is caught before profiling
+func parseProfileExpectations(profile ProfileDefinition) ([]expectation, error) {
+	expectations := []expectation{}
+	for _, table := range profile.CustomProfileTables {
+		for _, source := range table.Expectations {
+			parsed, err := parseExpectation(table.TableName, source)
+			if err != nil {
+				return nil, err
+			}
+			expectations = append(expectations, parsed)
+		}
+	}
+	return expectations, nil
+}
+
+//Parses one of the following forms
+//	row_count > 1000
+//	column email null_ratio < 0.01
+//	custom description_over_128 == 0
+//	maximum(created_at) within 1d of now
+func parseExpectation(tableName string, source string) (expectation, error) {
+	parsed := expectation{
+		source:    source,
+		tableName: tableName,
+	}
+	invalid := fmt.Errorf(`invalid expectation "%s" for table %s`, source, tableName)
+
+	fields := strings.Fields(source)
+	if len(fields) == 0 {
+		return parsed, invalid
+	}
+
+	var comparison []string
+	switch {
+	case fields[0] == ROW_COUNT_METRIC_NAME:
+		parsed.metricName = ROW_COUNT_METRIC_NAME
+		comparison = fields[1:]
+	case fields[0] == `column` && len(fields) >= 3:
+		parsed.columnName = fields[1]
+		parsed.metricName = strings.ToLower(fields[2])
+		comparison = fields[3:]
+	case fields[0] == `custom` && len(fields) >= 2:
+		parsed.columnName = fields[1]
+		parsed.metricName = CUSTOM_COLUMN_METRIC_NAME
+		parsed.isCustom = true
+		comparison = fields[2:]
+	case strings.Contains(fields[0], `(`) && strings.HasSuffix(fields[0], `)`):
+		open := strings.Index(fields[0], `(`)
+		parsed.metricName = strings.ToLower(fields[0][:open])
+		parsed.columnName = fields[0][open+1 : len(fields[0])-1]
+		comparison = fields[1:]
+	default:
+		return parsed, invalid
+	}
+
+	switch {
+	case len(comparison) == 4 && comparison[0] == `within` && comparison[2] == `of` && comparison[3] == `now`:
+		within, err := ParseDuration(comparison[1])
+		if err != nil {
+			return parsed, invalid
+		}
+		parsed.within = within
+	case len(comparison) == 2 && isExpectationOperator(comparison[0]):
+		threshold, err := strconv.ParseFloat(comparison[1], 64)
+		if err != nil {
+			return parsed, invalid
+		}
+		parsed.operator = comparison[0]
+		parsed.threshold = threshold
+	default:
+		return parsed, invalid
+	}
+
+	return parsed, nil
+}
+
+func isExpectationOperator(operator string) bool {
+	switch operator {
+	case `>`, `>=`, `<`, `<=`, `==`, `!=`:
+		return true
+	}
+	return false
+}
+
+//Checks the expectation against the run, a metric that was not gathered fails the expectation
+func (e expectation) evaluate(run *RunResult) ExpectationResult {
+	result := ExpectationResult{
+		TableName:   e.tableName,
+		Expectation: e.source,
+	}
+
+	tableResult, ok := run.Tables[e.tableName]
+	if !ok {
+		result.Message = fmt.Sprintf(`table %s was not profiled`, e.tableName)
+		return result
+	}
+
+	value, err := e.getObservedValue(tableResult)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.ObservedValue = value
+
+	if e.within > 0 {
+		if value.Kind != METRIC_KIND_TIMESTAMP {
+			result.Message = fmt.Sprintf(`%s is not a timestamp`, e.describeMetric())
+			return result
+		}
+		age := run.StartTime.Sub(value.Time)
+		result.Passed = math.Abs(float64(age)) <= float64(e.within)
+		if !result.Passed {
+			result.Message = fmt.Sprintf(`%s is %v from the run time`, e.describeMetric(), age.Round(time.Second))
+		}
+		return result
+	}
+
+	if !value.IsNumeric() {
+		result.Message = fmt.Sprintf(`%s is not numeric`, e.describeMetric())
+		return result
+	}
+	result.Passed = compareExpectation(value.Number, e.operator, e.threshold)
+	if !result.Passed {
+		result.Message = fmt.Sprintf(`%s is %s`, e.describeMetric(), value.String())
+	}
+	return result
+}
+
+func (e expectation) getObservedValue(tableResult *TableProfileResult) (MetricValue, error) {
+	if e.metricName == ROW_COUNT_METRIC_NAME && e.columnName == `` {
+		if tableResult.RowCount == nil {
+			return MetricValue{}, fmt.Errorf(`row count was not gathered for table %s`, e.tableName)
+		}
+		return NewMetricValue(*tableResult.RowCount), nil
+	}
+
+	if e.isCustom {
+		customColumnNames := map[string]bool{}
+		for columnName := range tableResult.CustomColumns {
+			customColumnNames[columnName] = true
+		}
+		columnName, ok := findProfiledColumnName(customColumnNames, e.columnName)
+		if !ok {
+			return MetricValue{}, fmt.Errorf(`custom column %s was not profiled`, e.columnName)
+		}
+		return tableResult.CustomColumns[columnName].Value, nil
+	}
+
+	columnNames := map[string]bool{}
+	for columnName := range tableResult.Columns {
+		columnNames[columnName] = true
+	}
+	columnName, ok := findProfiledColumnName(columnNames, e.columnName)
+	if !ok {
+		return MetricValue{}, fmt.Errorf(`column %s was not profiled`, e.columnName)
+	}
+	column := tableResult.Columns[columnName]
+
+	if e.metricName == NULL_RATIO_METRIC_NAME {
+		nullCount, ok := column.Metrics[`null_count`]
+		if !ok || !nullCount.IsNumeric() || tableResult.RowCount == nil {
+			return MetricValue{}, fmt.Errorf(`null ratio could not be worked out for column %s`, e.columnName)
+		}
+		if *tableResult.RowCount == 0 {
+			return NewMetricValue(0), nil
+		}
+		return NewMetricValue(nullCount.Number / float64(*tableResult.RowCount)), nil
+	}
+
+	value, ok := column.Metrics[e.metricName]
+	if !ok {
+		return MetricValue{}, fmt.Errorf(`metric %s was not gathered for column %s`, e.metricName, e.columnName)
+	}
+	return value, nil
+}
+
+//Returns the profiled column the expectation names, an exact match is preferred over one that only differs in case.
+//Names are matched rather than lowercased since quoted column names keep their case.
+func findProfiledColumnName(columnNames map[string]bool, columnName string) (string, bool) {
+	if columnNames[columnName] {
+		return columnName, true
+	}
+	for _, name := range getSortedKeys(columnNames) {
+		if strings.EqualFold(name, columnName) {
+			return name, true
+		}
+	}
+	return ``, false
+}
+
+func (e expectation) describeMetric() string {
+	if e.columnName == `` {
+		return e.metricName
+	}
+	return fmt.Sprintf(`%s of %s`, e.metricName, e.columnName)
+}
+
+func compareExpectation(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case `>`:
+		return value > threshold
+	case `>=`:
+		return value >= threshold
+	case `<`:
+		return value < threshold
+	case `<=`:
+		return value <= threshold
+	case `==`:
+		return value == threshold
+	case `!=`:
+		return value != threshold
+	}
+	return false
+}
+
+//Evaluates the expectations against the run, adds the results to the run and records them in the store
+func (p *Profiler) evaluateExpectations(run *RunResult, expectations []expectation) error {
+	for _, e := range expectations {
+		result := e.evaluate(run)
+		if p.hasProfileStore() {
+			err := p.profileStore.RecordExpectationResult(run.ProfileRecordID, result)
+			if err != nil {
+				return err
+			}
+		}
+		run.ExpectationResults = append(run.ExpectationResults, result)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpectation(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    expectation
+		wantErr bool
+	}{
+		{
+			name:   "row count",
+			source: `row_count > 1000`,
+			want:   expectation{metricName: ROW_COUNT_METRIC_NAME, operator: `>`, threshold: 1000},
+		},
+		{
+			name:   "column metric",
+			source: `column Email null_ratio < 0.01`,
+			want:   expectation{columnName: `Email`, metricName: NULL_RATIO_METRIC_NAME, operator: `<`, threshold: 0.01},
+		},
+		{
+			name:   "custom column",
+			source: `custom description_over_128 == 0`,
+			want:   expectation{columnName: `description_over_128`, metricName: CUSTOM_COLUMN_METRIC_NAME, isCustom: true, operator: `==`, threshold: 0},
+		},
+		{
+			name:   "function form",
+			source: `MAXIMUM(created_at) >= -5`,
+			want:   expectation{columnName: `created_at`, metricName: `maximum`, operator: `>=`, threshold: -5},
+		},
+		{
+			name:   "within of now",
+			source: `maximum(created_at) within 1d of now`,
+			want:   expectation{columnName: `created_at`, metricName: `maximum`, within: 24 * time.Hour},
+		},
+		{name: "empty", source: ``, wantErr: true},
+		{name: "unknown metric form", source: `rows > 10`, wantErr: true},
+		{name: "unknown operator", source: `row_count => 10`, wantErr: true},
+		{name: "threshold not a number", source: `row_count > lots`, wantErr: true},
+		{name: "missing threshold", source: `row_count >`, wantErr: true},
+		{name: "column without metric", source: `column email`, wantErr: true},
+		{name: "bad duration", source: `maximum(created_at) within soon of now`, wantErr: true},
+		{name: "within without of now", source: `maximum(created_at) within 1d`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseExpectation(`orders`, test.source)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got %+v", test.source, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			test.want.source = test.source
+			test.want.tableName = `orders`
+			if got != test.want {
+				t.Errorf("parsed = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseProfileExpectations(t *testing.T) {
+	profile := ProfileDefinition{
+		CustomProfileTables: []TableDefinition{
+			{TableName: `orders`, Expectations: []string{`row_count > 0`, `column total minimum >= 0`}},
+			{TableName: `customers`, Expectations: []string{`row_count > 0`}},
+		},
+	}
+	expectations, err := parseProfileExpectations(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expectations) != 3 {
+		t.Fatalf("parsed %d expectations, want 3", len(expectations))
+	}
+	if expectations[2].tableName != `customers` {
+		t.Errorf("table name = %s, want customers", expectations[2].tableName)
+	}
+
+	profile.CustomProfileTables[1].Expectations = append(profile.CustomProfileTables[1].Expectations, `nonsense`)
+	if _, err := parseProfileExpectations(profile); err == nil {
+		t.Error("expected an error for a bad expectation")
+	}
+}
+
+func TestEvaluateExpectation(t *testing.T) {
+	startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rowCount := 200
+	emptyRowCount := 0
+
+	run := &RunResult{
+		StartTime: startTime,
+		Tables: map[string]*TableProfileResult{
+			`orders`: {
+				TableName: `orders`,
+				RowCount:  &rowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`email`: {Metrics: map[string]MetricValue{
+						`null_count`: NewMetricValue(10),
+					}},
+					`created_at`: {Metrics: map[string]MetricValue{
+						`maximum`: NewMetricValue(startTime.Add(-2 * time.Hour)),
+					}},
+					`status`: {Metrics: map[string]MetricValue{
+						`maximum`: NewMetricValue(`shipped`),
+					}},
+					`ShippedAt`: {Metrics: map[string]MetricValue{
+						`maximum`: NewMetricValue(startTime.Add(-time.Hour)),
+					}},
+				},
+				CustomColumns: map[string]*CustomColumnProfileResult{
+					`long_descriptions`: {Value: NewMetricValue(3)},
+					`LongNotes`:         {Value: NewMetricValue(4)},
+					`longnotes`:         {Value: NewMetricValue(5)},
+				},
+			},
+			`empty`: {
+				TableName: `empty`,
+				RowCount:  &emptyRowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`email`: {Metrics: map[string]MetricValue{
+						`null_count`: NewMetricValue(0),
+					}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		tableName    string
+		source       string
+		wantPassed   bool
+		wantObserved MetricValue
+		wantMessage  bool
+	}{
+		{name: "row count passes", tableName: `orders`, source: `row_count > 100`, wantPassed: true, wantObserved: NewMetricValue(200)},
+		{name: "row count fails", tableName: `orders`, source: `row_count < 100`, wantObserved: NewMetricValue(200), wantMessage: true},
+		{name: "null ratio passes", tableName: `orders`, source: `column email null_ratio <= 0.05`, wantPassed: true, wantObserved: NewMetricValue(0.05)},
+		{name: "null ratio fails", tableName: `orders`, source: `column email null_ratio < 0.01`, wantObserved: NewMetricValue(0.05), wantMessage: true},
+		{name: "null ratio of empty table", tableName: `empty`, source: `column email null_ratio == 0`, wantPassed: true, wantObserved: NewMetricValue(0)},
+		{name: "null ratio without null count", tableName: `orders`, source: `column status null_ratio < 1`, wantMessage: true},
+		{name: "custom column", tableName: `orders`, source: `custom long_descriptions != 0`, wantPassed: true, wantObserved: NewMetricValue(3)},
+		{name: "within passes", tableName: `orders`, source: `maximum(created_at) within 3h of now`, wantPassed: true, wantObserved: NewMetricValue(startTime.Add(-2 * time.Hour))},
+		{name: "within fails", tableName: `orders`, source: `maximum(created_at) within 1h of now`, wantObserved: NewMetricValue(startTime.Add(-2 * time.Hour)), wantMessage: true},
+		{name: "within on a non timestamp", tableName: `orders`, source: `maximum(status) within 1h of now`, wantObserved: NewMetricValue(`shipped`), wantMessage: true},
+		{name: "comparison on text", tableName: `orders`, source: `maximum(status) > 1`, wantObserved: NewMetricValue(`shipped`), wantMessage: true},
+		{name: "quoted mixed case column", tableName: `orders`, source: `maximum(ShippedAt) within 2h of now`, wantPassed: true, wantObserved: NewMetricValue(startTime.Add(-time.Hour))},
+		{name: "column differs in case", tableName: `orders`, source: `column EMAIL null_count == 10`, wantPassed: true, wantObserved: NewMetricValue(10)},
+		{name: "exact custom column preferred", tableName: `orders`, source: `custom longnotes == 5`, wantPassed: true, wantObserved: NewMetricValue(5)},
+		{name: "custom column differs in case", tableName: `orders`, source: `custom LONG_DESCRIPTIONS == 3`, wantPassed: true, wantObserved: NewMetricValue(3)},
+		{name: "metric not gathered", tableName: `orders`, source: `column email maximum > 1`, wantMessage: true},
+		{name: "column not profiled", tableName: `orders`, source: `column phone null_ratio < 1`, wantMessage: true},
+		{name: "custom column not profiled", tableName: `orders`, source: `custom missing > 1`, wantMessage: true},
+		{name: "table not profiled", tableName: `customers`, source: `row_count > 0`, wantMessage: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := parseExpectation(test.tableName, test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := parsed.evaluate(run)
+			if result.Passed != test.wantPassed {
+				t.Errorf("passed = %v, want %v: %s", result.Passed, test.wantPassed, result.Message)
+			}
+			if !closeToValue(result.ObservedValue, test.wantObserved) {
+				t.Errorf("observed = %v, want %v", result.ObservedValue, test.wantObserved)
+			}
+			if (result.Message != ``) != test.wantMessage {
+				t.Errorf("message = %q, want a message %v", result.Message, test.wantMessage)
+			}
+			if result.TableName != test.tableName || result.Expectation != test.source {
+				t.Errorf("result is for %s %q", result.TableName, result.Expectation)
+			}
+		})
+	}
+}
+
+func TestCompareExpectation(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    float64
+		want     bool
+	}{
+		{`>`, 2, true},
+		{`>`, 1, false},
+		{`>=`, 1, true},
+		{`<`, 0, true},
+		{`<`, 1, false},
+		{`<=`, 1, true},
+		{`==`, 1, true},
+		{`==`, 2, false},
+		{`!=`, 2, true},
+		{`!=`, 1, false},
+		{`=`, 1, false},
+	}
+
+	for _, test := range tests {
+		if got := compareExpectation(test.value, test.operator, 1); got != test.want {
+			t.Errorf("%v %s 1 = %v, want %v", test.value, test.operator, got, test.want)
+		}
+	}
+}
+
+//Numbers are compared with a tolerance since ratios are worked out in floating point
+func closeToValue(a MetricValue, b MetricValue) bool {
+	if a.IsNumeric() && b.IsNumeric() {
+		return closeTo(a.Number, b.Number)
+	}
+	return a.Equal(b)
+}
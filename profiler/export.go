@@ -0,0 +1,278 @@
+package profiler
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+//ExportOptions filters the profile history to export, zero values include everything.
+//Since is inclusive and Until is exclusive.
+type ExportOptions struct {
+	Since   time.Time
+	Until   time.Time
+	Tables  []string
+	Metrics []string
+}
+
+//ExportRow is a single metric value from the profile history.
+//Row counts and other table level metrics have an empty column name, row counts use the metric name ROW_COUNT_METRIC_NAME.
+type ExportRow struct {
+	ProfileRecordID int         `json:"ProfileRecordID"`
+	ProfileDate     time.Time   `json:"ProfileDate"`
+	TableName       string      `json:"TableName"`
+	ColumnName      string      `json:"ColumnName"`
+	ColumnType      string      `json:"ColumnType"`
+	IsCustom        bool        `json:"IsCustom"`
+	MetricName      string      `json:"MetricName"`
+	Value           MetricValue `json:"Value"`
+}
+
+//ExportHistory reads the profile history as one row per metric value,
+//ordered by run, then table, column and metric name. Each store table is read once,
+//limited to the selected runs and tables.
+func (p *ProfileStore) ExportHistory(options ExportOptions) ([]ExportRow, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []ProfileRun{}
+	runIDs := []int{}
+	for id, date := range registry.profileDates {
+		if date.Before(options.Since) {
+			continue
+		}
+		if !options.Until.IsZero() && !date.Before(options.Until) {
+			continue
+		}
+		runs = append(runs, ProfileRun{
+			ID:          id,
+			ProfileDate: date,
+		})
+		runIDs = append(runIDs, id)
+	}
+	sortProfileRuns(runs)
+
+	rows := []ExportRow{}
+	if len(runs) == 0 {
+		return rows, nil
+	}
+
+	wheres := map[string]interface{}{
+		PROFILE_RECORD_ID: runIDs,
+	}
+	columnWheres := map[string]interface{}{
+		PROFILE_RECORD_ID: runIDs,
+	}
+	customColumnWheres := map[string]interface{}{
+		PROFILE_RECORD_ID: runIDs,
+	}
+	if len(options.Tables) > 0 {
+		tableNameIDs := map[int]bool{}
+		for _, tableName := range options.Tables {
+			if id, err := registry.getTableNameID(tableName); err == nil {
+				tableNameIDs[id] = true
+			}
+		}
+		if len(tableNameIDs) == 0 {
+			return rows, nil
+		}
+
+		wheres[`table_name_id`] = getSortedIDs(tableNameIDs)
+		columnWheres[TABLE_COLUMN_NAME_ID] = registry.getColumnIDs(tableNameIDs)
+		customColumnWheres[TABLE_CUSTOM_COLUMN_NAME_ID] = registry.getCustomColumnIDs(tableNameIDs)
+	}
+
+	tableProfiles, err := p.readTableProfiles(wheres)
+	if err != nil {
+		return nil, err
+	}
+
+	tableMetrics, err := p.readTableMetrics(wheres)
+	if err != nil {
+		return nil, err
+	}
+
+	metricRows := []storedMetricRow{}
+	for _, columnType := range p.getMetricTableColumnTypes(registry.getColumnTypesInUse()) {
+		typeRows, err := p.readColumnMetricRows(columnType, columnWheres)
+		if err != nil {
+			return nil, err
+		}
+		metricRows = append(metricRows, typeRows...)
+	}
+
+	customMetricRows := []storedMetricRow{}
+	for _, columnType := range p.getMetricTableColumnTypes(registry.getCustomColumnTypesInUse()) {
+		typeRows, err := p.readCustomColumnMetricRows(columnType, customColumnWheres)
+		if err != nil {
+			return nil, err
+		}
+		customMetricRows = append(customMetricRows, typeRows...)
+	}
+
+	results := registry.buildTableProfileResults(tableProfiles, tableMetrics, metricRows, customMetricRows)
+
+	includeMetric := map[string]bool{}
+	for _, metricName := range options.Metrics {
+		includeMetric[metricName] = true
+	}
+	addRow := func(row ExportRow) {
+		if len(includeMetric) == 0 || includeMetric[row.MetricName] {
+			rows = append(rows, row)
+		}
+	}
+
+	for _, run := range runs {
+		runResults := results[run.ID]
+
+		tableNames := map[string]bool{}
+		for tableName := range runResults {
+			tableNames[tableName] = true
+		}
+
+		for _, tableName := range getSortedKeys(tableNames) {
+			result := runResults[tableName]
+			if result.RowCount != nil {
+				addRow(ExportRow{
+					ProfileRecordID: run.ID,
+					ProfileDate:     run.ProfileDate,
+					TableName:       tableName,
+					MetricName:      ROW_COUNT_METRIC_NAME,
+					Value:           NewMetricValue(*result.RowCount),
+				})
+			}
+
+			tableMetricNames := map[string]bool{}
+			for metricName := range result.Metrics {
+				tableMetricNames[metricName] = true
+			}
+			for _, metricName := range getSortedKeys(tableMetricNames) {
+				addRow(ExportRow{
+					ProfileRecordID: run.ID,
+					ProfileDate:     run.ProfileDate,
+					TableName:       tableName,
+					MetricName:      metricName,
+					Value:           result.Metrics[metricName],
+				})
+			}
+
+			columns := getResultMetrics(result)
+			for _, column := range getSortedResultColumnKeys(columns) {
+				columnType := ``
+				if column.isCustom {
+					columnType = result.CustomColumns[column.columnName].ColumnType
+				} else {
+					columnType = result.Columns[column.columnName].ColumnType
+				}
+
+				metricNames := map[string]bool{}
+				for metricName := range columns[column] {
+					metricNames[metricName] = true
+				}
+				for _, metricName := range getSortedKeys(metricNames) {
+					addRow(ExportRow{
+						ProfileRecordID: run.ID,
+						ProfileDate:     run.ProfileDate,
+						TableName:       tableName,
+						ColumnName:      column.columnName,
+						ColumnType:      columnType,
+						IsCustom:        column.isCustom,
+						MetricName:      metricName,
+						Value:           columns[column][metricName],
+					})
+				}
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+//GroupExportRowsByTable splits the rows by table name, keeping their order
+func GroupExportRowsByTable(rows []ExportRow) map[string][]ExportRow {
+	tables := map[string][]ExportRow{}
+	for _, row := range rows {
+		tables[row.TableName] = append(tables[row.TableName], row)
+	}
+	return tables
+}
+
+//WriteExportCSV writes the rows in long format, one line per metric value
+func WriteExportCSV(w io.Writer, rows []ExportRow) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{`profile_record_id`, `profile_date`, `table_name`, `column_name`, `column_type`, `is_custom`, `metric_name`, `value`})
+	for _, row := range rows {
+		writer.Write([]string{
+			strconv.Itoa(row.ProfileRecordID),
+			row.ProfileDate.Format(time.RFC3339),
+			row.TableName,
+			row.ColumnName,
+			row.ColumnType,
+			strconv.FormatBool(row.IsCustom),
+			row.MetricName,
+			formatExportValue(row.Value),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+//WriteTableExportCSV writes the rows of a single table with one line per run and column
+//and a field for each metric. The row count and other table metrics are on the line with an empty column name.
+func WriteTableExportCSV(w io.Writer, rows []ExportRow) error {
+	type lineKey struct {
+		profileRecordID int
+		columnName      string
+		isCustom        bool
+	}
+
+	metricNames := map[string]bool{}
+	lines := map[lineKey]map[string]MetricValue{}
+	//rows are ordered by run and column so the first row of each line keeps them in order
+	lineRows := []ExportRow{}
+	for _, row := range rows {
+		metricNames[row.MetricName] = true
+		key := lineKey{row.ProfileRecordID, row.ColumnName, row.IsCustom}
+		if _, ok := lines[key]; !ok {
+			lines[key] = map[string]MetricValue{}
+			lineRows = append(lineRows, row)
+		}
+		lines[key][row.MetricName] = row.Value
+	}
+	sortedMetricNames := getSortedKeys(metricNames)
+
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{`profile_record_id`, `profile_date`, `column_name`, `column_type`, `is_custom`}, sortedMetricNames...))
+	for _, row := range lineRows {
+		metrics := lines[lineKey{row.ProfileRecordID, row.ColumnName, row.IsCustom}]
+		record := []string{
+			strconv.Itoa(row.ProfileRecordID),
+			row.ProfileDate.Format(time.RFC3339),
+			row.ColumnName,
+			row.ColumnType,
+			strconv.FormatBool(row.IsCustom),
+		}
+		for _, metricName := range sortedMetricNames {
+			value, ok := metrics[metricName]
+			if !ok {
+				record = append(record, ``)
+				continue
+			}
+			record = append(record, formatExportValue(value))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+//Null values are left empty so they load as missing values
+func formatExportValue(value MetricValue) string {
+	if value.IsNull() {
+		return ``
+	}
+	return value.String()
+}
@@ -0,0 +1,98 @@
+package profiler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func newExportTestRows() []ExportRow {
+	firstDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	secondDate := firstDate.Add(24 * time.Hour)
+	return []ExportRow{
+		{ProfileRecordID: 1, ProfileDate: firstDate, TableName: `orders`, MetricName: ROW_COUNT_METRIC_NAME, Value: NewMetricValue(100)},
+		{ProfileRecordID: 1, ProfileDate: firstDate, TableName: `orders`, ColumnName: `notes`, ColumnType: `TEXT`, MetricName: `maximum`, Value: NewMetricValue(`say "hi", ok`)},
+		{ProfileRecordID: 1, ProfileDate: firstDate, TableName: `orders`, ColumnName: `total`, ColumnType: `NUMERIC`, MetricName: `maximum`, Value: NewMetricValue(9.5)},
+		{ProfileRecordID: 1, ProfileDate: firstDate, TableName: `orders`, ColumnName: `total`, ColumnType: `NUMERIC`, MetricName: `minimum`, Value: MetricValue{}},
+		{ProfileRecordID: 2, ProfileDate: secondDate, TableName: `orders`, MetricName: ROW_COUNT_METRIC_NAME, Value: NewMetricValue(120)},
+		{ProfileRecordID: 2, ProfileDate: secondDate, TableName: `orders`, ColumnName: `long_notes`, ColumnType: `INT8`, IsCustom: true, MetricName: CUSTOM_COLUMN_METRIC_NAME, Value: NewMetricValue(3)},
+	}
+}
+
+func TestWriteExportCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	err := WriteExportCSV(&buffer, newExportTestRows())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//nulls are left empty and text is quoted when needed
+	want := "profile_record_id,profile_date,table_name,column_name,column_type,is_custom,metric_name,value\n" +
+		"1,2024-05-01T00:00:00Z,orders,,,false,row_count,100\n" +
+		"1,2024-05-01T00:00:00Z,orders,notes,TEXT,false,maximum,\"say \"\"hi\"\", ok\"\n" +
+		"1,2024-05-01T00:00:00Z,orders,total,NUMERIC,false,maximum,9.5\n" +
+		"1,2024-05-01T00:00:00Z,orders,total,NUMERIC,false,minimum,\n" +
+		"2,2024-05-02T00:00:00Z,orders,,,false,row_count,120\n" +
+		"2,2024-05-02T00:00:00Z,orders,long_notes,INT8,true,value,3\n"
+	if buffer.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", buffer.String(), want)
+	}
+}
+
+func TestWriteTableExportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []ExportRow
+		want string
+	}{
+		{
+			name: "one line per run and column",
+			rows: newExportTestRows(),
+			want: "profile_record_id,profile_date,column_name,column_type,is_custom,maximum,minimum,row_count,value\n" +
+				"1,2024-05-01T00:00:00Z,,,false,,,100,\n" +
+				"1,2024-05-01T00:00:00Z,notes,TEXT,false,\"say \"\"hi\"\", ok\",,,\n" +
+				"1,2024-05-01T00:00:00Z,total,NUMERIC,false,9.5,,,\n" +
+				"2,2024-05-02T00:00:00Z,,,false,,,120,\n" +
+				"2,2024-05-02T00:00:00Z,long_notes,INT8,true,,,,3\n",
+		},
+		{
+			name: "custom column with a default column name",
+			rows: []ExportRow{
+				{ProfileRecordID: 1, ColumnName: `total`, ColumnType: `NUMERIC`, MetricName: `maximum`, Value: NewMetricValue(9)},
+				{ProfileRecordID: 1, ColumnName: `total`, ColumnType: `INT8`, IsCustom: true, MetricName: CUSTOM_COLUMN_METRIC_NAME, Value: NewMetricValue(2)},
+			},
+			want: "profile_record_id,profile_date,column_name,column_type,is_custom,maximum,value\n" +
+				"1,0001-01-01T00:00:00Z,total,NUMERIC,false,9,\n" +
+				"1,0001-01-01T00:00:00Z,total,INT8,true,,2\n",
+		},
+		{
+			name: "no rows",
+			rows: []ExportRow{},
+			want: "profile_record_id,profile_date,column_name,column_type,is_custom\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			err := WriteTableExportCSV(&buffer, test.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buffer.String() != test.want {
+				t.Errorf("csv =\n%s\nwant\n%s", buffer.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestGroupExportRowsByTable(t *testing.T) {
+	rows := append(newExportTestRows(), ExportRow{ProfileRecordID: 1, TableName: `customers`, MetricName: ROW_COUNT_METRIC_NAME})
+	tables := GroupExportRowsByTable(rows)
+	if len(tables) != 2 || len(tables[`orders`]) != 6 || len(tables[`customers`]) != 1 {
+		t.Fatalf("tables = %v", tables)
+	}
+	if tables[`orders`][5].ColumnName != `long_notes` {
+		t.Errorf("rows are out of order: %+v", tables[`orders`])
+	}
+}
@@ -0,0 +1,150 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//InfluxDB line protocol measurement names
+const INFLUX_TABLE_MEASUREMENT = `profiler_table`
+const INFLUX_COLUMN_MEASUREMENT = `profiler_column`
+const INFLUX_RUN_MEASUREMENT = `profiler_run`
+
+//WritePrometheusTextfile writes the run's metrics to a node_exporter textfile collector .prom file.
+//The file is replaced atomically so the collector never reads a partial file.
+func WritePrometheusTextfile(path string, run *RunResult, runErr error) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WritePrometheusMetrics(w, run, runErr)
+	})
+}
+
+//WriteInfluxFile writes the run's metrics to a file in the InfluxDB line protocol, replacing it atomically
+func WriteInfluxFile(path string, run *RunResult) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return WriteInfluxLineProtocol(w, run)
+	})
+}
+
+//WriteInfluxLineProtocol writes one line per table with its row count, one line per column with
+//its metrics as fields and one line for the run, all stamped with the run's start time.
+//Numeric metrics are floats, timestamps are unix seconds and text metrics are strings, null metrics are left out.
+func WriteInfluxLineProtocol(w io.Writer, run *RunResult) error {
+	b := &strings.Builder{}
+	timestamp := run.StartTime.UnixNano()
+
+	tableNames := map[string]bool{}
+	for tableName := range run.Tables {
+		tableNames[tableName] = true
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		result := run.Tables[tableName]
+		tableTags := fmt.Sprintf(`table=%s`, escapeInfluxTag(tableName))
+		if result.RowCount != nil {
+			fmt.Fprintf(b, "%s,%s row_count=%d %d\n", INFLUX_TABLE_MEASUREMENT, tableTags, *result.RowCount, timestamp)
+		}
+
+		columns := getResultMetrics(result)
+		for _, column := range getSortedResultColumnKeys(columns) {
+			metricNames := map[string]bool{}
+			for metricName := range columns[column] {
+				metricNames[metricName] = true
+			}
+
+			fields := []string{}
+			for _, metricName := range getSortedKeys(metricNames) {
+				value, ok := formatInfluxField(columns[column][metricName])
+				if ok {
+					fields = append(fields, fmt.Sprintf(`%s=%s`, escapeInfluxTag(metricName), value))
+				}
+			}
+			//a line needs at least one field
+			if len(fields) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(b, "%s,%s,column=%s %s %d\n",
+				INFLUX_COLUMN_MEASUREMENT,
+				tableTags,
+				escapeInfluxTag(column.columnName),
+				strings.Join(fields, `,`),
+				timestamp,
+			)
+		}
+	}
+
+	if !run.EndTime.IsZero() {
+		fmt.Fprintf(b, "%s duration_seconds=%s,table_count=%di %d\n",
+			INFLUX_RUN_MEASUREMENT,
+			strconv.FormatFloat(run.DurationSeconds, 'f', -1, 64),
+			len(run.Tables),
+			timestamp,
+		)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatInfluxField(value MetricValue) (string, bool) {
+	switch value.Kind {
+	case METRIC_KIND_NUMERIC:
+		return strconv.FormatFloat(value.Number, 'f', -1, 64), true
+	case METRIC_KIND_TIMESTAMP:
+		return strconv.FormatFloat(float64(value.Time.UnixNano())/1e9, 'f', -1, 64), true
+	case METRIC_KIND_TEXT:
+		text := strings.Replace(value.Text, `\`, `\\`, -1)
+		return `"` + strings.Replace(text, `"`, `\"`, -1) + `"`, true
+	}
+	return ``, false
+}
+
+//Escapes commas, equals signs and spaces in tag keys, tag values and field keys
+func escapeInfluxTag(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `,`, `\,`, -1)
+	value = strings.Replace(value, `=`, `\=`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	return strings.Replace(value, ` `, `\ `, -1)
+}
+
+//Writes to a temporary file next to the path and renames it into place once complete
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	dir, name := filepath.Split(path)
+	if dir == `` {
+		dir = `.`
+	}
+
+	//the temporary file starts with a dot so collectors globbing for *.prom skip it
+	file, err := ioutil.TempFile(dir, `.`+name+`.tmp`)
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+
+	err = write(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		//temporary files are created readable only by the owner
+		err = os.Chmod(tempPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf(`error writing %s: %v`, path, err)
+	}
+
+	return nil
+}
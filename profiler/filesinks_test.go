@@ -0,0 +1,150 @@
+package profiler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeInfluxTag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`orders`, `orders`},
+		{`order items`, `order\ items`},
+		{`a,b`, `a\,b`},
+		{`a=b`, `a\=b`},
+		{`C:\data`, `C:\\data`},
+		{"two\nlines", `two\nlines`},
+		{`"quoted"`, `"quoted"`},
+		{`a\,b`, `a\\\,b`},
+	}
+
+	for _, test := range tests {
+		if got := escapeInfluxTag(test.value); got != test.want {
+			t.Errorf("escape %q = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFormatInfluxField(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  MetricValue
+		want   string
+		wantOK bool
+	}{
+		{name: "number", value: NewMetricValue(1.5), want: `1.5`, wantOK: true},
+		{name: "large number", value: NewMetricValue(1e21), want: `1000000000000000000000`, wantOK: true},
+		{name: "timestamp", value: NewMetricValue(time.Unix(1714564800, 500000000)), want: `1714564800.5`, wantOK: true},
+		{name: "text", value: NewMetricValue(`shipped`), want: `"shipped"`, wantOK: true},
+		{name: "text with quotes and backslashes", value: NewMetricValue(`say "hi" \o/`), want: `"say \"hi\" \\o/"`, wantOK: true},
+		{name: "text with spaces and commas", value: NewMetricValue(`a, b=c`), want: `"a, b=c"`, wantOK: true},
+		{name: "null", value: MetricValue{}, wantOK: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := formatInfluxField(test.value)
+			if ok != test.wantOK || got != test.want {
+				t.Errorf("field = %s %v, want %s %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestWriteInfluxLineProtocol(t *testing.T) {
+	rowCount := 3
+	startTime := time.Unix(1714564800, 0)
+	run := &RunResult{
+		StartTime:       startTime,
+		EndTime:         startTime.Add(2 * time.Second),
+		DurationSeconds: 2.5,
+		Tables: map[string]*TableProfileResult{
+			`order items`: {
+				RowCount: &rowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`unit,price`: {Metrics: map[string]MetricValue{
+						`maximum`: NewMetricValue(9.5),
+						`minimum`: MetricValue{},
+					}},
+					`all_null`: {Metrics: map[string]MetricValue{
+						`maximum`: MetricValue{},
+					}},
+				},
+				CustomColumns: map[string]*CustomColumnProfileResult{
+					`status`: {Value: NewMetricValue(`shipped`)},
+				},
+			},
+		},
+	}
+
+	var buffer bytes.Buffer
+	err := WriteInfluxLineProtocol(&buffer, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		`profiler_table,table=order\ items row_count=3 1714564800000000000`,
+		`profiler_column,table=order\ items,column=status value="shipped" 1714564800000000000`,
+		`profiler_column,table=order\ items,column=unit\,price maximum=9.5 1714564800000000000`,
+		`profiler_run duration_seconds=2.5,table_count=1i 1714564800000000000`,
+	}, "\n") + "\n"
+	if buffer.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buffer.String(), want)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, `profile.prom`)
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "first\n")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a failed write must leave the previous file in place and no temporary file behind
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New(`boom`)
+	})
+	if err == nil {
+		t.Fatal("expected the write error to be returned")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\n" {
+		t.Errorf("file = %q, want the first write", data)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("dir has %d files, want only the written one", len(entries))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("file mode = %v, want 0644", info.Mode().Perm())
+	}
+}
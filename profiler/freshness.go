@@ -0,0 +1,142 @@
+package profiler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Column metric holding the newest value of a timestamp column
+const MAXIMUM_METRIC_NAME = `maximum`
+
+//Table metric holding the freshness lag so it can be trended like the row count
+const FRESHNESS_LAG_METRIC_NAME = `freshness_lag_seconds`
+
+//FreshnessResult is how far the newest row of a table lags behind the run time.
+//NewestValue and LagSeconds are nil when the table has no rows with a value in the column.
+type FreshnessResult struct {
+	TableName           string     `json:"TableName"`
+	ColumnName          string     `json:"ColumnName"`
+	NewestValue         *time.Time `json:"NewestValue"`
+	LagSeconds          *float64   `json:"LagSeconds"`
+	MaxStalenessSeconds *float64   `json:"MaxStalenessSeconds"`
+	IsStale             bool       `json:"IsStale"`
+}
+
+//A parsed freshness setting of a table definition, a zero max staleness only records the lag
+type freshnessCheck struct {
+	tableName    string
+	columnName   string
+	maxStaleness time.Duration
+}
+
+//Parses the freshness settings of every table so bad durations are caught before profiling
+func parseFreshnessChecks(profile ProfileDefinition) ([]freshnessCheck, error) {
+	checks := []freshnessCheck{}
+	for _, table := range profile.CustomProfileTables {
+		if table.FreshnessColumn == `` {
+			if table.MaxStaleness != `` {
+				return nil, fmt.Errorf(`table %s has a MaxStaleness but no FreshnessColumn`, table.TableName)
+			}
+			continue
+		}
+
+		check := freshnessCheck{
+			tableName:  table.TableName,
+			columnName: strings.ToLower(table.FreshnessColumn),
+		}
+		if table.MaxStaleness != `` {
+			maxStaleness, err := ParseDuration(table.MaxStaleness)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid MaxStaleness for table %s: %v`, table.TableName, err)
+			}
+			check.maxStaleness = maxStaleness
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+//Works out the lag of each freshness column, adds the results to the run and records them in the store
+func (p *Profiler) checkFreshness(run *RunResult, checks []freshnessCheck) error {
+	for _, check := range checks {
+		newestValue, err := p.getNewestValue(run, check)
+		if err != nil {
+			return err
+		}
+
+		result := FreshnessResult{
+			TableName:  check.tableName,
+			ColumnName: check.columnName,
+		}
+		if check.maxStaleness > 0 {
+			maxStalenessSeconds := check.maxStaleness.Seconds()
+			result.MaxStalenessSeconds = &maxStalenessSeconds
+		}
+
+		if newestValue.Kind == METRIC_KIND_TIMESTAMP {
+			lagSeconds := run.StartTime.Sub(newestValue.Time).Seconds()
+			result.NewestValue = &newestValue.Time
+			result.LagSeconds = &lagSeconds
+			result.IsStale = check.maxStaleness > 0 && lagSeconds > check.maxStaleness.Seconds()
+			run.setTableMetric(check.tableName, FRESHNESS_LAG_METRIC_NAME, lagSeconds)
+		} else {
+			//an empty table has no fresh rows at all
+			result.IsStale = check.maxStaleness > 0
+		}
+
+		if p.hasProfileStore() {
+			err = p.profileStore.RecordTableFreshness(run.ProfileRecordID, result)
+			if err != nil {
+				return err
+			}
+			if result.LagSeconds != nil {
+				err = p.profileStore.RecordTableMetric(check.tableName, run.ProfileRecordID, FRESHNESS_LAG_METRIC_NAME, *result.LagSeconds)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		run.Freshness = append(run.Freshness, result)
+	}
+	return nil
+}
+
+//Uses the maximum already gathered for the column when it was profiled, otherwise queries it
+func (p *Profiler) getNewestValue(run *RunResult, check freshnessCheck) (MetricValue, error) {
+	if tableResult, ok := run.Tables[check.tableName]; ok {
+		if column, ok := tableResult.Columns[check.columnName]; ok {
+			if maximum, ok := column.Metrics[MAXIMUM_METRIC_NAME]; ok {
+				return check.checkNewestValue(maximum)
+			}
+		}
+	}
+
+	rows, err := p.targetDBConn.GetRowsSelect(check.tableName, []string{fmt.Sprintf(`max("%s")`, check.columnName)})
+	if err != nil {
+		return MetricValue{}, err
+	}
+	defer rows.Close()
+
+	var newestValue interface{}
+	if rows.Next() {
+		err = rows.Scan(&newestValue)
+		if err != nil {
+			return MetricValue{}, err
+		}
+	}
+
+	value, err := check.checkNewestValue(NewMetricValue(newestValue))
+	if err != nil {
+		return MetricValue{}, err
+	}
+	return value, rows.Err()
+}
+
+//The newest value is null when the table is empty, otherwise it has to be a timestamp
+func (c freshnessCheck) checkNewestValue(value MetricValue) (MetricValue, error) {
+	if !value.IsNull() && value.Kind != METRIC_KIND_TIMESTAMP {
+		return MetricValue{}, fmt.Errorf(`freshness column %s on table %s is not a timestamp`, c.columnName, c.tableName)
+	}
+	return value, nil
+}
@@ -0,0 +1,48 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetNewestValueFromProfiledMaximum(t *testing.T) {
+	newest := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		maximum MetricValue
+		wantErr bool
+	}{
+		{name: "timestamp", maximum: NewMetricValue(newest)},
+		{name: "empty table", maximum: MetricValue{}},
+		{name: "number", maximum: NewMetricValue(42), wantErr: true},
+		{name: "text", maximum: NewMetricValue(`2024-05-01`), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run := newRunResult(0)
+			run.Tables[`orders`] = &TableProfileResult{
+				TableName: `orders`,
+				Columns: map[string]*ColumnProfileResult{
+					`created_at`: {Metrics: map[string]MetricValue{MAXIMUM_METRIC_NAME: test.maximum}},
+				},
+			}
+
+			//the profiled maximum is used so the profiler never queries the database
+			value, err := (&Profiler{}).getNewestValue(run, freshnessCheck{tableName: `orders`, columnName: `created_at`})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %v", test.maximum)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !value.Equal(test.maximum) {
+				t.Errorf("newest value = %v, want %v", value, test.maximum)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package profiler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Name of the test suite holding the run error when a run fails
+const JUNIT_RUN_SUITE_NAME = `profiler`
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+//WriteJUnit writes the run as a JUnit XML report for CI systems.
+//Each table is a test suite with a test case for its row count, every column and every check on it:
+//expectations, freshness and relationships. Anomalies fail the row count or column test case they belong to.
+//Failures carry the metric values, a run error is reported as an error in a suite named JUNIT_RUN_SUITE_NAME.
+func WriteJUnit(w io.Writer, run *RunResult, runErr error) error {
+	report := junitTestSuites{
+		Name:   JUNIT_RUN_SUITE_NAME,
+		Time:   formatJUnitSeconds(0),
+		Suites: []junitTestSuite{},
+	}
+
+	if run != nil {
+		report.Time = formatJUnitSeconds(run.DurationSeconds)
+		for _, tableName := range getJUnitTableNames(run) {
+			report.Suites = append(report.Suites, getJUnitTableSuite(run, tableName))
+		}
+	}
+
+	if runErr != nil {
+		report.Suites = append(report.Suites, junitTestSuite{
+			Name:   JUNIT_RUN_SUITE_NAME,
+			Tests:  1,
+			Errors: 1,
+			Time:   formatJUnitSeconds(0),
+			Cases: []junitTestCase{
+				{
+					Name:      `run`,
+					ClassName: JUNIT_RUN_SUITE_NAME,
+					Error: &junitFailure{
+						Message: runErr.Error(),
+						Type:    `error`,
+						Text:    runErr.Error(),
+					},
+				},
+			},
+		})
+	}
+
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent(``, `  `)
+	err = encoder.Encode(report)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+//Returns the profiled tables along with any table that only has checks, sorted
+func getJUnitTableNames(run *RunResult) []string {
+	tableNames := map[string]bool{}
+	for tableName := range run.Tables {
+		tableNames[tableName] = true
+	}
+	for _, result := range run.ExpectationResults {
+		tableNames[result.TableName] = true
+	}
+	for _, result := range run.Freshness {
+		tableNames[result.TableName] = true
+	}
+	for _, relationship := range run.Relationships {
+		tableNames[relationship.TableName] = true
+	}
+	return getSortedKeys(tableNames)
+}
+
+func getJUnitTableSuite(run *RunResult, tableName string) junitTestSuite {
+	suite := junitTestSuite{
+		Name:      tableName,
+		Time:      formatJUnitSeconds(run.TableDurationSeconds[tableName]),
+		Timestamp: run.StartTime.Format(`2006-01-02T15:04:05`),
+		Cases:     []junitTestCase{},
+	}
+
+	//anomalies keyed by column name, table metrics have an empty column name
+	anomalies := map[string][]Anomaly{}
+	for _, anomaly := range run.Anomalies {
+		if anomaly.TableName == tableName {
+			anomalies[anomaly.ColumnName] = append(anomalies[anomaly.ColumnName], anomaly)
+		}
+	}
+
+	if result, ok := run.Tables[tableName]; ok {
+		if result.RowCount != nil {
+			rowCount := strconv.Itoa(*result.RowCount)
+			suite.Properties = &junitProperties{
+				Properties: []junitProperty{{Name: `row_count`, Value: rowCount}},
+			}
+			suite.Cases = append(suite.Cases, getJUnitMetricsCase(tableName, `row_count`,
+				map[string]MetricValue{`row_count`: NewMetricValue(*result.RowCount)},
+				anomalies[``],
+			))
+		}
+
+		columns := getResultMetrics(result)
+		for _, column := range getSortedResultColumnKeys(columns) {
+			suite.Cases = append(suite.Cases, getJUnitMetricsCase(tableName, column.columnName, columns[column], anomalies[column.columnName]))
+		}
+	}
+
+	for _, result := range run.ExpectationResults {
+		if result.TableName != tableName {
+			continue
+		}
+		testCase := junitTestCase{
+			Name:      `expect ` + result.Expectation,
+			ClassName: tableName,
+		}
+		if !result.Passed {
+			testCase.Failure = &junitFailure{
+				Message: result.Message,
+				Type:    `expectation`,
+				Text:    fmt.Sprintf("%s\nobserved value = %s\n", result.Expectation, result.ObservedValue),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for _, result := range run.Freshness {
+		if result.TableName != tableName {
+			continue
+		}
+		testCase := junitTestCase{
+			Name:      `freshness ` + result.ColumnName,
+			ClassName: tableName,
+		}
+		if result.IsStale {
+			text := fmt.Sprintf("max_staleness_seconds = %s\n", formatJUnitSeconds(*result.MaxStalenessSeconds))
+			message := fmt.Sprintf(`no rows with a %s value`, result.ColumnName)
+			if result.LagSeconds != nil {
+				text += fmt.Sprintf("lag_seconds = %s\nnewest_value = %s\n", formatJUnitSeconds(*result.LagSeconds), result.NewestValue.Format(time.RFC3339))
+				message = fmt.Sprintf(`newest %s is older than allowed`, result.ColumnName)
+			}
+			testCase.Failure = &junitFailure{
+				Message: message,
+				Type:    `freshness`,
+				Text:    text,
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for _, relationship := range run.Relationships {
+		if relationship.TableName != tableName {
+			continue
+		}
+		testCase := junitTestCase{
+			Name: fmt.Sprintf(`relationship (%s) references %s (%s)`,
+				strings.Join(relationship.Columns, `, `),
+				relationship.ReferencedTable,
+				strings.Join(relationship.ReferencedColumns, `, `),
+			),
+			ClassName: tableName,
+		}
+		if relationship.OrphanCount > 0 {
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf(`%d orphan rows`, relationship.OrphanCount),
+				Type:    `relationship`,
+				Text:    fmt.Sprintf("orphan_count = %d\n", relationship.OrphanCount),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for _, testCase := range suite.Cases {
+		suite.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	return suite
+}
+
+//A test case listing the metrics, failed when any of them are anomalies
+func getJUnitMetricsCase(tableName string, name string, metrics map[string]MetricValue, anomalies []Anomaly) junitTestCase {
+	metricNames := map[string]bool{}
+	for metricName := range metrics {
+		metricNames[metricName] = true
+	}
+	lines := []string{}
+	for _, metricName := range getSortedKeys(metricNames) {
+		lines = append(lines, fmt.Sprintf(`%s = %s`, metricName, metrics[metricName]))
+	}
+	metricText := strings.Join(lines, "\n") + "\n"
+
+	testCase := junitTestCase{
+		Name:      name,
+		ClassName: tableName,
+		SystemOut: &junitOutput{Text: metricText},
+	}
+
+	if len(anomalies) > 0 {
+		text := ``
+		for _, anomaly := range anomalies {
+			text += fmt.Sprintf("anomaly in %s: %v, expected %v +/- %v (%s)\n",
+				anomaly.MetricName,
+				anomaly.Value,
+				anomaly.ExpectedValue,
+				anomaly.Spread,
+				anomaly.Method,
+			)
+		}
+		testCase.Failure = &junitFailure{
+			Message: fmt.Sprintf(`%d anomalous metrics`, len(anomalies)),
+			Type:    `anomaly`,
+			Text:    text + metricText,
+		}
+	}
+
+	return testCase
+}
+
+func formatJUnitSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
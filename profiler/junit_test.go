@@ -0,0 +1,137 @@
+package profiler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	startTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rowCount := 200
+	lag := 7200.0
+	maxStaleness := 3600.0
+	newest := startTime.Add(-2 * time.Hour)
+
+	run := &RunResult{
+		StartTime:       startTime,
+		DurationSeconds: 1.5,
+		Tables: map[string]*TableProfileResult{
+			`orders`: {
+				TableName: `orders`,
+				RowCount:  &rowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`total`: {Metrics: map[string]MetricValue{`maximum`: NewMetricValue(90)}},
+				},
+				CustomColumns: map[string]*CustomColumnProfileResult{},
+			},
+		},
+		Anomalies: []Anomaly{
+			{TableName: `orders`, ColumnName: `total`, MetricName: `maximum`, Value: 90, ExpectedValue: 10, Spread: 2, Method: ANOMALY_METHOD_STDDEV},
+		},
+		ExpectationResults: []ExpectationResult{
+			{TableName: `orders`, Expectation: `row_count > 100`, Passed: true, ObservedValue: NewMetricValue(200)},
+			{TableName: `orders`, Expectation: `row_count > 1000`, ObservedValue: NewMetricValue(200), Message: `row_count is 200`},
+		},
+		Freshness: []FreshnessResult{
+			{TableName: `orders`, ColumnName: `created_at`, NewestValue: &newest, LagSeconds: &lag, MaxStalenessSeconds: &maxStaleness, IsStale: true},
+		},
+		//a table that only has checks still gets a suite
+		Relationships: []RelationshipResult{
+			{TableName: `order_lines`, Columns: []string{`order_id`}, ReferencedTable: `orders`, ReferencedColumns: []string{`id`}, OrphanCount: 3},
+		},
+		TableDurationSeconds: map[string]float64{`orders`: 0.25},
+	}
+
+	tests := []struct {
+		name         string
+		run          *RunResult
+		runErr       error
+		wantSuites   []string
+		wantTests    int
+		wantFailures int
+		wantErrors   int
+	}{
+		{name: "run with failures", run: run, wantSuites: []string{`order_lines`, `orders`}, wantTests: 6, wantFailures: 4},
+		{name: "failed run with results", run: run, runErr: errors.New(`connection refused`), wantSuites: []string{`order_lines`, `orders`, JUNIT_RUN_SUITE_NAME}, wantTests: 7, wantFailures: 4, wantErrors: 1},
+		{name: "failed run without results", runErr: errors.New(`connection refused`), wantSuites: []string{JUNIT_RUN_SUITE_NAME}, wantTests: 1, wantErrors: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			err := WriteJUnit(&buffer, test.run, test.runErr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(buffer.String(), xml.Header) {
+				t.Error("report is missing the xml header")
+			}
+
+			var report junitTestSuites
+			err = xml.Unmarshal(buffer.Bytes(), &report)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			suiteNames := []string{}
+			for _, suite := range report.Suites {
+				suiteNames = append(suiteNames, suite.Name)
+			}
+			if strings.Join(suiteNames, `,`) != strings.Join(test.wantSuites, `,`) {
+				t.Errorf("suites = %v, want %v", suiteNames, test.wantSuites)
+			}
+			if report.Tests != test.wantTests || report.Failures != test.wantFailures || report.Errors != test.wantErrors {
+				t.Errorf("tests, failures, errors = %d, %d, %d, want %d, %d, %d", report.Tests, report.Failures, report.Errors, test.wantTests, test.wantFailures, test.wantErrors)
+			}
+		})
+	}
+}
+
+func TestGetJUnitTableSuite(t *testing.T) {
+	rowCount := 200
+	run := &RunResult{
+		StartTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Tables: map[string]*TableProfileResult{
+			`orders`: {
+				TableName: `orders`,
+				RowCount:  &rowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`total`: {Metrics: map[string]MetricValue{`maximum`: NewMetricValue(90), `minimum`: NewMetricValue(1)}},
+				},
+				CustomColumns: map[string]*CustomColumnProfileResult{},
+			},
+		},
+		Anomalies: []Anomaly{
+			{TableName: `orders`, MetricName: ROW_COUNT_METRIC_NAME, Value: 200, ExpectedValue: 20, Spread: 5, Method: ANOMALY_METHOD_MAD},
+			{TableName: `customers`, ColumnName: `total`, MetricName: `maximum`},
+		},
+		TableDurationSeconds: map[string]float64{`orders`: 0.25},
+	}
+
+	suite := getJUnitTableSuite(run, `orders`)
+	if suite.Time != `0.250` || suite.Timestamp != `2024-05-01T12:00:00` {
+		t.Errorf("suite time = %s at %s", suite.Time, suite.Timestamp)
+	}
+	if suite.Properties == nil || suite.Properties.Properties[0] != (junitProperty{Name: `row_count`, Value: `200`}) {
+		t.Errorf("properties = %+v", suite.Properties)
+	}
+	if len(suite.Cases) != 2 || suite.Tests != 2 || suite.Failures != 1 {
+		t.Fatalf("suite = %+v", suite)
+	}
+
+	//the row count anomaly fails the row count case, the other table's anomaly is ignored
+	rowCountCase, totalCase := suite.Cases[0], suite.Cases[1]
+	if rowCountCase.Name != `row_count` || rowCountCase.Failure == nil || rowCountCase.Failure.Type != `anomaly` {
+		t.Errorf("row count case = %+v", rowCountCase)
+	}
+	if !strings.Contains(rowCountCase.Failure.Text, `expected 20 +/- 5 (mad)`) {
+		t.Errorf("row count failure = %q", rowCountCase.Failure.Text)
+	}
+	if totalCase.Name != `total` || totalCase.Failure != nil || totalCase.SystemOut.Text != "maximum = 90\nminimum = 1\n" {
+		t.Errorf("total case = %+v", totalCase)
+	}
+}
@@ -0,0 +1,220 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//Most metric changes listed in Markdown before the rest are summarised, keeps the output pasteable
+const MARKDOWN_MAX_METRIC_CHANGES = 25
+
+//Most earlier runs searched for the previous profile of each table
+const MARKDOWN_MAX_PREVIOUS_RUNS = 10
+
+//WriteRunMarkdown writes a compact Markdown summary of the run for pull requests and chat.
+//The diff is optional, pass the diff against the previous run to include row count deltas and changed metrics.
+func WriteRunMarkdown(w io.Writer, run *RunResult, diff *ProfileDiff) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "### Profile run %d\n\n", run.ProfileRecordID)
+	fmt.Fprintf(b, "%d tables profiled in %.1fs", len(run.Tables), run.DurationSeconds)
+	if diff != nil {
+		fmt.Fprintf(b, ", compared with run %d", diff.FromProfileRecordID)
+	}
+	b.WriteString("\n\n")
+
+	tableDiffs := map[string]TableDiff{}
+	if diff != nil {
+		for _, tableDiff := range diff.Tables {
+			tableDiffs[tableDiff.TableName] = tableDiff
+		}
+	}
+
+	tableNames := map[string]bool{}
+	for tableName := range run.Tables {
+		tableNames[tableName] = true
+	}
+
+	if diff != nil {
+		b.WriteString("| Table | Rows | Change |\n|---|--:|--:|\n")
+	} else {
+		b.WriteString("| Table | Rows |\n|---|--:|\n")
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		fmt.Fprintf(b, "| %s | %s |", escapeMarkdown(tableName), formatOptionalInt(run.Tables[tableName].RowCount))
+		if diff != nil {
+			fmt.Fprintf(b, " %s |", formatMarkdownTableChange(tableDiffs[tableName]))
+		}
+		b.WriteString("\n")
+	}
+
+	if diff != nil {
+		writeMarkdownMetricChanges(b, diff)
+	}
+
+	writeMarkdownFailedChecks(b, run)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+//WriteMarkdown writes the diff as Markdown tables
+func (d *ProfileDiff) WriteMarkdown(w io.Writer) error {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "### Profile %d -> %d\n\n", d.FromProfileRecordID, d.ToProfileRecordID)
+	b.WriteString("| Table | Status | From Rows | To Rows | Change |\n|---|---|--:|--:|--:|\n")
+	for _, table := range d.Tables {
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n",
+			escapeMarkdown(table.TableName),
+			table.Status,
+			formatOptionalInt(table.FromRowCount),
+			formatOptionalInt(table.ToRowCount),
+			formatMarkdownTableChange(table),
+		)
+	}
+
+	writeMarkdownMetricChanges(b, d)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatMarkdownTableChange(table TableDiff) string {
+	switch table.Status {
+	case ``:
+		return `-`
+	case DIFF_STATUS_ADDED:
+		return `new`
+	case DIFF_STATUS_REMOVED:
+		return `removed`
+	}
+	if table.RowCountDelta == nil {
+		return `-`
+	}
+	return fmt.Sprintf(`%+d`, *table.RowCountDelta)
+}
+
+func writeMarkdownMetricChanges(b *strings.Builder, diff *ProfileDiff) {
+	rows := []string{}
+	for _, table := range diff.Tables {
+		for _, columnName := range table.AddedColumns {
+			rows = append(rows, fmt.Sprintf("| %s | %s | column added | | | |\n", escapeMarkdown(table.TableName), escapeMarkdown(columnName)))
+		}
+		for _, columnName := range table.RemovedColumns {
+			rows = append(rows, fmt.Sprintf("| %s | %s | column removed | | | |\n", escapeMarkdown(table.TableName), escapeMarkdown(columnName)))
+		}
+		for _, change := range table.MetricChanges {
+			rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+				escapeMarkdown(table.TableName),
+				escapeMarkdown(change.ColumnName),
+				escapeMarkdown(change.MetricName),
+				escapeMarkdown(change.From.String()),
+				escapeMarkdown(change.To.String()),
+				formatOptionalPercent(change.RelativeDifference),
+			))
+		}
+	}
+
+	if len(rows) == 0 {
+		return
+	}
+
+	b.WriteString("\n#### Changed metrics\n\n| Table | Column | Metric | From | To | Change |\n|---|---|---|--:|--:|--:|\n")
+	for idx, row := range rows {
+		if idx >= MARKDOWN_MAX_METRIC_CHANGES {
+			fmt.Fprintf(b, "\n_and %d more_\n", len(rows)-MARKDOWN_MAX_METRIC_CHANGES)
+			break
+		}
+		b.WriteString(row)
+	}
+}
+
+func writeMarkdownFailedChecks(b *strings.Builder, run *RunResult) {
+	checks := []string{}
+	for _, failed := range run.FailedExpectations() {
+		checks = append(checks, fmt.Sprintf("| %s | expectation | `%s` %s |\n", escapeMarkdown(failed.TableName), escapeMarkdown(failed.Expectation), escapeMarkdown(failed.Message)))
+	}
+	for _, stale := range run.StaleTables() {
+		lag := `no rows`
+		if stale.LagSeconds != nil {
+			lag = fmt.Sprintf(`newest %s is %.0fs old`, stale.ColumnName, *stale.LagSeconds)
+		}
+		checks = append(checks, fmt.Sprintf("| %s | stale | %s |\n", escapeMarkdown(stale.TableName), escapeMarkdown(lag)))
+	}
+	for _, relationship := range run.Relationships {
+		if relationship.OrphanCount > 0 {
+			checks = append(checks, fmt.Sprintf("| %s | orphans | %d rows of (%s) missing in %s |\n",
+				escapeMarkdown(relationship.TableName),
+				relationship.OrphanCount,
+				escapeMarkdown(strings.Join(relationship.Columns, `, `)),
+				escapeMarkdown(relationship.ReferencedTable),
+			))
+		}
+	}
+	for _, anomaly := range run.Anomalies {
+		metric := anomaly.MetricName
+		if anomaly.ColumnName != `` {
+			metric = fmt.Sprintf(`%s.%s`, anomaly.ColumnName, anomaly.MetricName)
+		}
+		checks = append(checks, fmt.Sprintf("| %s | anomaly | %s is %g, expected %g |\n", escapeMarkdown(anomaly.TableName), escapeMarkdown(metric), anomaly.Value, anomaly.ExpectedValue))
+	}
+	for _, change := range run.SchemaChanges {
+		checks = append(checks, fmt.Sprintf("| %s | schema | %s %s |\n", escapeMarkdown(change.TableName), change.ChangeType, escapeMarkdown(change.getColumnName())))
+	}
+
+	if len(checks) == 0 {
+		return
+	}
+
+	sort.Strings(checks)
+	b.WriteString("\n#### Failed checks\n\n| Table | Check | Detail |\n|---|---|---|\n")
+	for _, check := range checks {
+		b.WriteString(check)
+	}
+}
+
+//Escapes characters that would break a Markdown table cell
+func escapeMarkdown(value string) string {
+	value = strings.Replace(value, `|`, `\|`, -1)
+	return strings.Replace(value, "\n", ` `, -1)
+}
+
+//DiffWithPreviousRun compares each table in the run with its latest stored profile from an earlier run,
+//looking back at most MARKDOWN_MAX_PREVIOUS_RUNS runs. Returns nil when no table has an earlier profile.
+func (p *ProfileStore) DiffWithPreviousRun(run *RunResult) (*ProfileDiff, error) {
+	history, err := p.getRecentProfileRunResults(run.ProfileRecordID, MARKDOWN_MAX_PREVIOUS_RUNS)
+	if err != nil {
+		return nil, err
+	}
+
+	previousResults := map[string]*TableProfileResult{}
+	previousProfileRecordID := 0
+	for _, runResults := range history {
+		for tableName, result := range runResults {
+			if _, inRun := run.Tables[tableName]; !inRun {
+				continue
+			}
+			if _, found := previousResults[tableName]; found {
+				continue
+			}
+			previousResults[tableName] = result
+			if previousProfileRecordID == 0 {
+				previousProfileRecordID = result.ProfileRecordID
+			}
+		}
+		if len(previousResults) == len(run.Tables) {
+			break
+		}
+	}
+	if previousProfileRecordID == 0 {
+		return nil, nil
+	}
+
+	diff := DiffTableProfileResults(previousResults, run.Tables)
+	diff.FromProfileRecordID = previousProfileRecordID
+	diff.ToProfileRecordID = run.ProfileRecordID
+	return diff, nil
+}
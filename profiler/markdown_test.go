@@ -0,0 +1,157 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteRunMarkdown(t *testing.T) {
+	rowCount := 200
+	lag := 7200.0
+
+	run := &RunResult{
+		ProfileRecordID: 12,
+		DurationSeconds: 3.25,
+		Tables: map[string]*TableProfileResult{
+			`orders`:    {TableName: `orders`, RowCount: &rowCount},
+			`a|b`:       {TableName: `a|b`},
+			`customers`: {TableName: `customers`, RowCount: &rowCount},
+		},
+		ExpectationResults: []ExpectationResult{
+			{TableName: `orders`, Expectation: `row_count > 100`, Passed: true},
+			{TableName: `orders`, Expectation: `row_count > 1000`, Message: "row_count\nis 200"},
+		},
+		Freshness: []FreshnessResult{
+			{TableName: `orders`, ColumnName: `created_at`, LagSeconds: &lag, IsStale: true},
+			{TableName: `customers`, ColumnName: `updated_at`, IsStale: true},
+		},
+		Relationships: []RelationshipResult{
+			{TableName: `orders`, Columns: []string{`customer_id`}, ReferencedTable: `customers`, OrphanCount: 3},
+			{TableName: `orders`, Columns: []string{`product_id`}, ReferencedTable: `products`},
+		},
+		Anomalies: []Anomaly{
+			{TableName: `orders`, MetricName: ROW_COUNT_METRIC_NAME, Value: 200, ExpectedValue: 20},
+			{TableName: `orders`, ColumnName: `total`, MetricName: `maximum`, Value: 1.5, ExpectedValue: 1},
+		},
+		SchemaChanges: []SchemaChange{
+			{TableName: `customers`, ChangeType: SCHEMA_CHANGE_ADDED, ColumnName: `phone`},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		diff     *ProfileDiff
+		want     []string
+		wantNone []string
+	}{
+		{
+			name: "without a diff",
+			want: []string{
+				"### Profile run 12\n\n3 tables profiled in 3.2s\n\n",
+				"| Table | Rows |\n|---|--:|\n| a\\|b | - |\n| customers | 200 |\n| orders | 200 |\n",
+				"#### Failed checks\n\n| Table | Check | Detail |\n|---|---|---|\n" +
+					"| customers | schema | added phone |\n" +
+					"| customers | stale | no rows |\n" +
+					"| orders | anomaly | row_count is 200, expected 20 |\n" +
+					"| orders | anomaly | total.maximum is 1.5, expected 1 |\n" +
+					"| orders | expectation | `row_count > 1000` row_count is 200 |\n" +
+					"| orders | orphans | 3 rows of (customer_id) missing in customers |\n" +
+					"| orders | stale | newest created_at is 7200s old |\n",
+			},
+			wantNone: []string{`Change`, `Changed metrics`, "`row_count > 100`", `products`},
+		},
+		{
+			name: "with a diff",
+			diff: &ProfileDiff{
+				FromProfileRecordID: 11,
+				ToProfileRecordID:   12,
+				Tables: []TableDiff{
+					{TableName: `orders`, Status: DIFF_STATUS_CHANGED, RowCountDelta: intPointer(-5), AddedColumns: []string{`notes`}, MetricChanges: []MetricDiff{
+						{ColumnName: `total`, MetricName: `maximum`, From: NewMetricValue(1), To: NewMetricValue(1.5), RelativeDifference: floatPointer(0.5)},
+					}},
+					{TableName: `customers`, Status: DIFF_STATUS_ADDED},
+				},
+			},
+			want: []string{
+				"3 tables profiled in 3.2s, compared with run 11\n\n",
+				"| Table | Rows | Change |\n|---|--:|--:|\n| a\\|b | - | - |\n| customers | 200 | new |\n| orders | 200 | -5 |\n",
+				"#### Changed metrics\n\n| Table | Column | Metric | From | To | Change |\n|---|---|---|--:|--:|--:|\n" +
+					"| orders | notes | column added | | | |\n" +
+					"| orders | total | maximum | 1 | 1.5 | +50.00% |\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			err := WriteRunMarkdown(&buffer, run, test.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := buffer.String()
+			for _, want := range test.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("markdown is missing %q in\n%s", want, output)
+				}
+			}
+			for _, unwanted := range test.wantNone {
+				if strings.Contains(output, unwanted) {
+					t.Errorf("markdown should not have %q in\n%s", unwanted, output)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteRunMarkdownLimitsMetricChanges(t *testing.T) {
+	changes := []MetricDiff{}
+	for idx := 0; idx < MARKDOWN_MAX_METRIC_CHANGES+3; idx++ {
+		changes = append(changes, MetricDiff{ColumnName: fmt.Sprintf(`column_%d`, idx), MetricName: `maximum`, From: NewMetricValue(idx), To: NewMetricValue(idx + 1)})
+	}
+	diff := &ProfileDiff{Tables: []TableDiff{{TableName: `orders`, Status: DIFF_STATUS_CHANGED, MetricChanges: changes}}}
+
+	var buffer bytes.Buffer
+	err := WriteRunMarkdown(&buffer, &RunResult{Tables: map[string]*TableProfileResult{}}, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+	if strings.Count(output, `| maximum |`) != MARKDOWN_MAX_METRIC_CHANGES {
+		t.Errorf("listed %d metric changes, want %d", strings.Count(output, `| maximum |`), MARKDOWN_MAX_METRIC_CHANGES)
+	}
+	if !strings.Contains(output, "_and 3 more_") {
+		t.Errorf("markdown does not summarise the rest:\n%s", output)
+	}
+	if strings.Contains(output, `Failed checks`) {
+		t.Error("a run without problems should not list failed checks")
+	}
+}
+
+func TestFormatMarkdownTableChange(t *testing.T) {
+	tests := []struct {
+		table TableDiff
+		want  string
+	}{
+		{table: TableDiff{}, want: `-`},
+		{table: TableDiff{Status: DIFF_STATUS_ADDED}, want: `new`},
+		{table: TableDiff{Status: DIFF_STATUS_REMOVED}, want: `removed`},
+		{table: TableDiff{Status: DIFF_STATUS_CHANGED}, want: `-`},
+		{table: TableDiff{Status: DIFF_STATUS_CHANGED, RowCountDelta: intPointer(7)}, want: `+7`},
+		{table: TableDiff{Status: DIFF_STATUS_UNCHANGED, RowCountDelta: intPointer(0)}, want: `+0`},
+	}
+
+	for _, test := range tests {
+		if got := formatMarkdownTableChange(test.table); got != test.want {
+			t.Errorf("change of %+v = %s, want %s", test.table, got, test.want)
+		}
+	}
+}
+
+func intPointer(value int) *int {
+	return &value
+}
@@ -0,0 +1,146 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Kinds of metric values, a null value has an empty kind
+const METRIC_KIND_NUMERIC = `numeric`
+const METRIC_KIND_TEXT = `text`
+const METRIC_KIND_TIMESTAMP = `timestamp`
+
+//MetricValue is a single profile value read back from the store.
+//Only the field matching the kind is set, a null value has an empty kind.
+type MetricValue struct {
+	Kind   string
+	Number float64
+	Text   string
+	Time   time.Time
+}
+
+//NewMetricValue converts a value as returned by the database driver into a metric value
+func NewMetricValue(value interface{}) MetricValue {
+	numericValue, textValue, timestampValue := splitMetricValue(value)
+
+	switch {
+	case numericValue != nil:
+		return MetricValue{
+			Kind:   METRIC_KIND_NUMERIC,
+			Number: toFloat64(numericValue),
+		}
+	case textValue != nil:
+		return MetricValue{
+			Kind: METRIC_KIND_TEXT,
+			Text: textValue.(string),
+		}
+	case timestampValue != nil:
+		return MetricValue{
+			Kind: METRIC_KIND_TIMESTAMP,
+			Time: timestampValue.(time.Time),
+		}
+	}
+
+	return MetricValue{}
+}
+
+func (m MetricValue) IsNull() bool {
+	return m.Kind == ``
+}
+
+func (m MetricValue) IsNumeric() bool {
+	return m.Kind == METRIC_KIND_NUMERIC
+}
+
+func (m MetricValue) String() string {
+	switch m.Kind {
+	case METRIC_KIND_NUMERIC:
+		return strconv.FormatFloat(m.Number, 'f', -1, 64)
+	case METRIC_KIND_TEXT:
+		return m.Text
+	case METRIC_KIND_TIMESTAMP:
+		return m.Time.Format(time.RFC3339Nano)
+	}
+	return `null`
+}
+
+//Converts the numeric values returned by splitMetricValue to a float
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case []byte:
+		number, _ := strconv.ParseFloat(string(v), 64)
+		return number
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	}
+
+	number, _ := strconv.ParseFloat(fmt.Sprint(value), 64)
+	return number
+}
+
+//Equal reports whether both values are of the same kind and hold the same value
+func (m MetricValue) Equal(other MetricValue) bool {
+	if m.Kind != other.Kind {
+		return false
+	}
+	switch m.Kind {
+	case METRIC_KIND_NUMERIC:
+		return m.Number == other.Number
+	case METRIC_KIND_TEXT:
+		return m.Text == other.Text
+	case METRIC_KIND_TIMESTAMP:
+		return m.Time.Equal(other.Time)
+	}
+	return true
+}
+
+//MarshalJSON writes the value as a plain JSON number, string or null
+func (m MetricValue) MarshalJSON() ([]byte, error) {
+	switch m.Kind {
+	case METRIC_KIND_NUMERIC:
+		return json.Marshal(m.Number)
+	case METRIC_KIND_TEXT:
+		return json.Marshal(m.Text)
+	case METRIC_KIND_TIMESTAMP:
+		return json.Marshal(m.Time)
+	}
+	return []byte(`null`), nil
+}
+
+//UnmarshalJSON reads a value written by MarshalJSON, strings holding an RFC 3339 time are read as timestamps
+func (m *MetricValue) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		*m = MetricValue{}
+	case float64:
+		*m = MetricValue{
+			Kind:   METRIC_KIND_NUMERIC,
+			Number: typedValue,
+		}
+	case string:
+		if timestamp, err := time.Parse(time.RFC3339Nano, typedValue); err == nil {
+			*m = MetricValue{
+				Kind: METRIC_KIND_TIMESTAMP,
+				Time: timestamp,
+			}
+			return nil
+		}
+		*m = MetricValue{
+			Kind: METRIC_KIND_TEXT,
+			Text: typedValue,
+		}
+	default:
+		return fmt.Errorf(`unsupported metric value %s`, string(data))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package profiler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewMetricValue(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  MetricValue
+	}{
+		{name: "nil", value: nil, want: MetricValue{}},
+		{name: "int", value: 42, want: MetricValue{Kind: METRIC_KIND_NUMERIC, Number: 42}},
+		{name: "int64", value: int64(-7), want: MetricValue{Kind: METRIC_KIND_NUMERIC, Number: -7}},
+		{name: "float64", value: 1.25, want: MetricValue{Kind: METRIC_KIND_NUMERIC, Number: 1.25}},
+		{name: "numeric bytes", value: []byte(`3.5`), want: MetricValue{Kind: METRIC_KIND_NUMERIC, Number: 3.5}},
+		{name: "text bytes", value: []byte(`abc`), want: MetricValue{Kind: METRIC_KIND_TEXT, Text: `abc`}},
+		{name: "string", value: `42`, want: MetricValue{Kind: METRIC_KIND_TEXT, Text: `42`}},
+		{name: "timestamp", value: timestamp, want: MetricValue{Kind: METRIC_KIND_TIMESTAMP, Time: timestamp}},
+		{name: "bool", value: true, want: MetricValue{Kind: METRIC_KIND_TEXT, Text: `true`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewMetricValue(test.value)
+			if !got.Equal(test.want) {
+				t.Errorf("value = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMetricValueJSON(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 30, 0, 123, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    MetricValue
+		wantJSON string
+	}{
+		{name: "null", value: MetricValue{}, wantJSON: `null`},
+		{name: "integer", value: NewMetricValue(42), wantJSON: `42`},
+		{name: "fraction", value: NewMetricValue(0.125), wantJSON: `0.125`},
+		{name: "negative", value: NewMetricValue(-3), wantJSON: `-3`},
+		{name: "text", value: NewMetricValue(`a "quoted" value`), wantJSON: `"a \"quoted\" value"`},
+		{name: "numeric looking text", value: NewMetricValue(`42`), wantJSON: `"42"`},
+		{name: "empty text", value: NewMetricValue(``), wantJSON: `""`},
+		{name: "timestamp", value: NewMetricValue(timestamp), wantJSON: `"2024-05-01T12:30:00.000000123Z"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.wantJSON {
+				t.Errorf("json = %s, want %s", data, test.wantJSON)
+			}
+
+			var got MetricValue
+			err = json.Unmarshal(data, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.value) {
+				t.Errorf("round trip = %+v, want %+v", got, test.value)
+			}
+		})
+	}
+}
+
+func TestMetricValueJSONInStruct(t *testing.T) {
+	column := ColumnProfileResult{
+		ColumnName: `total`,
+		Metrics: map[string]MetricValue{
+			`maximum`:    NewMetricValue(10),
+			`null_count`: MetricValue{},
+		},
+	}
+
+	data, err := json.Marshal(column)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got ColumnProfileResult
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Metrics[`maximum`].Equal(NewMetricValue(10)) {
+		t.Errorf("maximum = %+v, want 10", got.Metrics[`maximum`])
+	}
+	nullCount, ok := got.Metrics[`null_count`]
+	if !ok || !nullCount.IsNull() {
+		t.Errorf("null_count = %+v, want a null value", nullCount)
+	}
+}
+
+func TestMetricValueUnmarshalInvalid(t *testing.T) {
+	for _, data := range []string{`{"a":1}`, `[1]`, `true`, `not json`} {
+		var value MetricValue
+		if err := json.Unmarshal([]byte(data), &value); err == nil {
+			t.Errorf("expected an error for %s, got %+v", data, value)
+		}
+	}
+}
+
+func TestMetricValueString(t *testing.T) {
+	tests := []struct {
+		value MetricValue
+		want  string
+	}{
+		{MetricValue{}, `null`},
+		{NewMetricValue(1.5), `1.5`},
+		{NewMetricValue(1e21), `1000000000000000000000`},
+		{NewMetricValue(`text`), `text`},
+		{NewMetricValue(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)), `2024-05-01T00:00:00Z`},
+	}
+
+	for _, test := range tests {
+		if got := test.value.String(); got != test.want {
+			t.Errorf("string = %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestMetricValueEqual(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		a    MetricValue
+		b    MetricValue
+		want bool
+	}{
+		{name: "nulls", a: MetricValue{}, b: MetricValue{}, want: true},
+		{name: "same number", a: NewMetricValue(1), b: NewMetricValue(1.0), want: true},
+		{name: "different number", a: NewMetricValue(1), b: NewMetricValue(2), want: false},
+		{name: "number and text", a: NewMetricValue(1), b: NewMetricValue(`1`), want: false},
+		{name: "null and zero", a: MetricValue{}, b: NewMetricValue(0), want: false},
+		{name: "same instant in other zones", a: NewMetricValue(timestamp), b: NewMetricValue(timestamp.In(time.FixedZone(`plus2`, 7200))), want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.a.Equal(test.b); got != test.want {
+				t.Errorf("equal = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package profiler
+
+import "fmt"
+
+//ProfileError is returned when profiling a table fails, ColumnName is empty when the failure
+//was not in a single column's profile
+type ProfileError struct {
+	TableName  string
+	ColumnName string
+	Err        error
+}
+
+func (e *ProfileError) Error() string {
+	if e.ColumnName == `` {
+		return fmt.Sprintf(`error profiling table %s: %v`, e.TableName, e.Err)
+	}
+	return fmt.Sprintf(`error profiling column %s of table %s: %v`, e.ColumnName, e.TableName, e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+//Wraps the error with the table and column, an error that already has them is returned as is
+func newProfileError(tableName string, columnName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*ProfileError); ok {
+		return err
+	}
+	return &ProfileError{
+		TableName:  tableName,
+		ColumnName: columnName,
+		Err:        err,
+	}
+}
@@ -1,22 +1,43 @@
 package profiler
 
 import (
-	
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/intxlog/profiler/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Profiler struct {
 	targetDBConn  db.DBConn
 	profileDBConn db.DBConn
 	profileStore  *ProfileStore
+	tracer        trace.Tracer
+	logger        *slog.Logger
 }
 
 type ProfilerOptions struct{
 	UsePascalCase bool
+	//Number of profile rows to buffer per store table before writing, 0 writes each row individually
+	InsertBatchSize int
+	//Write buffered profile rows with copy instead of multi-row inserts
+	UseCopy bool
+	//Layout of the profile store, STORE_LAYOUT_WIDE (default) or STORE_LAYOUT_LONG
+	StoreLayout string
+	//Schema to create all profile store tables in, empty uses the default schema
+	StoreSchema string
+	//Prefix for the name of every profile store table
+	StoreTablePrefix string
+	//Provider of the tracer for run, table and column spans, nil uses the global provider
+	TracerProvider trace.TracerProvider
+	//Logger for per table progress and, at debug level, every SQL statement run against either database.
+	//Nil uses slog.Default.
+	Logger *slog.Logger
 }
 
 // NewProfiler returns a new profiler with default options for the specified databases
@@ -27,15 +48,28 @@ func NewProfiler(targetDBConn db.DBConn, profileDBConn db.DBConn) *Profiler {
 }
 
 // NewProfilerWithOptions returns a new profiler with the specified options
+// profileDBConn can be nil to profile without storing anything, results are then only
+// available from RunProfileWithResults and checks that need history are skipped
 func NewProfilerWithOptions(targetDBConn db.DBConn, profileDBConn db.DBConn, options ProfilerOptions) *Profiler {
 	profiler := &Profiler{
 		targetDBConn:  targetDBConn,
 		profileDBConn: profileDBConn,
-		profileStore:  NewProfileStore(profileDBConn),
+		tracer:        getTracer(options),
+		logger:        options.Logger,
+	}
+	if profiler.logger == nil {
+		profiler.logger = slog.Default()
+	}
+	if targetDBConn != nil {
+		targetDBConn.SetLogger(profiler.logger)
 	}
 
-	profiler.profileStore.UsePascalCase = options.UsePascalCase
+	if profileDBConn == nil {
+		return profiler
+	}
 
+	profileDBConn.SetLogger(profiler.logger)
+	profiler.profileStore = NewProfileStoreWithOptions(profileDBConn, options)
 	if err := profiler.profileStore.ScaffoldProfileStore(); err != nil {
 		panic(err)
 	}
@@ -43,18 +77,39 @@ func NewProfilerWithOptions(targetDBConn db.DBConn, profileDBConn db.DBConn, opt
 	return profiler
 }
 
+//Returns the profile store results are written to, nil when profiling without one
+func (p *Profiler) GetProfileStore() *ProfileStore {
+	return p.profileStore
+}
+
+//Returns true if results are written to a profile store
+func (p *Profiler) hasProfileStore() bool {
+	return p.profileStore != nil
+}
+
 //Run profiles on all provided tables and store
-func (p *Profiler) ProfileTablesByName(tableNames []string) error {
+func (p *Profiler) ProfileTablesByName(tableNames []string) (err error) {
+	ctx, span := p.startSpan(context.Background(), SPAN_RUN)
+	defer func() { span.end(err) }()
 
-	profileID, err := p.profileStore.NewProfile()
+	run, err := p.startRun()
 	if err != nil {
 		return err
 	}
+	span.SetAttributes(attribute.Int(ATTRIBUTE_PROFILE_RECORD_ID, run.ProfileRecordID))
+
+	//keep what earlier tables buffered when the run fails part way, a failed final flush is not retried
+	flushed := false
+	defer func() {
+		if err != nil && !flushed {
+			err = p.flushProfileStoreOnError(err)
+		}
+	}()
 
 	errChan := make(chan error)
 	defer close(errChan)
 	for _, tableName := range tableNames {
-		go p.profileTableChannel(tableName, profileID, errChan)
+		go p.profileTableChannel(ctx, tableName, run, errChan)
 	}
 
 	err = p.waitForTableChannels(errChan, len(tableNames))
@@ -62,16 +117,70 @@ func (p *Profiler) ProfileTablesByName(tableNames []string) error {
 		return err
 	}
 
-	return nil
+	err = p.checkRelationships(run, getRelationshipTables(ProfileDefinition{FullProfileTables: tableNames}))
+	if err != nil {
+		return err
+	}
+
+	flushed = true
+	return p.flushProfileStore()
 }
 
 //Run profiles on all provided tables and store
 func (p *Profiler) RunProfile(profile ProfileDefinition) error {
+	_, err := p.RunProfileWithResults(profile)
+	return err
+}
 
-	profileID, err := p.profileStore.NewProfile()
+//Run profiles on all provided tables and store, returning everything gathered during the run
+func (p *Profiler) RunProfileWithResults(profile ProfileDefinition) (*RunResult, error) {
+	return p.RunProfileWithContext(context.Background(), profile)
+}
+
+//RunProfileWithContext is RunProfileWithResults with the run span started as a child of any span in the context
+func (p *Profiler) RunProfileWithContext(ctx context.Context, profile ProfileDefinition) (run *RunResult, err error) {
+	ctx, span := p.startSpan(ctx, SPAN_RUN)
+	defer func() { span.end(err) }()
+
+	//catch bad expectations before doing any work
+	expectations, err := parseProfileExpectations(profile)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	freshnessChecks, err := parseFreshnessChecks(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateRelationships(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateAnomalyDetection(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err = p.startRun()
+	if err != nil {
+		return nil, err
 	}
+	span.SetAttributes(attribute.Int(ATTRIBUTE_PROFILE_RECORD_ID, run.ProfileRecordID))
+	run.gatherQualityProfiles = needsQualityProfiles(profile)
+
+	//keep what earlier tables buffered when the run fails part way, a failed final flush is not retried
+	flushed := false
+	defer func() {
+		if err != nil && !flushed {
+			err = p.flushProfileStoreOnError(err)
+		}
+	}()
+	p.logger.Info(`profile run started`,
+		`profile_record_id`, run.ProfileRecordID,
+		`tables`, len(profile.FullProfileTables)+len(profile.CustomProfileTables),
+	)
 
 	//Profile full tables
 	errChan := make(chan error)
@@ -79,65 +188,212 @@ func (p *Profiler) RunProfile(profile ProfileDefinition) error {
 
 	if len(profile.FullProfileTables) > 0 {
 		for _, tableName := range profile.FullProfileTables {
-			go p.profileTableChannel(tableName, profileID, errChan)
+			go p.profileTableChannel(ctx, tableName, run, errChan)
 		}
 
 		err := p.waitForTableChannels(errChan, len(profile.FullProfileTables))
 		if err != nil {
-			return err
+			return run, err
 		}
 	}
 
 	if len(profile.CustomProfileTables) > 0 {
 		//Profile the custom profile definitions
 		for _, table := range profile.CustomProfileTables {
-			go p.profileTableCustomColumnsChannel(table, profileID, errChan)
+			go p.profileTableCustomColumnsChannel(ctx, table, run, errChan)
 		}
 
 		err := p.waitForTableChannels(errChan, len(profile.CustomProfileTables))
 		if err != nil {
-			return err
+			return run, err
 		}
 	}
 
-	return nil
-}
+	err = p.checkRelationships(run, getRelationshipTables(profile))
+	if err != nil {
+		return run, err
+	}
 
-func (p *Profiler) waitForTableChannels(errChan chan error, totalResults int) error {
-	tablesProfiled := 0
-	for err := range errChan {
+	err = p.checkFreshness(run, freshnessChecks)
+	if err != nil {
+		return run, err
+	}
+
+	err = p.evaluateExpectations(run, expectations)
+	if err != nil {
+		return run, err
+	}
+
+	//compare the new metrics against their history
+	if profile.AnomalyDetection != nil {
+		err := p.detectAnomalies(run, *profile.AnomalyDetection)
 		if err != nil {
-			return err
+			return run, err
 		}
-		tablesProfiled++
-		if tablesProfiled >= totalResults {
-			break
+	}
+
+	//write out anything still buffered in the store
+	err = p.flushProfileStore()
+	flushed = true
+	run.setEndTime(time.Now())
+	if err != nil {
+		p.logger.Error(`error writing to the profile store`, `profile_record_id`, run.ProfileRecordID, `error`, err)
+		return run, err
+	}
+
+	p.logger.Info(`profile run finished`,
+		`profile_record_id`, run.ProfileRecordID,
+		`duration`, time.Duration(run.DurationSeconds*float64(time.Second)),
+	)
+	return run, nil
+}
+
+//The quality profiles are only worth their cost when something checks or shows them
+func needsQualityProfiles(profile ProfileDefinition) bool {
+	if profile.QualityProfiles || profile.AnomalyDetection != nil {
+		return true
+	}
+	for _, table := range profile.CustomProfileTables {
+		if len(table.Expectations) > 0 {
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+//Creates the profile record for a new run, the run has no profile record id without a store
+func (p *Profiler) startRun() (*RunResult, error) {
+	if !p.hasProfileStore() {
+		return newRunResult(0), nil
+	}
+
+	profileID, err := p.profileStore.NewProfile()
+	if err != nil {
+		return nil, err
+	}
+	return newRunResult(profileID), nil
+}
+
+func (p *Profiler) flushProfileStore() error {
+	if !p.hasProfileStore() {
+		return nil
+	}
+	return p.profileStore.Flush()
 }
 
-func (p *Profiler) profileTableCustomColumnsChannel(tableDef TableDefinition, profileID int, c chan error) {
-	c <- p.profileTableCustomColumns(tableDef, profileID)
+//Waits for every table to finish and returns the first error, waiting on all of them means
+//the rows buffered by tables that succeeded are complete when the store is flushed
+func (p *Profiler) waitForTableChannels(errChan chan error, totalResults int) error {
+	var firstErr error
+	for tablesProfiled := 0; tablesProfiled < totalResults; tablesProfiled++ {
+		err := <-errChan
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//Writes out the rows buffered before a run failed, the flush error is added to the run's error
+func (p *Profiler) flushProfileStoreOnError(runErr error) error {
+	flushErr := p.flushProfileStore()
+	if flushErr == nil {
+		return runErr
+	}
+	p.logger.Error(`error writing to the profile store`, `error`, flushErr)
+	return fmt.Errorf(`%v, writing the buffered profile rows also failed: %v`, runErr, flushErr)
 }
 
-func (p *Profiler) profileTableChannel(tableName string, profileID int, c chan error) {
-	c <- p.profileTable(tableName, profileID)
+func (p *Profiler) profileTableCustomColumnsChannel(ctx context.Context, tableDef TableDefinition, run *RunResult, c chan error) {
+	c <- p.runTableProfile(ctx, tableDef.TableName, run, func(ctx context.Context) error {
+		return p.profileTableCustomColumns(ctx, tableDef, run)
+	})
+}
+
+func (p *Profiler) profileTableChannel(ctx context.Context, tableName string, run *RunResult, c chan error) {
+	c <- p.runTableProfile(ctx, tableName, run, func(ctx context.Context) error {
+		return p.profileTable(ctx, tableName, run)
+	})
+}
+
+//Runs the profile of a table in its span, timing and logging it.
+//Errors are returned as a ProfileError so they carry the table and column.
+func (p *Profiler) runTableProfile(ctx context.Context, tableName string, run *RunResult, profile func(ctx context.Context) error) error {
+	start := time.Now()
+	p.logger.Info(`profiling table`, `table`, tableName)
+
+	ctx, span := p.startSpan(ctx, SPAN_TABLE, attribute.String(ATTRIBUTE_TABLE, tableName))
+	err := newProfileError(tableName, ``, profile(ctx))
+	span.end(err)
+
+	duration := time.Since(start)
+	run.addTableDuration(tableName, duration)
+
+	if err != nil {
+		profileErr := err.(*ProfileError)
+		attrs := []any{`table`, profileErr.TableName}
+		if profileErr.ColumnName != `` {
+			attrs = append(attrs, `column`, profileErr.ColumnName)
+		}
+		p.logger.Error(`error profiling table`, append(attrs, `error`, profileErr.Err)...)
+		return err
+	}
+
+	p.logger.Info(`profiled table`, `table`, tableName, `duration`, duration)
+	return nil
 }
 
 //Profiles the provided table
-func (p *Profiler) profileTableCustomColumns(tableDef TableDefinition, profileID int) error {
+func (p *Profiler) profileTableCustomColumns(ctx context.Context, tableDef TableDefinition, run *RunResult) error {
 
-	tableNameID, err := p.profileStore.RegisterTable(tableDef.TableName)
+	err := p.recordTableSchema(tableDef.TableName, run)
 	if err != nil {
 		return err
 	}
 
+	if len(tableDef.CustomColumns) > 0 {
+		//profile the custom columns
+		err := p.profileTableCustomColumnValues(ctx, tableDef, run)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(tableDef.Columns) > 0 {
+		//profile the defined columns
+		err := p.profileTableDefinedColumns(ctx, tableDef.TableName, run, tableDef.Columns)
+		if err != nil {
+			return err
+		}
+	} else if len(tableDef.Expectations) > 0 {
+		//the defined columns record the row count, without them expectations on it still need it
+		tableNameObj, err := p.registerTableName(tableDef.TableName)
+		if err != nil {
+			return err
+		}
+		err = p.recordTableRowCount(ctx, tableNameObj, run)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Runs the custom column definitions of the table as a single query and stores each value
+func (p *Profiler) profileTableCustomColumnValues(ctx context.Context, tableDef TableDefinition, run *RunResult) (err error) {
+
 	selects := []string{}
 	for _, col := range tableDef.CustomColumns {
 		selects = append(selects, fmt.Sprintf(`%s as %s`, col.ColumnDefinition, col.ColumnName))
 	}
 
+	_, span := p.startSpan(ctx, SPAN_CUSTOM_COLUMNS,
+		attribute.String(ATTRIBUTE_TABLE, tableDef.TableName),
+		attribute.String(ATTRIBUTE_SQL, p.targetDBConn.GetSelectQueryString(tableDef.TableName, selects)),
+	)
+	defer func() { span.end(err) }()
+
 	rows, err := p.targetDBConn.GetRowsSelect(tableDef.TableName, selects)
 	defer rows.Close()
 	if err != nil {
@@ -170,11 +426,6 @@ func (p *Profiler) profileTableCustomColumns(tableDef TableDefinition, profileID
 	}
 
 	for idx, columnData := range columnsData {
-		columnTypeID, err := p.profileStore.RegisterTableColumnType(columnData.DatabaseTypeName())
-		if err != nil {
-			return err
-		}
-
 		//Find the column definition for this result by iterating our column definitions
 		var colDefinition string
 		for _, col := range tableDef.CustomColumns {
@@ -184,11 +435,9 @@ func (p *Profiler) profileTableCustomColumns(tableDef TableDefinition, profileID
 			}
 		}
 
-		columnNamesID, err := p.profileStore.RegisterTableCustomColumn(tableNameID, columnTypeID, columnData.Name(), colDefinition)
-		if err != nil {
-			return err
-		}
-		err = p.profileStore.StoreCustomColumnProfileData(columnNamesID, columnData, profileID, profileValues[idx])
+		run.addCustomColumnResult(tableDef.TableName, columnData.Name(), columnData.DatabaseTypeName(), colDefinition, profileValues[idx])
+
+		err = p.storeCustomColumnProfile(tableDef.TableName, columnData, colDefinition, run, profileValues[idx])
 		if err != nil {
 			return err
 		}
@@ -196,19 +445,35 @@ func (p *Profiler) profileTableCustomColumns(tableDef TableDefinition, profileID
 
 	rows.Close()
 
-	if len(tableDef.Columns) > 0 {
-		//profile the defined columns
-		err := p.profileTableDefinedColumns(tableDef.TableName, profileID, tableDef.Columns)
-		if err != nil {
-			return err
-		}
+	return nil
+}
+
+//Registers the custom column and stores its value when there is a profile store
+func (p *Profiler) storeCustomColumnProfile(tableName string, columnData *sql.ColumnType, colDefinition string, run *RunResult, profileValue interface{}) error {
+	if !p.hasProfileStore() {
+		return nil
 	}
 
-	return nil
+	tableNameID, err := p.profileStore.RegisterTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	columnTypeID, err := p.profileStore.RegisterTableColumnType(columnData.DatabaseTypeName())
+	if err != nil {
+		return err
+	}
+
+	columnNamesID, err := p.profileStore.RegisterTableCustomColumn(tableNameID, columnTypeID, columnData.Name(), colDefinition)
+	if err != nil {
+		return err
+	}
+
+	return p.profileStore.StoreCustomColumnProfileData(columnNamesID, columnData, run.ProfileRecordID, profileValue)
 }
 
 //does a  table profile but only with the specified columns instead of the full thing
-func (p *Profiler) profileTableDefinedColumns(tableName string, profileID int, columns []string) error {
+func (p *Profiler) profileTableDefinedColumns(ctx context.Context, tableName string, run *RunResult, columns []string) error {
 	// rows, err := p.targetDBConn.GetSelectAllColumnsSingle(tableName)
 	rows, err := p.targetDBConn.GetSelectSingle(tableName, columns)
 	if err != nil {
@@ -222,11 +487,16 @@ func (p *Profiler) profileTableDefinedColumns(tableName string, profileID int, c
 
 	rows.Close()
 
-	return p.profileTableWithColumnsData(tableName, profileID, columnsData)
+	return p.profileTableWithColumnsData(ctx, tableName, run, columnsData)
 }
 
 //Profiles the provided table
-func (p *Profiler) profileTable(tableName string, profileID int) error {
+func (p *Profiler) profileTable(ctx context.Context, tableName string, run *RunResult) error {
+
+	err := p.recordTableSchema(tableName, run)
+	if err != nil {
+		return err
+	}
 
 	rows, err := p.targetDBConn.GetSelectAllColumnsSingle(tableName)
 	if err != nil {
@@ -240,42 +510,61 @@ func (p *Profiler) profileTable(tableName string, profileID int) error {
 
 	rows.Close()
 
-	return p.profileTableWithColumnsData(tableName, profileID, columnsData)
+	return p.profileTableWithColumnsData(ctx, tableName, run, columnsData)
 }
 
-func (p *Profiler) profileTableWithColumnsData(tableName string, profileID int, columnsData []*sql.ColumnType) error {
-	tableNameID, err := p.profileStore.RegisterTable(tableName)
+func (p *Profiler) profileTableWithColumnsData(ctx context.Context, tableName string, run *RunResult, columnsData []*sql.ColumnType) error {
+	tableNameObj, err := p.registerTableName(tableName)
 	if err != nil {
 		return err
 	}
 
+	err = p.recordTableRowCount(ctx, tableNameObj, run)
+	if err != nil {
+		return err
+	}
+
+	return p.handleProfileTableColumns(ctx, tableNameObj, run, columnsData)
+}
+
+//Returns the table name with its id, the id is only set when there is a profile store
+func (p *Profiler) registerTableName(tableName string) (TableName, error) {
 	tableNameObj := TableName{
-		ID:        tableNameID,
 		TableName: tableName,
 	}
 
-	err = p.recordTableRowCount(tableNameObj, profileID)
-	if err != nil {
-		return err
+	if p.hasProfileStore() {
+		tableNameID, err := p.profileStore.RegisterTable(tableName)
+		if err != nil {
+			return tableNameObj, err
+		}
+		tableNameObj.ID = tableNameID
 	}
 
-	return p.handleProfileTableColumns(tableNameObj, profileID, columnsData)
+	return tableNameObj, nil
 }
 
-func (p *Profiler) recordTableRowCount(tableName TableName, profileID int) error {
+func (p *Profiler) recordTableRowCount(ctx context.Context, tableName TableName, run *RunResult) error {
 	rowCount, err := p.targetDBConn.GetTableRowCount(tableName.TableName)
 	if err != nil {
 		return err
 	}
 
-	_, err = p.profileStore.RecordTableProfile(tableName.ID, rowCount, profileID)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int(ATTRIBUTE_ROW_COUNT, rowCount))
+
+	run.setRowCount(tableName.TableName, rowCount)
 
+	if !p.hasProfileStore() {
+		return nil
+	}
+
+	_, err = p.profileStore.RecordTableProfile(tableName.ID, rowCount, run.ProfileRecordID)
 	return err
 }
 
-func (p *Profiler) handleProfileTableColumns(tableName TableName, profileID int, columnsData []*sql.ColumnType) error {
+func (p *Profiler) handleProfileTableColumns(ctx context.Context, tableName TableName, run *RunResult, columnsData []*sql.ColumnType) error {
 	for _, columnData := range columnsData {
-		err := p.handleProfileTableColumn(tableName, profileID, columnData)
+		err := p.handleProfileTableColumn(ctx, tableName, run, columnData)
 		if err != nil {
 			return err
 		}
@@ -283,25 +572,32 @@ func (p *Profiler) handleProfileTableColumns(tableName TableName, profileID int,
 	return nil
 }
 
-func (p *Profiler) handleProfileTableColumn(tableName TableName, profileID int, columnData *sql.ColumnType) error {
-
-	columnTypeID, err := p.profileStore.RegisterTableColumnType(columnData.DatabaseTypeName())
-	if err != nil {
-		return err
-	}
-	columnNamesID, err := p.profileStore.RegisterTableColumn(tableName.ID, columnTypeID, columnData.Name())
-	if err != nil {
-		return err
-	}
+func (p *Profiler) handleProfileTableColumn(ctx context.Context, tableName TableName, run *RunResult, columnData *sql.ColumnType) (err error) {
 
 	columnNameEscaped := fmt.Sprintf(`"%s"`, columnData.Name())
 	//TODO - make this more generic
 	profileSelects := []string{}
 	profiles := p.targetDBConn.ProfilesByType(columnData.DatabaseTypeName())
+	if run.gatherQualityProfiles {
+		for col, pro := range p.targetDBConn.QualityProfilesByType(columnData.DatabaseTypeName()) {
+			profiles[col] = pro
+		}
+	}
 	for col, pro := range profiles {
 		profileSelects = append(profileSelects, fmt.Sprintf(`%s as "%s"`, fmt.Sprintf(pro, columnNameEscaped), col))
 	}
 
+	_, span := p.startSpan(ctx, SPAN_COLUMN,
+		attribute.String(ATTRIBUTE_TABLE, tableName.TableName),
+		attribute.String(ATTRIBUTE_COLUMN, columnData.Name()),
+		attribute.String(ATTRIBUTE_COLUMN_TYPE, columnData.DatabaseTypeName()),
+		attribute.String(ATTRIBUTE_SQL, p.targetDBConn.GetSelectQueryString(tableName.TableName, profileSelects)),
+	)
+	defer func() {
+		err = newProfileError(tableName.TableName, columnData.Name(), err)
+		span.end(err)
+	}()
+
 	rows, err := p.targetDBConn.GetRowsSelect(tableName.TableName, profileSelects)
 	if err != nil {
 		return err
@@ -333,5 +629,20 @@ func (p *Profiler) handleProfileTableColumn(tableName TableName, profileID int,
 		})
 	}
 
-	return p.profileStore.StoreColumnProfileData(columnNamesID, columnData.DatabaseTypeName(), profileID, profileResults)
+	run.addColumnResult(tableName.TableName, columnData.Name(), columnData.DatabaseTypeName(), profileResults)
+
+	if !p.hasProfileStore() {
+		return nil
+	}
+
+	columnTypeID, err := p.profileStore.RegisterTableColumnType(columnData.DatabaseTypeName())
+	if err != nil {
+		return err
+	}
+	columnNamesID, err := p.profileStore.RegisterTableColumn(tableName.ID, columnTypeID, columnData.Name())
+	if err != nil {
+		return err
+	}
+
+	return p.profileStore.StoreColumnProfileData(columnNamesID, columnData.DatabaseTypeName(), run.ProfileRecordID, profileResults)
 }
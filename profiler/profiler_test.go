@@ -0,0 +1,100 @@
+package profiler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProfilerWithoutProfileStore(t *testing.T) {
+	p := NewProfilerWithOptions(nil, nil, ProfilerOptions{})
+	if p.hasProfileStore() {
+		t.Fatal("a profiler without a profile database should have no store")
+	}
+
+	run, err := p.startRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ProfileRecordID != 0 {
+		t.Errorf("profile record id = %d, want 0", run.ProfileRecordID)
+	}
+
+	rowCount := 5
+	run.Tables[`orders`] = &TableProfileResult{TableName: `orders`, RowCount: &rowCount}
+
+	//checks that need history are skipped, the others still fill in the run
+	err = p.detectAnomalies(run, AnomalyDetectionDefinition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Anomalies) != 0 {
+		t.Errorf("anomalies = %+v, want none without history", run.Anomalies)
+	}
+
+	expectations, err := parseProfileExpectations(ProfileDefinition{
+		CustomProfileTables: []TableDefinition{{TableName: `orders`, Expectations: []string{`row_count > 10`}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.evaluateExpectations(run, expectations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.ExpectationResults) != 1 || run.ExpectationResults[0].Passed {
+		t.Errorf("expectation results = %+v", run.ExpectationResults)
+	}
+
+	err = p.flushProfileStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunResultJSON(t *testing.T) {
+	rowCount := 5
+	run := newRunResult(0)
+	run.Tables[`orders`] = &TableProfileResult{
+		TableName: `orders`,
+		RowCount:  &rowCount,
+		Columns: map[string]*ColumnProfileResult{
+			`total`: {ColumnName: `total`, ColumnType: `NUMERIC`, Metrics: map[string]MetricValue{`maximum`: NewMetricValue(9.5)}},
+		},
+		CustomColumns: map[string]*CustomColumnProfileResult{},
+	}
+	run.markSchemaCaptured(`orders`)
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the unexported bookkeeping stays out of the output and empty lists are arrays rather than null
+	for _, key := range []string{`ProfileRecordID`, `StartTime`, `Tables`, `Anomalies`, `ExpectationResults`, `SchemaChanges`, `Freshness`, `Relationships`} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("json is missing %s", key)
+		}
+	}
+	if anomalies, ok := decoded[`Anomalies`].([]interface{}); !ok || len(anomalies) != 0 {
+		t.Errorf("anomalies = %v, want an empty array", decoded[`Anomalies`])
+	}
+	if _, ok := decoded[`schemaCaptured`]; ok {
+		t.Error("json has the unexported schema bookkeeping")
+	}
+
+	var roundTrip RunResult
+	err = json.Unmarshal(data, &roundTrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := roundTrip.Tables[`orders`].Columns[`total`]
+	if *roundTrip.Tables[`orders`].RowCount != 5 || !total.Metrics[`maximum`].Equal(NewMetricValue(9.5)) {
+		t.Errorf("round trip = %+v", roundTrip.Tables[`orders`])
+	}
+}
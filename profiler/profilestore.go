@@ -15,9 +15,27 @@ import (
 //This is a db wrapper essentially
 type ProfileStore struct {
 	UsePascalCase bool
+	//Number of rows to buffer per table before writing them in a single statement, 0 writes each row as it comes
+	InsertBatchSize int
+	//Write buffered rows using copy instead of multi-row inserts
+	UseCopy bool
+	//Either STORE_LAYOUT_WIDE or STORE_LAYOUT_LONG, empty uses the wide layout
+	StoreLayout string
+	//Schema all store tables are created in, empty uses the default schema
+	StoreSchema string
+	//Prefix added to the name of every store table
+	StoreTablePrefix string
 	dbConn      db.DBConn
 	tablesHaveBeenCreated bool
 	mux         sync.Mutex
+	//ids of registered tables, columns and types for the life of the store
+	registeredIDs map[string]int
+	registeredIDsMux sync.Mutex
+	//profile tables and columns we already know exist
+	verifiedColumns map[string]bool
+	verifiedColumnsMux sync.Mutex
+	pendingRows map[string]*pendingRowBatch
+	batchMux sync.Mutex
 }
 
 type ColumnProfileData struct {
@@ -29,16 +47,41 @@ type ColumnProfileData struct {
 func NewProfileStore(dbConn db.DBConn) *ProfileStore {
 	p := &ProfileStore{
 		UsePascalCase: false,
+		StoreLayout: STORE_LAYOUT_WIDE,
 		dbConn:      dbConn,
 		tablesHaveBeenCreated: false,
+		registeredIDs: map[string]int{},
+		verifiedColumns: map[string]bool{},
+		pendingRows: map[string]*pendingRowBatch{},
 	}
 	return p
 }
 
+//Creates a new profile store using the store settings from the profiler options
+func NewProfileStoreWithOptions(dbConn db.DBConn, options ProfilerOptions) *ProfileStore {
+	p := NewProfileStore(dbConn)
+	p.UsePascalCase = options.UsePascalCase
+	p.InsertBatchSize = options.InsertBatchSize
+	p.UseCopy = options.UseCopy
+	if options.StoreLayout != `` {
+		p.StoreLayout = options.StoreLayout
+	}
+	p.StoreSchema = options.StoreSchema
+	p.StoreTablePrefix = options.StoreTablePrefix
+	return p
+}
+
 //Ensures the core profile db data stores are built
 func (p *ProfileStore) ScaffoldProfileStore() error {
 
-	
+	//build the dedicated schema if we have one
+	if p.StoreSchema != `` {
+		err := p.dbConn.CreateSchemaIfNotExists(p.StoreSchema)
+		if err != nil {
+			return err
+		}
+	}
+
 	//build profile runs table
 	err := p.createTableForProfileStoreTableStruct(ProfileRecord{})
 	if err != nil {
@@ -57,6 +100,12 @@ func (p *ProfileStore) ScaffoldProfileStore() error {
 		return err
 	}
 
+	//build table metrics table
+	err = p.createTableForProfileStoreTableStruct(TableMetric{})
+	if err != nil {
+		return err
+	}
+
 	//build table columns table
 	err = p.createTableForProfileStoreTableStruct(TableColumnName{})
 	if err != nil {
@@ -75,6 +124,65 @@ func (p *ProfileStore) ScaffoldProfileStore() error {
 		return err
 	}
 
+	//build anomalies table
+	err = p.createTableForProfileStoreTableStruct(ProfileAnomaly{})
+	if err != nil {
+		return err
+	}
+
+	//build expectation results table
+	err = p.createTableForProfileStoreTableStruct(ExpectationResultRecord{})
+	if err != nil {
+		return err
+	}
+
+	//build schema snapshot and change tables
+	err = p.createTableForProfileStoreTableStruct(TableColumnSnapshot{})
+	if err != nil {
+		return err
+	}
+
+	err = p.createTableForProfileStoreTableStruct(SchemaChangeRecord{})
+	if err != nil {
+		return err
+	}
+
+	//build freshness table
+	err = p.createTableForProfileStoreTableStruct(TableFreshness{})
+	if err != nil {
+		return err
+	}
+
+	//build candidate keys table
+	err = p.createTableForProfileStoreTableStruct(CandidateKeyRecord{})
+	if err != nil {
+		return err
+	}
+
+	//build relationship profiles table
+	err = p.createTableForProfileStoreTableStruct(TableRelationshipProfile{})
+	if err != nil {
+		return err
+	}
+
+	switch p.StoreLayout {
+	case ``, STORE_LAYOUT_WIDE:
+		//wide layout tables are created per column type as profiles come in
+	case STORE_LAYOUT_LONG:
+		//build metric tables for the long layout
+		err = p.createTableForProfileStoreTableStruct(TableColumnMetric{})
+		if err != nil {
+			return err
+		}
+
+		err = p.createTableForProfileStoreTableStruct(TableCustomColumnMetric{})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(`unknown profile store layout %v`, p.StoreLayout)
+	}
+
 	p.tablesHaveBeenCreated = true
 	return nil
 
@@ -82,6 +190,9 @@ func (p *ProfileStore) ScaffoldProfileStore() error {
 
 //Stores the custom column profile data, scaffolds the custom profile table for the value type if needed
 func (p *ProfileStore) StoreCustomColumnProfileData(columnNamesID int, columnType *sql.ColumnType, profileID int, profileValue interface{}) error {
+	if p.isLongLayout() {
+		return p.storeCustomColumnProfileDataLong(columnNamesID, profileID, profileValue)
+	}
 
 	profileTable := p.getCustomColumnProfileTableName(columnType.DatabaseTypeName())
 
@@ -104,14 +215,17 @@ func (p *ProfileStore) StoreCustomColumnProfileData(columnNamesID int, columnTyp
 
 	columnDefinitions = p.handleDBColumnDefinitionArrNamingConvention(columnDefinitions)
 
-	//error here just means does not exist
-	tableExists, _ := p.dbConn.DoesTableExist(profileTable)
+	if !p.isColumnVerified(profileTable, ``) {
+		//error here just means does not exist
+		tableExists, _ := p.dbConn.DoesTableExist(profileTable)
 
-	if !tableExists {
-		err := p.dbConn.CreateTable(profileTable, columnDefinitions)
-		if err != nil {
-			return err
+		if !tableExists {
+			err := p.dbConn.CreateTable(profileTable, columnDefinitions)
+			if err != nil {
+				return err
+			}
 		}
+		p.setColumnVerified(profileTable, ``)
 	}
 
 	columnData := map[string]interface{}{
@@ -123,13 +237,14 @@ func (p *ProfileStore) StoreCustomColumnProfileData(columnNamesID int, columnTyp
 	columnData = p.handleColumnDataNamingConvention(columnData)
 
 	//At this point the table and columns exist, so insert data
-	p.dbConn.InsertRowAndReturnID(profileTable, columnData)
-
-	return nil
+	return p.queueRow(profileTable, columnData)
 }
 
 //TODO - make this function not horrible
 func (p *ProfileStore) StoreColumnProfileData(columnNamesID int, columnType string, profileID int, profileResults []ColumnProfileData) error {
+	if p.isLongLayout() {
+		return p.storeColumnProfileDataLong(columnNamesID, profileID, profileResults)
+	}
 
 	profileTable := p.getColumnProfileTableName(columnType)
 
@@ -154,17 +269,26 @@ func (p *ProfileStore) StoreColumnProfileData(columnNamesID int, columnType stri
 	columnDefinitions = p.handleDBColumnDefinitionArrNamingConvention(columnDefinitions)
 
 	//error here just means does not exist
-	tableExists, _ := p.dbConn.DoesTableExist(profileTable)
+	tableExists := p.isColumnVerified(profileTable, ``)
+	if !tableExists {
+		tableExists, _ = p.dbConn.DoesTableExist(profileTable)
+	}
 
 	if !tableExists {
 		err := p.dbConn.CreateTable(profileTable, columnDefinitions)
 		if err != nil {
 			return err
 		}
+		for _, data := range profileResults {
+			p.setColumnVerified(profileTable, p.handleNamingConvention(data.name))
+		}
 	} else {
 		//Table exists so just make sure each column exists
 		for _, data := range profileResults {
 			columnName := p.handleNamingConvention(data.name)
+			if p.isColumnVerified(profileTable, columnName) {
+				continue
+			}
 			columnExists, _ := p.dbConn.DoesTableColumnExist(profileTable, columnName)
 
 			//if column does not exist then create it
@@ -177,8 +301,10 @@ func (p *ProfileStore) StoreColumnProfileData(columnNamesID int, columnType stri
 					return err
 				}
 			}
+			p.setColumnVerified(profileTable, columnName)
 		}
 	}
+	p.setColumnVerified(profileTable, ``)
 
 	columnData := map[string]interface{}{
 		TABLE_COLUMN_NAME_ID: columnNamesID,
@@ -191,9 +317,7 @@ func (p *ProfileStore) StoreColumnProfileData(columnNamesID int, columnType stri
 	columnData = p.handleColumnDataNamingConvention(columnData)
 
 	//At this point the table and columns exist, so insert data
-	p.dbConn.InsertRowAndReturnID(profileTable, columnData)
-
-	return nil
+	return p.queueRow(profileTable, columnData)
 }
 
 //Creates a new profile entry and returns the profile id
@@ -204,7 +328,7 @@ func (p *ProfileStore) NewProfile() (int, error) {
 }
 
 func (p *ProfileStore) RegisterTableColumn(tableNameID int, columnTypeID int, columnName string) (int, error) {
-	return p.getOrInsertTableRowIDFromStruct(TableColumnName{
+	return p.getOrInsertCachedTableRowIDFromStruct(TableColumnName{
 		TableNameID: tableNameID,
 		TableColumnName: columnName,
 		TableColumnTypeID: columnTypeID,
@@ -212,7 +336,7 @@ func (p *ProfileStore) RegisterTableColumn(tableNameID int, columnTypeID int, co
 }
 
 func (p *ProfileStore) RegisterTableCustomColumn(tableNameID int, columnTypeID int, columnName string, columnDefinition string) (int, error) {
-	return p.getOrInsertTableRowIDFromStruct(TableCustomColumnName{
+	return p.getOrInsertCachedTableRowIDFromStruct(TableCustomColumnName{
 		TableNameID: tableNameID,
 		TableColumnName: columnName,
 		TableColumnTypeID: columnTypeID,
@@ -221,7 +345,7 @@ func (p *ProfileStore) RegisterTableCustomColumn(tableNameID int, columnTypeID i
 }
 
 func (p *ProfileStore) RegisterTable(tableName string) (int, error) {
-	return p.getOrInsertTableRowIDFromStruct(TableName{
+	return p.getOrInsertCachedTableRowIDFromStruct(TableName{
 		TableName: tableName,
 	})
 }
@@ -229,7 +353,7 @@ func (p *ProfileStore) RegisterTable(tableName string) (int, error) {
 func (p *ProfileStore) RegisterTableColumnType(columnDataType string) (int, error) {
 	p.mux.Lock()
 	defer p.mux.Unlock()
-	return p.getOrInsertTableRowIDFromStruct(TableColumnType{
+	return p.getOrInsertCachedTableRowIDFromStruct(TableColumnType{
 		TableColumnType: columnDataType,
 	})
 }
@@ -242,6 +366,43 @@ func (p *ProfileStore) RecordTableProfile(tableNameID int, rowCount int, profile
 	})
 }
 
+//Same as getOrInsertTableRowIDFromStruct but remembers the id for the life of the store
+//so repeat registrations do not hit the database
+func (p *ProfileStore) getOrInsertCachedTableRowIDFromStruct(tableStruct interface{}) (int, error) {
+	cacheKey := fmt.Sprintf(`%T%+v`, tableStruct, tableStruct)
+
+	p.registeredIDsMux.Lock()
+	id, ok := p.registeredIDs[cacheKey]
+	p.registeredIDsMux.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := p.getOrInsertTableRowIDFromStruct(tableStruct)
+	if err != nil {
+		return 0, err
+	}
+
+	p.registeredIDsMux.Lock()
+	p.registeredIDs[cacheKey] = id
+	p.registeredIDsMux.Unlock()
+
+	return id, nil
+}
+
+func (p *ProfileStore) isColumnVerified(tableName string, columnName string) bool {
+	p.verifiedColumnsMux.Lock()
+	defer p.verifiedColumnsMux.Unlock()
+	return p.verifiedColumns[tableName+`.`+columnName]
+}
+
+//Marks the table column as existing, an empty column name marks the table itself
+func (p *ProfileStore) setColumnVerified(tableName string, columnName string) {
+	p.verifiedColumnsMux.Lock()
+	defer p.verifiedColumnsMux.Unlock()
+	p.verifiedColumns[tableName+`.`+columnName] = true
+}
+
 //Converts the struct to the params needed for getOrInsertTableRowID
 //uses tag data, excludes primary key field
 func (p *ProfileStore) getOrInsertTableRowIDFromStruct(tableStruct interface{}) (int, error) {
@@ -250,6 +411,21 @@ func (p *ProfileStore) getOrInsertTableRowIDFromStruct(tableStruct interface{})
 		return 0, err
 	}
 
+	return p.getOrInsertTableRowID(tableName, p.getColumnDataMapFromStruct(tableStruct))
+}
+
+//Writes the struct as a new row without checking for an existing one, goes through the batches if enabled
+func (p *ProfileStore) insertStoreRowFromStruct(tableStruct interface{}) error {
+	tableName, err := p.getTableNameFromStruct(tableStruct)
+	if err != nil {
+		return err
+	}
+
+	return p.queueRow(tableName, p.getColumnDataMapFromStruct(tableStruct))
+}
+
+//Returns a map of column name to field value using the db tags, excludes the primary key field
+func (p *ProfileStore) getColumnDataMapFromStruct(tableStruct interface{}) map[string]interface{} {
 	columnDataMap := map[string]interface{}{}
 
 	fieldValues := reflect.ValueOf(tableStruct)	//for value references below
@@ -267,12 +443,11 @@ func (p *ProfileStore) getOrInsertTableRowIDFromStruct(tableStruct interface{})
 		}
 	}
 
-	return p.getOrInsertTableRowID(tableName, columnDataMap)
+	return columnDataMap
 }
 
 func (p *ProfileStore) getOrInsertTableRowID(tableName string, values map[string]interface{}) (int, error) {
-	//fix naming conventions
-	tableName = p.handleNamingConvention(tableName)
+	//fix naming conventions, the table name already has them applied
 	values = p.handleColumnDataNamingConvention(values)
 
 	rows, err := p.dbConn.GetRowsSelectWhere(tableName, []string{`id`}, values)
@@ -294,12 +469,30 @@ func (p *ProfileStore) getOrInsertTableRowID(tableName string, values map[string
 
 func (p *ProfileStore) getColumnProfileTableName(columnDataType string) string {
 	name := fmt.Sprintf(`%s%s`, TABLE_COLUMN_PROFILE_PREFIX, columnDataType)
-	return p.handleNamingConvention(name)
+	return p.getStoreTableName(name)
 }
 
 func (p *ProfileStore) getCustomColumnProfileTableName(columnDataType string) string {
 	name := fmt.Sprintf(`%s%s`, TABLE_CUSTOM_COLUMN_PROFILE_PREFIX, columnDataType)
-	return p.handleNamingConvention(name)
+	return p.getStoreTableName(name)
+}
+
+//Returns the full name of a store table, with the table prefix, naming convention and schema applied
+func (p *ProfileStore) getStoreTableName(name string) string {
+	return p.qualifyStoreTableName(p.getUnqualifiedStoreTableName(name, p.UsePascalCase))
+}
+
+//Returns the store table name with the table prefix and requested naming convention but without the schema
+func (p *ProfileStore) getUnqualifiedStoreTableName(name string, usePascalCase bool) string {
+	return p.convertNamingConvention(p.StoreTablePrefix+name, usePascalCase)
+}
+
+//Adds the store schema to a table name if we have one
+func (p *ProfileStore) qualifyStoreTableName(tableName string) string {
+	if p.StoreSchema == `` {
+		return tableName
+	}
+	return fmt.Sprintf(`%s.%s`, p.StoreSchema, tableName)
 }
 
 //Creates a table for the profile store table struct if not exists
@@ -320,12 +513,21 @@ func (p *ProfileStore) createTableForProfileStoreTableStruct(tableStruct interfa
 //Takes a struct and looks for a table tag on a field
 //returns the string of the tag or error if none found
 func (p *ProfileStore) getTableNameFromStruct(tableStruct interface{}) (string, error){
+	tableName, err := getStructTableTag(tableStruct)
+	if err != nil {
+		return ``, err
+	}
+	return p.getStoreTableName(tableName), nil
+}
+
+//Returns the raw table tag of the struct before any naming convention is applied
+func getStructTableTag(tableStruct interface{}) (string, error) {
 	fields := reflect.TypeOf(tableStruct)
 	for i := 0; i < fields.NumField(); i++ {
 		field := fields.Field(i)
 		tableName, ok := field.Tag.Lookup(`table`)
 		if ok {
-			return p.handleNamingConvention(tableName), nil			
+			return tableName, nil
 		}
 	}
 	return ``, fmt.Errorf(`no table tag found on struct %v`, tableStruct)
@@ -372,7 +574,12 @@ func (p *ProfileStore) handleDBColumnDefinitionArrNamingConvention(definitions [
 }
 
 func (p *ProfileStore) handleNamingConvention(name string) string {
-	if p.UsePascalCase {
+	return p.convertNamingConvention(name, p.UsePascalCase)
+}
+
+//Converts the snake case name to the requested naming convention
+func (p *ProfileStore) convertNamingConvention(name string, usePascalCase bool) string {
+	if usePascalCase {
 		return p.convertSnakeCaseToPascalCase(name)
 	}
 	return name
@@ -398,4 +605,140 @@ func (p *ProfileStore) resolveDataType(data interface{}, dbScanType reflect.Type
 	}
 
 	return dbScanType
-}
\ No newline at end of file
+}
+
+//Records an anomaly found for the run
+func (p *ProfileStore) RecordAnomaly(profileID int, anomaly Anomaly) error {
+	tableNameID, err := p.RegisterTable(anomaly.TableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(ProfileAnomaly{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		ColumnName: anomaly.ColumnName,
+		MetricName: anomaly.MetricName,
+		MetricValue: anomaly.Value,
+		ExpectedValue: anomaly.ExpectedValue,
+		Spread: anomaly.Spread,
+		Score: anomaly.Score,
+		Method: anomaly.Method,
+		HistorySize: anomaly.HistorySize,
+	})
+}
+
+//Records the outcome of an expectation for the run
+func (p *ProfileStore) RecordExpectationResult(profileID int, result ExpectationResult) error {
+	tableNameID, err := p.RegisterTable(result.TableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(ExpectationResultRecord{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		Expectation: result.Expectation,
+		Passed: result.Passed,
+		ObservedValue: result.ObservedValue.String(),
+		Message: result.Message,
+	})
+}
+
+//Records the catalog definition of a table column for the run
+func (p *ProfileStore) RecordTableColumnSnapshot(tableNameID int, profileID int, column db.DBTableColumn) error {
+	return p.insertStoreRowFromStruct(TableColumnSnapshot{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		ColumnName: column.ColumnName,
+		ColumnType: column.DataType,
+		IsNullable: column.IsNullable,
+		OrdinalPosition: column.OrdinalPosition,
+	})
+}
+
+//Records a schema change found for the run
+func (p *ProfileStore) RecordSchemaChange(profileID int, change SchemaChange) error {
+	tableNameID, err := p.RegisterTable(change.TableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(SchemaChangeRecord{
+		ProfileRecordID: profileID,
+		PreviousProfileRecordID: change.PreviousProfileRecordID,
+		TableNameID: tableNameID,
+		ChangeType: change.ChangeType,
+		ColumnName: change.ColumnName,
+		PreviousColumnName: change.PreviousColumnName,
+		ColumnType: change.ColumnType,
+		PreviousColumnType: change.PreviousColumnType,
+	})
+}
+
+//Records a table level metric for the run
+func (p *ProfileStore) RecordTableMetric(tableName string, profileID int, metricName string, value float64) error {
+	tableNameID, err := p.RegisterTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(TableMetric{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		MetricName: metricName,
+		MetricValue: value,
+	})
+}
+
+//Records the freshness of a table for the run
+func (p *ProfileStore) RecordTableFreshness(profileID int, result FreshnessResult) error {
+	tableNameID, err := p.RegisterTable(result.TableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(TableFreshness{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		ColumnName: result.ColumnName,
+		NewestValue: result.NewestValue,
+		LagSeconds: result.LagSeconds,
+		MaxStalenessSeconds: result.MaxStalenessSeconds,
+		IsStale: result.IsStale,
+	})
+}
+
+//Records a candidate key found for the table
+func (p *ProfileStore) RecordCandidateKey(tableName string, discoveredAt time.Time, columnNames []string) error {
+	tableNameID, err := p.RegisterTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(CandidateKeyRecord{
+		DiscoveredAt: discoveredAt,
+		TableNameID: tableNameID,
+		ColumnNames: formatCandidateKey(columnNames),
+		ColumnCount: len(columnNames),
+	})
+}
+
+//Records the orphan count of a relationship for the run
+func (p *ProfileStore) RecordRelationshipProfile(profileID int, relationship RelationshipResult) error {
+	tableNameID, err := p.RegisterTable(relationship.TableName)
+	if err != nil {
+		return err
+	}
+
+	return p.insertStoreRowFromStruct(TableRelationshipProfile{
+		ProfileRecordID: profileID,
+		TableNameID: tableNameID,
+		RelationshipName: relationship.Name,
+		ColumnNames: strings.Join(relationship.Columns, `,`),
+		ReferencedTableName: relationship.ReferencedTable,
+		ReferencedColumnNames: strings.Join(relationship.ReferencedColumns, `,`),
+		IsDeclared: relationship.IsDeclared,
+		OrphanCount: relationship.OrphanCount,
+	})
+}
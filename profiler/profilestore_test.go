@@ -0,0 +1,115 @@
+package profiler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetStoreTableName(t *testing.T) {
+	tests := []struct {
+		name          string
+		schema        string
+		prefix        string
+		usePascalCase bool
+		want          string
+		wantProfile   string
+	}{
+		{name: "defaults", want: `table_names`, wantProfile: `table_column_profiles_INT4`},
+		{name: "schema", schema: `profiler`, want: `profiler.table_names`, wantProfile: `profiler.table_column_profiles_INT4`},
+		{name: "prefix", prefix: `prof_`, want: `prof_table_names`, wantProfile: `prof_table_column_profiles_INT4`},
+		{name: "schema and prefix in pascal case", schema: `profiler`, prefix: `prof_`, usePascalCase: true, want: `profiler.ProfTableNames`, wantProfile: `profiler.ProfTableColumnProfilesINT4`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewProfileStoreWithOptions(nil, ProfilerOptions{
+				StoreSchema:      test.schema,
+				StoreTablePrefix: test.prefix,
+				UsePascalCase:    test.usePascalCase,
+			})
+
+			//the schema is never converted to the naming convention
+			got, err := store.getTableNameFromStruct(TableName{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("table name = %s, want %s", got, test.want)
+			}
+			if got := store.getColumnProfileTableName(`INT4`); got != test.wantProfile {
+				t.Errorf("column profile table name = %s, want %s", got, test.wantProfile)
+			}
+		})
+	}
+}
+
+func TestScaffoldProfileStoreInSchema(t *testing.T) {
+	conn := &recordingDBConn{}
+	store := NewProfileStoreWithOptions(conn, ProfilerOptions{StoreSchema: `profiler`, StoreTablePrefix: `prof_`, StoreLayout: STORE_LAYOUT_LONG})
+
+	err := store.ScaffoldProfileStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(conn.created) != len(getStoreTableStructs())+1 || conn.created[0] != `schema profiler` {
+		t.Fatalf("created = %v", conn.created)
+	}
+	for _, tableName := range conn.created[1:] {
+		if !strings.HasPrefix(tableName, `profiler.prof_`) {
+			t.Errorf("table %s is not in the store schema with the prefix", tableName)
+		}
+	}
+}
+
+func TestGetLaterProfileID(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	registry := &storeRegistry{profileDates: map[int]time.Time{
+		1: day,
+		2: day.Add(time.Hour),
+		3: day.Add(time.Hour),
+		4: day,
+	}}
+
+	tests := []struct {
+		name  string
+		id    int
+		other int
+		want  int
+	}{
+		{name: "no run yet", id: 0, other: 1, want: 1},
+		{name: "newer date", id: 1, other: 2, want: 2},
+		{name: "older date", id: 2, other: 4, want: 2},
+		{name: "same date takes the higher id", id: 3, other: 2, want: 3},
+		{name: "same date either way round", id: 2, other: 3, want: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := registry.getLaterProfileID(test.id, test.other)
+			if got != test.want {
+				t.Errorf("later of %d and %d = %d, want %d", test.id, test.other, got, test.want)
+			}
+		})
+	}
+
+	//the latest run and the end of the sorted runs agree on ties
+	runs := []ProfileRun{}
+	latestProfileID := 0
+	for id, date := range registry.profileDates {
+		runs = append(runs, ProfileRun{ID: id, ProfileDate: date})
+		latestProfileID = registry.getLaterProfileID(latestProfileID, id)
+	}
+	sortProfileRuns(runs)
+	ids := []int{}
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 4 || ids[2] != 2 || ids[3] != 3 {
+		t.Errorf("sorted runs = %v, want [1 4 2 3]", ids)
+	}
+	if latestProfileID != 3 {
+		t.Errorf("latest run = %d, want 3", latestProfileID)
+	}
+}
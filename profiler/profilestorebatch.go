@@ -0,0 +1,84 @@
+package profiler
+
+import (
+	"sort"
+	"strings"
+)
+
+//Rows waiting to be written to a single profile store table
+//all rows in a batch share the same set of columns
+type pendingRowBatch struct {
+	tableName string
+	columns   []string
+	rows      [][]interface{}
+}
+
+//Returns true if rows should be buffered and written in batches instead of one insert per row
+func (p *ProfileStore) isBatchingEnabled() bool {
+	return p.InsertBatchSize > 0 || p.UseCopy
+}
+
+//Writes a row to the profile store table, either directly or via the pending batches
+func (p *ProfileStore) queueRow(tableName string, values map[string]interface{}) error {
+	if !p.isBatchingEnabled() {
+		p.dbConn.InsertRowAndReturnID(tableName, values)
+		return nil
+	}
+
+	//sort the columns so rows with the same columns always land in the same batch
+	columns := []string{}
+	for col := range values {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	row := make([]interface{}, len(columns))
+	for idx, col := range columns {
+		row[idx] = values[col]
+	}
+
+	batchKey := tableName + `|` + strings.Join(columns, `,`)
+
+	p.batchMux.Lock()
+	defer p.batchMux.Unlock()
+
+	batch, ok := p.pendingRows[batchKey]
+	if !ok {
+		batch = &pendingRowBatch{
+			tableName: tableName,
+			columns:   columns,
+		}
+		p.pendingRows[batchKey] = batch
+	}
+	batch.rows = append(batch.rows, row)
+
+	if p.InsertBatchSize > 0 && len(batch.rows) >= p.InsertBatchSize {
+		delete(p.pendingRows, batchKey)
+		return p.writeBatch(batch)
+	}
+
+	return nil
+}
+
+//Writes all pending batched rows to the profile store
+func (p *ProfileStore) Flush() error {
+	p.batchMux.Lock()
+	defer p.batchMux.Unlock()
+
+	for batchKey, batch := range p.pendingRows {
+		delete(p.pendingRows, batchKey)
+		err := p.writeBatch(batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *ProfileStore) writeBatch(batch *pendingRowBatch) error {
+	if p.UseCopy {
+		return p.dbConn.CopyRows(batch.tableName, batch.columns, batch.rows)
+	}
+	return p.dbConn.InsertRows(batch.tableName, batch.columns, batch.rows)
+}
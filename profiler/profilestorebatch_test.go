@@ -0,0 +1,139 @@
+package profiler
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//Records the writes made to it, any other call panics on the nil embedded connection
+type recordingDBConn struct {
+	db.DBConn
+	inserted []string
+	written  []string
+	created  []string
+}
+
+func (c *recordingDBConn) CreateSchemaIfNotExists(schemaName string) error {
+	c.created = append(c.created, `schema `+schemaName)
+	return nil
+}
+
+func (c *recordingDBConn) CreateTableIfNotExists(tableName string, columns []db.DBColumnDefinition) error {
+	c.created = append(c.created, tableName)
+	return nil
+}
+
+func (c *recordingDBConn) InsertRowAndReturnID(tableName string, values map[string]interface{}) int {
+	c.inserted = append(c.inserted, tableName)
+	return len(c.inserted)
+}
+
+func (c *recordingDBConn) InsertRows(tableName string, columns []string, rows [][]interface{}) error {
+	c.written = append(c.written, describeBatch(`insert`, tableName, columns, rows))
+	return nil
+}
+
+func (c *recordingDBConn) CopyRows(tableName string, columns []string, rows [][]interface{}) error {
+	c.written = append(c.written, describeBatch(`copy`, tableName, columns, rows))
+	return nil
+}
+
+func describeBatch(method string, tableName string, columns []string, rows [][]interface{}) string {
+	return method + ` ` + tableName + `(` + strings.Join(columns, `,`) + `) ` + strings.Repeat(`r`, len(rows))
+}
+
+func TestQueueRow(t *testing.T) {
+	tests := []struct {
+		name         string
+		batchSize    int
+		useCopy      bool
+		wantInserted int
+		wantQueued   []string
+		wantFlushed  []string
+	}{
+		{
+			name:         "batching off",
+			wantInserted: 4,
+		},
+		{
+			name:        "full batches are written as they fill",
+			batchSize:   2,
+			wantQueued:  []string{`insert metrics(id,value) rr`},
+			wantFlushed: []string{`insert metrics(id,value) rr`, `insert metrics(id,value,x) r`, `insert profiles(id) r`},
+		},
+		{
+			name:        "copy buffers until flushed",
+			useCopy:     true,
+			wantFlushed: []string{`copy metrics(id,value) rr`, `copy metrics(id,value,x) r`, `copy profiles(id) r`},
+		},
+		{
+			name:        "copy with a batch size",
+			batchSize:   2,
+			useCopy:     true,
+			wantQueued:  []string{`copy metrics(id,value) rr`},
+			wantFlushed: []string{`copy metrics(id,value) rr`, `copy metrics(id,value,x) r`, `copy profiles(id) r`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := &recordingDBConn{}
+			store := NewProfileStore(conn)
+			store.InsertBatchSize = test.batchSize
+			store.UseCopy = test.useCopy
+
+			//rows with other columns go in their own batch even for the same table
+			rows := []struct {
+				tableName string
+				values    map[string]interface{}
+			}{
+				{`metrics`, map[string]interface{}{`id`: 1, `value`: 1.5}},
+				{`metrics`, map[string]interface{}{`value`: 2.5, `id`: 2}},
+				{`metrics`, map[string]interface{}{`id`: 3, `value`: 3.5, `x`: nil}},
+				{`profiles`, map[string]interface{}{`id`: 4}},
+			}
+			for _, row := range rows {
+				err := store.queueRow(row.tableName, row.values)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if strings.Join(conn.written, `;`) != strings.Join(test.wantQueued, `;`) {
+				t.Errorf("written before flush = %v, want %v", conn.written, test.wantQueued)
+			}
+
+			err := store.Flush()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(conn.written)
+			if strings.Join(conn.written, `;`) != strings.Join(test.wantFlushed, `;`) {
+				t.Errorf("written after flush = %v, want %v", conn.written, test.wantFlushed)
+			}
+			if len(conn.inserted) != test.wantInserted {
+				t.Errorf("inserted %d single rows, want %d", len(conn.inserted), test.wantInserted)
+			}
+			if len(store.pendingRows) != 0 {
+				t.Errorf("%d batches still pending", len(store.pendingRows))
+			}
+		})
+	}
+}
+
+func TestQueueRowKeepsColumnOrder(t *testing.T) {
+	conn := &recordingDBConn{}
+	store := NewProfileStore(conn)
+	store.UseCopy = true
+
+	store.queueRow(`metrics`, map[string]interface{}{`value`: `b`, `id`: 1, `name`: `a`})
+	batch := store.pendingRows[`metrics|id,name,value`]
+	if batch == nil {
+		t.Fatalf("pending batches = %v", store.pendingRows)
+	}
+	if row := batch.rows[0]; row[0] != 1 || row[1] != `a` || row[2] != `b` {
+		t.Errorf("row = %v, want the values in sorted column order", row)
+	}
+}
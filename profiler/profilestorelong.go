@@ -0,0 +1,77 @@
+package profiler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Returns true if the store writes one row per metric instead of one wide row per column profile
+func (p *ProfileStore) isLongLayout() bool {
+	return p.StoreLayout == STORE_LAYOUT_LONG
+}
+
+//Stores each profile result as its own metric row in the long layout metrics table
+func (p *ProfileStore) storeColumnProfileDataLong(columnNamesID int, profileID int, profileResults []ColumnProfileData) error {
+	tableName, err := p.getTableNameFromStruct(TableColumnMetric{})
+	if err != nil {
+		return err
+	}
+
+	for _, data := range profileResults {
+		err := p.queueRow(tableName, p.getMetricRowData(TABLE_COLUMN_NAME_ID, columnNamesID, profileID, data.name, data.data))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Stores the custom column value as a metric row in the long layout custom metrics table
+func (p *ProfileStore) storeCustomColumnProfileDataLong(columnNamesID int, profileID int, profileValue interface{}) error {
+	tableName, err := p.getTableNameFromStruct(TableCustomColumnMetric{})
+	if err != nil {
+		return err
+	}
+
+	return p.queueRow(tableName, p.getMetricRowData(TABLE_CUSTOM_COLUMN_NAME_ID, columnNamesID, profileID, CUSTOM_COLUMN_METRIC_NAME, profileValue))
+}
+
+//Builds the insert data for a metric row, the value lands in the column matching its type and the others are left null
+func (p *ProfileStore) getMetricRowData(columnNameIDField string, columnNamesID int, profileID int, metricName string, value interface{}) map[string]interface{} {
+	numericValue, textValue, timestampValue := splitMetricValue(value)
+
+	columnData := map[string]interface{}{
+		columnNameIDField: columnNamesID,
+		PROFILE_RECORD_ID: profileID,
+		METRIC_NAME:       metricName,
+		NUMERIC_VALUE:     numericValue,
+		TEXT_VALUE:        textValue,
+		TIMESTAMP_VALUE:   timestampValue,
+	}
+
+	return p.handleColumnDataNamingConvention(columnData)
+}
+
+//Splits a profile value into its numeric, text and timestamp parts, only one of which is ever set
+func splitMetricValue(value interface{}) (numericValue interface{}, textValue interface{}, timestampValue interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, nil, nil
+	case []byte:
+		//the driver hands back numerics as bytes, anything else in bytes is kept as text
+		if _, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return v, nil, nil
+		}
+		return nil, string(v), nil
+	case string:
+		return nil, v, nil
+	case time.Time:
+		return nil, nil, v
+	default:
+		return nil, fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,68 @@
+package profiler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitMetricValue(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		value         interface{}
+		wantNumeric   interface{}
+		wantText      interface{}
+		wantTimestamp interface{}
+	}{
+		{name: "null", value: nil},
+		{name: "integer", value: int64(42), wantNumeric: int64(42)},
+		{name: "float", value: 1.5, wantNumeric: 1.5},
+		{name: "numeric bytes", value: []byte(`12.50`), wantNumeric: []byte(`12.50`)},
+		{name: "text bytes", value: []byte(`shipped`), wantText: `shipped`},
+		{name: "text", value: `shipped`, wantText: `shipped`},
+		{name: "timestamp", value: timestamp, wantTimestamp: timestamp},
+		{name: "anything else", value: true, wantText: `true`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			numeric, text, timestamp := splitMetricValue(test.value)
+			if !reflect.DeepEqual(numeric, test.wantNumeric) || !reflect.DeepEqual(text, test.wantText) || !reflect.DeepEqual(timestamp, test.wantTimestamp) {
+				t.Errorf("split = %v, %v, %v, want %v, %v, %v", numeric, text, timestamp, test.wantNumeric, test.wantText, test.wantTimestamp)
+			}
+		})
+	}
+}
+
+func TestGetMetricRowData(t *testing.T) {
+	store := NewProfileStore(nil)
+	got := store.getMetricRowData(TABLE_COLUMN_NAME_ID, 3, 7, `maximum`, `zebra`)
+	want := map[string]interface{}{
+		TABLE_COLUMN_NAME_ID: 3,
+		PROFILE_RECORD_ID:    7,
+		METRIC_NAME:          `maximum`,
+		NUMERIC_VALUE:        nil,
+		TEXT_VALUE:           `zebra`,
+		TIMESTAMP_VALUE:      nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("row = %v, want %v", got, want)
+	}
+
+	//the column names follow the store's naming convention
+	store.UsePascalCase = true
+	got = store.getMetricRowData(TABLE_CUSTOM_COLUMN_NAME_ID, 3, 7, CUSTOM_COLUMN_METRIC_NAME, 5)
+	want = map[string]interface{}{
+		`TableColumnNameId`: 3,
+		`ProfileRecordId`:   7,
+		`MetricName`:        CUSTOM_COLUMN_METRIC_NAME,
+		`NumericValue`:      5,
+		`TextValue`:         nil,
+		`TimestampValue`:    nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pascal case row = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,266 @@
+package profiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//NamingMigrationResult reports the renames done, or planned on a dry run, when moving
+//a profile store between snake_case and PascalCase
+type NamingMigrationResult struct {
+	ToPascalCase bool
+	DryRun       bool
+	TableRenames []TableRename
+	//Columns that could not be mapped to a known store column, in table.column form
+	UnmappedColumns []string
+}
+
+//Table names are without the store schema, renamed tables stay in their schema
+type TableRename struct {
+	TableName     string
+	NewTableName  string
+	ColumnRenames []ColumnRename
+}
+
+type ColumnRename struct {
+	ColumnName    string
+	NewColumnName string
+}
+
+//MigrateNamingConvention renames every store table and column, including the per type profile
+//tables, from one naming convention to the other in place and then verifies the result.
+//The store's own UsePascalCase setting is updated to the new convention on success.
+func (p *ProfileStore) MigrateNamingConvention(toPascalCase bool, dryRun bool) (*NamingMigrationResult, error) {
+	result := &NamingMigrationResult{
+		ToPascalCase: toPascalCase,
+		DryRun:       dryRun,
+	}
+
+	tableRenames, unmappedColumns, err := p.planNamingMigration(!toPascalCase, toPascalCase)
+	if err != nil {
+		return nil, err
+	}
+	result.TableRenames = tableRenames
+	result.UnmappedColumns = unmappedColumns
+
+	if dryRun {
+		return result, nil
+	}
+
+	//run every rename and the verification in one transaction so a failure leaves the store in its old convention
+	err = p.dbConn.RunInTransaction(func(conn db.DBConn) error {
+		for _, tableRename := range tableRenames {
+			tableName := p.qualifyStoreTableName(tableRename.TableName)
+
+			//rename the columns while the table still has its old name
+			for _, columnRename := range tableRename.ColumnRenames {
+				err := conn.RenameTableColumn(tableName, columnRename.ColumnName, columnRename.NewColumnName)
+				if err != nil {
+					return err
+				}
+			}
+
+			//renamed tables stay in their schema so the new name is unqualified
+			if !isSameStoreName(tableRename.TableName, tableRename.NewTableName) {
+				err := conn.RenameTable(tableName, tableRename.NewTableName)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		//verify inside the transaction so a bad result is rolled back
+		return p.verifyNamingMigration(conn, tableRenames)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	p.UsePascalCase = toPascalCase
+	return result, nil
+}
+
+//Builds the list of table and column renames needed to move between naming conventions
+func (p *ProfileStore) planNamingMigration(fromPascalCase bool, toPascalCase bool) ([]TableRename, []string, error) {
+	tableRenames := []TableRename{}
+	unmappedColumns := []string{}
+
+	addTable := func(tableName string, newTableName string, knownColumns []string) error {
+		//make sure we never merge into tables that already exist in the target convention
+		if !isSameStoreName(tableName, newTableName) {
+			targetExists, _ := p.dbConn.DoesTableExist(p.qualifyStoreTableName(newTableName))
+			if targetExists {
+				return fmt.Errorf(`cannot migrate %s, table %s already exists`, tableName, newTableName)
+			}
+		}
+
+		tableRename, unmapped, err := p.planTableColumnRenames(tableName, newTableName, knownColumns, fromPascalCase, toPascalCase)
+		if err != nil {
+			return err
+		}
+		tableRenames = append(tableRenames, tableRename)
+		unmappedColumns = append(unmappedColumns, unmapped...)
+		return nil
+	}
+
+	//statically defined store tables
+	for _, tableStruct := range getStoreTableStructs() {
+		tableTag, err := getStructTableTag(tableStruct)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tableName := p.getUnqualifiedStoreTableName(tableTag, fromPascalCase)
+		//error here just means does not exist
+		tableExists, _ := p.dbConn.DoesTableExist(p.qualifyStoreTableName(tableName))
+		if !tableExists {
+			continue
+		}
+
+		err = addTable(tableName, p.getUnqualifiedStoreTableName(tableTag, toPascalCase), getStructColumnNames(tableStruct))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	//per type profile tables created by the wide layout
+	dynamicTables := map[string][]string{
+		TABLE_COLUMN_PROFILE_PREFIX:        {`id`, TABLE_COLUMN_NAME_ID, PROFILE_RECORD_ID},
+		TABLE_CUSTOM_COLUMN_PROFILE_PREFIX: {`id`, TABLE_CUSTOM_COLUMN_NAME_ID, PROFILE_RECORD_ID, CUSTOM_COLUMN_METRIC_NAME},
+	}
+	for prefix, knownColumns := range dynamicTables {
+		sourcePrefix := p.getUnqualifiedStoreTableName(prefix, fromPascalCase)
+		tableNames, err := p.dbConn.GetTableNamesWithPrefix(p.StoreSchema, sourcePrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, tableName := range tableNames {
+			//the remainder of the name is the database column type
+			columnType := tableName[len(sourcePrefix):]
+			columns := append([]string{}, knownColumns...)
+			for metricName := range p.dbConn.ProfilesByType(strings.ToUpper(columnType)) {
+				columns = append(columns, metricName)
+			}
+			for metricName := range p.dbConn.QualityProfilesByType(strings.ToUpper(columnType)) {
+				columns = append(columns, metricName)
+			}
+
+			newTableName := p.getUnqualifiedStoreTableName(prefix+columnType, toPascalCase)
+			err = addTable(tableName, newTableName, columns)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return tableRenames, unmappedColumns, nil
+}
+
+//Works out the column renames for a single table, columns that are not known store
+//columns cannot be converted back from pascal case and are returned as unmapped
+func (p *ProfileStore) planTableColumnRenames(tableName string, newTableName string, knownColumns []string, fromPascalCase bool, toPascalCase bool) (TableRename, []string, error) {
+	existingColumns, err := getStoreTableColumnNames(p.dbConn, p.qualifyStoreTableName(tableName))
+	if err != nil {
+		return TableRename{TableName: tableName, NewTableName: newTableName}, nil, err
+	}
+
+	tableRename, unmappedColumns := p.planColumnRenames(tableName, newTableName, existingColumns, knownColumns, fromPascalCase, toPascalCase)
+	return tableRename, unmappedColumns, nil
+}
+
+//Maps the existing columns of a table to the new naming convention
+func (p *ProfileStore) planColumnRenames(tableName string, newTableName string, existingColumns []string, knownColumns []string, fromPascalCase bool, toPascalCase bool) (TableRename, []string) {
+	tableRename := TableRename{
+		TableName:    tableName,
+		NewTableName: newTableName,
+	}
+	unmappedColumns := []string{}
+
+	//map the source convention of each known snake case column back to its snake case name
+	knownColumnsBySourceName := map[string]string{}
+	for _, columnName := range knownColumns {
+		knownColumnsBySourceName[strings.ToLower(p.convertNamingConvention(columnName, fromPascalCase))] = columnName
+	}
+
+	for _, columnName := range existingColumns {
+		snakeCaseName, ok := knownColumnsBySourceName[strings.ToLower(columnName)]
+		if !ok {
+			if fromPascalCase {
+				unmappedColumns = append(unmappedColumns, fmt.Sprintf(`%s.%s`, tableName, columnName))
+				continue
+			}
+			//snake case columns can always be converted
+			snakeCaseName = columnName
+		}
+
+		newColumnName := p.convertNamingConvention(snakeCaseName, toPascalCase)
+		if !isSameStoreName(columnName, newColumnName) {
+			tableRename.ColumnRenames = append(tableRename.ColumnRenames, ColumnRename{
+				ColumnName:    columnName,
+				NewColumnName: newColumnName,
+			})
+		}
+	}
+
+	return tableRename, unmappedColumns
+}
+
+//Checks that every renamed table and column exists under its new name and the old tables are gone
+func (p *ProfileStore) verifyNamingMigration(conn db.DBConn, tableRenames []TableRename) error {
+	problems := []string{}
+	for _, tableRename := range tableRenames {
+		newTableName := p.qualifyStoreTableName(tableRename.NewTableName)
+
+		//error here just means does not exist
+		newTableExists, _ := conn.DoesTableExist(newTableName)
+		if !newTableExists {
+			problems = append(problems, fmt.Sprintf(`table %s does not exist`, newTableName))
+			continue
+		}
+
+		if !isSameStoreName(tableRename.TableName, tableRename.NewTableName) {
+			oldTableExists, _ := conn.DoesTableExist(p.qualifyStoreTableName(tableRename.TableName))
+			if oldTableExists {
+				problems = append(problems, fmt.Sprintf(`table %s still exists`, tableRename.TableName))
+			}
+		}
+
+		columnNames, err := getStoreTableColumnNames(conn, newTableName)
+		if err != nil {
+			return err
+		}
+		existingColumns := map[string]bool{}
+		for _, columnName := range columnNames {
+			existingColumns[strings.ToLower(columnName)] = true
+		}
+		for _, columnRename := range tableRename.ColumnRenames {
+			if !existingColumns[strings.ToLower(columnRename.NewColumnName)] {
+				problems = append(problems, fmt.Sprintf(`column %s.%s does not exist`, tableRename.NewTableName, columnRename.NewColumnName))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf(`naming migration verification failed: %s`, strings.Join(problems, `; `))
+	}
+	return nil
+}
+
+//Returns the column names of a store table
+func getStoreTableColumnNames(conn db.DBConn, tableName string) ([]string, error) {
+	rows, err := conn.GetSelectAllColumnsSingle(tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rows.Columns()
+}
+
+//Store names are created unquoted so the database treats them case insensitively
+func isSameStoreName(name string, otherName string) bool {
+	return strings.EqualFold(name, otherName)
+}
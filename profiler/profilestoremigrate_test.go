@@ -0,0 +1,79 @@
+package profiler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanColumnRenames(t *testing.T) {
+	knownColumns := []string{`id`, `table_name_id`, `row_count`}
+
+	tests := []struct {
+		name            string
+		fromPascalCase  bool
+		toPascalCase    bool
+		existingColumns []string
+		wantRenames     []ColumnRename
+		wantUnmapped    []string
+	}{
+		{
+			name:            "to pascal case",
+			toPascalCase:    true,
+			existingColumns: []string{`id`, `table_name_id`, `row_count`, `null_count`},
+			wantRenames: []ColumnRename{
+				{ColumnName: `table_name_id`, NewColumnName: `TableNameId`},
+				{ColumnName: `row_count`, NewColumnName: `RowCount`},
+				{ColumnName: `null_count`, NewColumnName: `NullCount`},
+			},
+			wantUnmapped: []string{},
+		},
+		{
+			//unquoted pascal case names come back folded to lower case
+			name:            "from pascal case",
+			fromPascalCase:  true,
+			existingColumns: []string{`id`, `tablenameid`, `RowCount`, `nullcount`},
+			wantRenames: []ColumnRename{
+				{ColumnName: `tablenameid`, NewColumnName: `table_name_id`},
+				{ColumnName: `RowCount`, NewColumnName: `row_count`},
+			},
+			wantUnmapped: []string{`table_profiles.nullcount`},
+		},
+		{
+			name:            "same convention",
+			existingColumns: []string{`id`, `table_name_id`, `row_count`},
+			wantUnmapped:    []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewProfileStore(nil)
+			tableRename, unmapped := store.planColumnRenames(`table_profiles`, `TableProfiles`, test.existingColumns, knownColumns, test.fromPascalCase, test.toPascalCase)
+			if tableRename.TableName != `table_profiles` || tableRename.NewTableName != `TableProfiles` {
+				t.Errorf("table rename = %s -> %s", tableRename.TableName, tableRename.NewTableName)
+			}
+			if !reflect.DeepEqual(tableRename.ColumnRenames, test.wantRenames) {
+				t.Errorf("column renames = %+v, want %+v", tableRename.ColumnRenames, test.wantRenames)
+			}
+			if !reflect.DeepEqual(unmapped, test.wantUnmapped) {
+				t.Errorf("unmapped = %v, want %v", unmapped, test.wantUnmapped)
+			}
+		})
+	}
+}
+
+func TestGetUnqualifiedStoreTableName(t *testing.T) {
+	store := NewProfileStore(nil)
+	store.StoreTablePrefix = `prof_`
+	store.StoreSchema = `profiler`
+
+	if name := store.getUnqualifiedStoreTableName(`table_names`, false); name != `prof_table_names` {
+		t.Errorf("snake case name = %s", name)
+	}
+	if name := store.getUnqualifiedStoreTableName(`table_names`, true); name != `ProfTableNames` {
+		t.Errorf("pascal case name = %s", name)
+	}
+	if !isSameStoreName(`ProfTableNames`, `proftablenames`) || isSameStoreName(`prof_table_names`, `ProfTableNames`) {
+		t.Error("store names should only match ignoring case")
+	}
+}
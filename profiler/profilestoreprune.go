@@ -0,0 +1,192 @@
+package profiler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//PruneOptions is the retention policy used when pruning profile runs.
+//A run is removed if it is older than OlderThan or is not one of the newest KeepLatest runs.
+type PruneOptions struct {
+	//Remove runs older than this, 0 disables the age check
+	OlderThan time.Duration
+	//Keep only this many of the newest runs, 0 disables the count check
+	KeepLatest int
+	//Report what would be removed without deleting anything
+	DryRun bool
+}
+
+//PruneResult reports the runs and rows removed by a prune, or that would be removed on a dry run
+type PruneResult struct {
+	DryRun           bool
+	ProfileRecordIDs []int
+	//Rows removed per profile store table
+	TableRowCounts map[string]int
+}
+
+//PruneProfiles removes profile runs matching the retention policy along with all of their
+//table, column and custom column profile data
+func (p *ProfileStore) PruneProfiles(options PruneOptions) (*PruneResult, error) {
+	if options.OlderThan <= 0 && options.KeepLatest <= 0 {
+		return nil, fmt.Errorf(`a prune requires an age or a number of runs to keep`)
+	}
+
+	profileRecordIDs, err := p.getProfileRecordIDsToPrune(options, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{
+		DryRun:           options.DryRun,
+		ProfileRecordIDs: profileRecordIDs,
+		TableRowCounts:   map[string]int{},
+	}
+
+	if len(profileRecordIDs) == 0 {
+		return result, nil
+	}
+
+	values := []interface{}{}
+	for _, id := range profileRecordIDs {
+		values = append(values, id)
+	}
+
+	childTables, err := p.getProfileRecordChildTables()
+	if err != nil {
+		return nil, err
+	}
+
+	profileRecordTable, err := p.getTableNameFromStruct(ProfileRecord{})
+	if err != nil {
+		return nil, err
+	}
+
+	//remove the child rows first then the runs themselves, all in one transaction so a failure
+	//part way through does not leave runs with only some of their profile data
+	pruneRows := func(conn db.DBConn) error {
+		profileRecordColumn := p.handleNamingConvention(PROFILE_RECORD_ID)
+		for _, tableName := range childTables {
+			count, err := pruneTableRows(conn, tableName, profileRecordColumn, values, options.DryRun)
+			if err != nil {
+				return err
+			}
+			result.TableRowCounts[tableName] = count
+		}
+
+		count, err := pruneTableRows(conn, profileRecordTable, `id`, values, options.DryRun)
+		if err != nil {
+			return err
+		}
+		result.TableRowCounts[profileRecordTable] = count
+		return nil
+	}
+
+	if options.DryRun {
+		err = pruneRows(p.dbConn)
+	} else {
+		err = p.dbConn.RunInTransaction(pruneRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func pruneTableRows(conn db.DBConn, tableName string, columnName string, values []interface{}, dryRun bool) (int, error) {
+	if dryRun {
+		return conn.CountRowsWhereIn(tableName, columnName, values)
+	}
+	return conn.DeleteRowsWhereIn(tableName, columnName, values)
+}
+
+//Returns the ids of the runs that fall outside the retention policy
+func (p *ProfileStore) getProfileRecordIDsToPrune(options PruneOptions, now time.Time) ([]int, error) {
+	tableName, err := p.getTableNameFromStruct(ProfileRecord{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.dbConn.GetRowsSelect(tableName, []string{`id`, p.handleNamingConvention(`profile_date`)})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []ProfileRecord{}
+	for rows.Next() {
+		record := ProfileRecord{}
+		err = rows.Scan(&record.ID, &record.ProfileDate)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	//newest first so the index is the run's position for KeepLatest
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ProfileDate.After(records[j].ProfileDate)
+	})
+
+	cutoff := now.Add(-options.OlderThan)
+	profileRecordIDs := []int{}
+	for idx, record := range records {
+		tooOld := options.OlderThan > 0 && record.ProfileDate.Before(cutoff)
+		beyondLatest := options.KeepLatest > 0 && idx >= options.KeepLatest
+		if tooOld || beyondLatest {
+			profileRecordIDs = append(profileRecordIDs, record.ID)
+		}
+	}
+
+	return profileRecordIDs, nil
+}
+
+//Returns every existing store table that references a profile record
+func (p *ProfileStore) getProfileRecordChildTables() ([]string, error) {
+	tableNames := []string{}
+
+	for _, tableStruct := range p.getProfileRecordChildTableStructs() {
+		tableName, err := p.getTableNameFromStruct(tableStruct)
+		if err != nil {
+			return nil, err
+		}
+		//error here just means does not exist
+		tableExists, _ := p.dbConn.DoesTableExist(tableName)
+		if tableExists {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+
+	//the wide layout profile tables are created per type so look them up
+	for _, prefix := range []string{TABLE_COLUMN_PROFILE_PREFIX, TABLE_CUSTOM_COLUMN_PROFILE_PREFIX} {
+		dynamicTables, err := p.dbConn.GetTableNamesWithPrefix(p.StoreSchema, p.getUnqualifiedStoreTableName(prefix, p.UsePascalCase))
+		if err != nil {
+			return nil, err
+		}
+		for _, tableName := range dynamicTables {
+			tableNames = append(tableNames, p.qualifyStoreTableName(tableName))
+		}
+	}
+
+	return tableNames, nil
+}
+
+//Store table structs that carry a profile record id
+func (p *ProfileStore) getProfileRecordChildTableStructs() []interface{} {
+	tableStructs := []interface{}{}
+	for _, tableStruct := range getStoreTableStructs() {
+		for _, columnName := range getStructColumnNames(tableStruct) {
+			if columnName == PROFILE_RECORD_ID {
+				tableStructs = append(tableStructs, tableStruct)
+				break
+			}
+		}
+	}
+	return tableStructs
+}
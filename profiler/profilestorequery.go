@@ -0,0 +1,975 @@
+package profiler
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//ProfileRun is a single run of the profiler
+type ProfileRun struct {
+	ID          int       `json:"ID"`
+	ProfileDate time.Time `json:"ProfileDate"`
+}
+
+//TableProfileResult holds everything recorded for a table in a single run.
+//RowCount is nil when only custom columns were profiled for the table.
+type TableProfileResult struct {
+	TableName       string    `json:"TableName"`
+	ProfileRecordID int       `json:"ProfileRecordID"`
+	ProfileDate     time.Time `json:"ProfileDate"`
+	RowCount        *int      `json:"RowCount,omitempty"`
+	//Table level metrics other than the row count, such as the freshness lag
+	Metrics       map[string]MetricValue                `json:"Metrics,omitempty"`
+	Columns       map[string]*ColumnProfileResult       `json:"Columns"`
+	CustomColumns map[string]*CustomColumnProfileResult `json:"CustomColumns"`
+}
+
+//ColumnProfileResult holds the default profile metrics of a column, keyed by snake case metric name
+type ColumnProfileResult struct {
+	ColumnName string                 `json:"ColumnName"`
+	ColumnType string                 `json:"ColumnType"`
+	Metrics    map[string]MetricValue `json:"Metrics"`
+}
+
+//CustomColumnProfileResult holds the value of a custom column aggregate
+type CustomColumnProfileResult struct {
+	ColumnName       string      `json:"ColumnName"`
+	ColumnType       string      `json:"ColumnType"`
+	ColumnDefinition string      `json:"ColumnDefinition"`
+	Value            MetricValue `json:"Value"`
+}
+
+//TableHistoryEntry is the row count of a table in a single run
+type TableHistoryEntry struct {
+	ProfileRecordID int       `json:"ProfileRecordID"`
+	ProfileDate     time.Time `json:"ProfileDate"`
+	RowCount        int       `json:"RowCount"`
+}
+
+//MetricPoint is the value of a metric in a single run
+type MetricPoint struct {
+	ProfileRecordID int         `json:"ProfileRecordID"`
+	ProfileDate     time.Time   `json:"ProfileDate"`
+	Value           MetricValue `json:"Value"`
+}
+
+//Registered names and runs, loaded up front so results can be joined in memory
+type storeRegistry struct {
+	tableNames    map[int]string
+	tableIDs      map[string]int
+	columns       map[int]TableColumnName
+	customColumns map[int]TableCustomColumnName
+	columnTypes   map[int]string
+	profileDates  map[int]time.Time
+}
+
+//Metric values stored for a single column in a single run
+type storedMetricRow struct {
+	columnNameID    int
+	profileRecordID int
+	metrics         map[string]MetricValue
+}
+
+//ListProfileRuns returns every profile run, oldest first
+func (p *ProfileStore) ListProfileRuns() ([]ProfileRun, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []ProfileRun{}
+	for id, date := range registry.profileDates {
+		runs = append(runs, ProfileRun{
+			ID:          id,
+			ProfileDate: date,
+		})
+	}
+	sortProfileRuns(runs)
+
+	return runs, nil
+}
+
+//GetTableHistory returns the row count of the table for every run since the provided time, oldest first
+func (p *ProfileStore) GetTableHistory(tableName string, since time.Time) ([]TableHistoryEntry, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	tableNameID, err := registry.getTableNameID(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tableProfiles, err := p.readTableProfiles(map[string]interface{}{
+		`table_name_id`: tableNameID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := []TableHistoryEntry{}
+	for _, tableProfile := range tableProfiles {
+		profileDate := registry.profileDates[tableProfile.ProfileRecordID]
+		if profileDate.Before(since) {
+			continue
+		}
+		history = append(history, TableHistoryEntry{
+			ProfileRecordID: tableProfile.ProfileRecordID,
+			ProfileDate:     profileDate,
+			RowCount:        tableProfile.TableRowCount,
+		})
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].ProfileDate.Before(history[j].ProfileDate)
+	})
+
+	return history, nil
+}
+
+//GetColumnMetricSeries returns the value of the metric for the column in every run, oldest first.
+//Custom columns are looked up as well, their single metric is named value.
+func (p *ProfileStore) GetColumnMetricSeries(tableName string, columnName string, metricName string) ([]MetricPoint, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	tableNameID, err := registry.getTableNameID(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	metricRows := []storedMetricRow{}
+
+	//a column is registered again whenever its type changes so there may be more than one
+	for id, column := range registry.columns {
+		if column.TableNameID != tableNameID || !strings.EqualFold(column.TableColumnName, columnName) {
+			continue
+		}
+		rows, err := p.readColumnMetricRows(registry.columnTypes[column.TableColumnTypeID], map[string]interface{}{
+			TABLE_COLUMN_NAME_ID: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		metricRows = append(metricRows, rows...)
+	}
+
+	for id, column := range registry.customColumns {
+		if column.TableNameID != tableNameID || !strings.EqualFold(column.TableColumnName, columnName) {
+			continue
+		}
+		rows, err := p.readCustomColumnMetricRows(registry.columnTypes[column.TableColumnTypeID], map[string]interface{}{
+			TABLE_CUSTOM_COLUMN_NAME_ID: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		metricRows = append(metricRows, rows...)
+	}
+
+	series := []MetricPoint{}
+	for _, row := range metricRows {
+		value, ok := row.metrics[strings.ToLower(metricName)]
+		if !ok {
+			continue
+		}
+		series = append(series, MetricPoint{
+			ProfileRecordID: row.profileRecordID,
+			ProfileDate:     registry.profileDates[row.profileRecordID],
+			Value:           value,
+		})
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].ProfileDate.Before(series[j].ProfileDate)
+	})
+
+	return series, nil
+}
+
+//GetLatestProfile returns everything recorded for the table in the most recent run that profiled it
+func (p *ProfileStore) GetLatestProfile(tableName string) (*TableProfileResult, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	tableNameID, err := registry.getTableNameID(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tableProfiles, err := p.readTableProfiles(map[string]interface{}{
+		`table_name_id`: tableNameID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//tables with only custom columns have no table profile so check those runs too
+	customMetricRows := []storedMetricRow{}
+	for id, column := range registry.customColumns {
+		if column.TableNameID != tableNameID {
+			continue
+		}
+		rows, err := p.readCustomColumnMetricRows(registry.columnTypes[column.TableColumnTypeID], map[string]interface{}{
+			TABLE_CUSTOM_COLUMN_NAME_ID: id,
+		})
+		if err != nil {
+			return nil, err
+		}
+		customMetricRows = append(customMetricRows, rows...)
+	}
+
+	latestProfileID := 0
+	for _, tableProfile := range tableProfiles {
+		latestProfileID = registry.getLaterProfileID(latestProfileID, tableProfile.ProfileRecordID)
+	}
+	for _, row := range customMetricRows {
+		latestProfileID = registry.getLaterProfileID(latestProfileID, row.profileRecordID)
+	}
+	if latestProfileID == 0 {
+		return nil, fmt.Errorf(`no profiles found for table %s`, tableName)
+	}
+
+	metricRows := []storedMetricRow{}
+	for id, column := range registry.columns {
+		if column.TableNameID != tableNameID {
+			continue
+		}
+		rows, err := p.readColumnMetricRows(registry.columnTypes[column.TableColumnTypeID], map[string]interface{}{
+			TABLE_COLUMN_NAME_ID: id,
+			PROFILE_RECORD_ID:    latestProfileID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		metricRows = append(metricRows, rows...)
+	}
+
+	tableMetrics, err := p.readTableMetrics(map[string]interface{}{
+		`table_name_id`:   tableNameID,
+		PROFILE_RECORD_ID: latestProfileID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := registry.buildTableProfileResults(tableProfiles, tableMetrics, metricRows, customMetricRows)
+	return results[latestProfileID][registry.tableNames[tableNameID]], nil
+}
+
+//GetProfileRunResults returns everything recorded in a single run keyed by table name
+func (p *ProfileStore) GetProfileRunResults(profileRecordID int) (map[string]*TableProfileResult, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getProfileRunResults(registry, profileRecordID)
+}
+
+//GetLatestRunResult returns the newest stored run with its table results. Only what the store keeps
+//per table is filled in, the run's duration, check results and anomalies are left empty.
+func (p *ProfileStore) GetLatestRunResult() (*RunResult, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	latestProfileID := 0
+	for profileRecordID := range registry.profileDates {
+		latestProfileID = registry.getLaterProfileID(latestProfileID, profileRecordID)
+	}
+	if latestProfileID == 0 {
+		return nil, fmt.Errorf(`no profile runs found`)
+	}
+
+	tableResults, err := p.getProfileRunResults(registry, latestProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	run := newRunResult(latestProfileID)
+	run.StartTime = registry.profileDates[latestProfileID]
+	run.Tables = tableResults
+	return run, nil
+}
+
+//Same as GetProfileRunResults but with the registry already loaded
+func (p *ProfileStore) getProfileRunResults(registry *storeRegistry, profileRecordID int) (map[string]*TableProfileResult, error) {
+	if _, ok := registry.profileDates[profileRecordID]; !ok {
+		return nil, fmt.Errorf(`profile run %d not found`, profileRecordID)
+	}
+
+	wheres := map[string]interface{}{
+		PROFILE_RECORD_ID: profileRecordID,
+	}
+
+	tableProfiles, err := p.readTableProfiles(wheres)
+	if err != nil {
+		return nil, err
+	}
+
+	tableMetrics, err := p.readTableMetrics(wheres)
+	if err != nil {
+		return nil, err
+	}
+
+	metricRows := []storedMetricRow{}
+	for _, columnType := range p.getMetricTableColumnTypes(registry.getColumnTypesInUse()) {
+		rows, err := p.readColumnMetricRows(columnType, wheres)
+		if err != nil {
+			return nil, err
+		}
+		metricRows = append(metricRows, rows...)
+	}
+
+	customMetricRows := []storedMetricRow{}
+	for _, columnType := range p.getMetricTableColumnTypes(registry.getCustomColumnTypesInUse()) {
+		rows, err := p.readCustomColumnMetricRows(columnType, wheres)
+		if err != nil {
+			return nil, err
+		}
+		customMetricRows = append(customMetricRows, rows...)
+	}
+
+	results := registry.buildTableProfileResults(tableProfiles, tableMetrics, metricRows, customMetricRows)
+	tableResults, ok := results[profileRecordID]
+	if !ok {
+		tableResults = map[string]*TableProfileResult{}
+	}
+	return tableResults, nil
+}
+
+//Reads the registered tables, columns, types and runs
+func (p *ProfileStore) loadStoreRegistry() (*storeRegistry, error) {
+	registry := &storeRegistry{
+		tableNames:    map[int]string{},
+		tableIDs:      map[string]int{},
+		columns:       map[int]TableColumnName{},
+		customColumns: map[int]TableCustomColumnName{},
+		columnTypes:   map[int]string{},
+		profileDates:  map[int]time.Time{},
+	}
+
+	profileDates, err := p.loadProfileDates()
+	if err != nil {
+		return nil, err
+	}
+	registry.profileDates = profileDates
+
+	err = p.readStoreTable(TableName{}, []string{`id`, `table_name`}, func(rows *sql.Rows) error {
+		table := TableName{}
+		err := rows.Scan(&table.ID, &table.TableName)
+		registry.tableNames[table.ID] = table.TableName
+		registry.tableIDs[strings.ToLower(table.TableName)] = table.ID
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.readStoreTable(TableColumnType{}, []string{`id`, `table_column_type`}, func(rows *sql.Rows) error {
+		columnType := TableColumnType{}
+		err := rows.Scan(&columnType.ID, &columnType.TableColumnType)
+		registry.columnTypes[columnType.ID] = columnType.TableColumnType
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.readStoreTable(TableColumnName{}, []string{`id`, `table_name_id`, `table_column_name`, `table_column_type_id`}, func(rows *sql.Rows) error {
+		column := TableColumnName{}
+		err := rows.Scan(&column.ID, &column.TableNameID, &column.TableColumnName, &column.TableColumnTypeID)
+		registry.columns[column.ID] = column
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.readStoreTable(TableCustomColumnName{}, []string{`id`, `table_name_id`, `table_column_name`, `table_column_type_id`, `table_custom_column_definition`}, func(rows *sql.Rows) error {
+		column := TableCustomColumnName{}
+		err := rows.Scan(&column.ID, &column.TableNameID, &column.TableColumnName, &column.TableColumnTypeID, &column.CustomColumnDefinition)
+		registry.customColumns[column.ID] = column
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+//Returns the date of every profile run keyed by its profile record id
+func (p *ProfileStore) loadProfileDates() (map[int]time.Time, error) {
+	profileDates := map[int]time.Time{}
+	err := p.readStoreTable(ProfileRecord{}, []string{`id`, `profile_date`}, func(rows *sql.Rows) error {
+		record := ProfileRecord{}
+		err := rows.Scan(&record.ID, &record.ProfileDate)
+		profileDates[record.ID] = record.ProfileDate
+		return err
+	})
+	return profileDates, err
+}
+
+//Selects the rows matching the snake case where values, []int values match any of the ids
+func (p *ProfileStore) getStoreRowsWhere(tableName string, selects []string, wheres map[string]interface{}) (*sql.Rows, error) {
+	equalWheres := map[string]interface{}{}
+	whereIns := map[string][]int{}
+	for columnName, value := range wheres {
+		if ids, ok := value.([]int); ok {
+			whereIns[p.handleNamingConvention(columnName)] = ids
+			continue
+		}
+		equalWheres[p.handleNamingConvention(columnName)] = value
+	}
+
+	if len(whereIns) == 0 {
+		return p.dbConn.GetRowsSelectWhere(tableName, selects, equalWheres)
+	}
+	return p.dbConn.GetRowsSelectWhereIn(tableName, selects, equalWheres, whereIns)
+}
+
+//Selects the snake case columns from the store table for the struct and hands each row to the scan func
+func (p *ProfileStore) readStoreTable(tableStruct interface{}, columns []string, scan func(rows *sql.Rows) error) error {
+	tableName, err := p.getTableNameFromStruct(tableStruct)
+	if err != nil {
+		return err
+	}
+
+	selects := []string{}
+	for _, columnName := range columns {
+		selects = append(selects, p.handleNamingConvention(columnName))
+	}
+
+	rows, err := p.dbConn.GetRowsSelect(tableName, selects)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+//Reads the table row counts matching the snake case where values
+func (p *ProfileStore) readTableProfiles(wheres map[string]interface{}) ([]TableProfile, error) {
+	tableName, err := p.getTableNameFromStruct(TableProfile{})
+	if err != nil {
+		return nil, err
+	}
+
+	selects := []string{
+		p.handleNamingConvention(`table_name_id`),
+		p.handleNamingConvention(`table_row_count`),
+		p.handleNamingConvention(PROFILE_RECORD_ID),
+	}
+
+	rows, err := p.getStoreRowsWhere(tableName, selects, wheres)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tableProfiles := []TableProfile{}
+	for rows.Next() {
+		tableProfile := TableProfile{}
+		err = rows.Scan(&tableProfile.TableNameID, &tableProfile.TableRowCount, &tableProfile.ProfileRecordID)
+		if err != nil {
+			return nil, err
+		}
+		tableProfiles = append(tableProfiles, tableProfile)
+	}
+
+	return tableProfiles, rows.Err()
+}
+
+//Reads the table level metrics matching the snake case where values
+func (p *ProfileStore) readTableMetrics(wheres map[string]interface{}) ([]TableMetric, error) {
+	tableName, err := p.getTableNameFromStruct(TableMetric{})
+	if err != nil {
+		return nil, err
+	}
+
+	//stores scaffolded before table metrics existed don't have the table until the next profile run
+	tableExists, _ := p.dbConn.DoesTableExist(tableName)
+	if !tableExists {
+		return []TableMetric{}, nil
+	}
+
+	selects := []string{
+		p.handleNamingConvention(`table_name_id`),
+		p.handleNamingConvention(PROFILE_RECORD_ID),
+		p.handleNamingConvention(`metric_name`),
+		p.handleNamingConvention(`metric_value`),
+	}
+
+	rows, err := p.getStoreRowsWhere(tableName, selects, wheres)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tableMetrics := []TableMetric{}
+	for rows.Next() {
+		tableMetric := TableMetric{}
+		err = rows.Scan(&tableMetric.TableNameID, &tableMetric.ProfileRecordID, &tableMetric.MetricName, &tableMetric.MetricValue)
+		if err != nil {
+			return nil, err
+		}
+		tableMetrics = append(tableMetrics, tableMetric)
+	}
+
+	return tableMetrics, rows.Err()
+}
+
+//Reads the default column profile metrics matching the snake case where values
+//for the wide layout only the profile table for the column type is read
+func (p *ProfileStore) readColumnMetricRows(columnType string, wheres map[string]interface{}) ([]storedMetricRow, error) {
+	if p.isLongLayout() {
+		tableName, err := p.getTableNameFromStruct(TableColumnMetric{})
+		if err != nil {
+			return nil, err
+		}
+		return p.readLongMetricRows(tableName, TABLE_COLUMN_NAME_ID, wheres)
+	}
+
+	return p.readWideMetricRows(p.getColumnProfileTableName(columnType), columnType, TABLE_COLUMN_NAME_ID, wheres)
+}
+
+//Reads the custom column values matching the snake case where values
+func (p *ProfileStore) readCustomColumnMetricRows(columnType string, wheres map[string]interface{}) ([]storedMetricRow, error) {
+	if p.isLongLayout() {
+		tableName, err := p.getTableNameFromStruct(TableCustomColumnMetric{})
+		if err != nil {
+			return nil, err
+		}
+		return p.readLongMetricRows(tableName, TABLE_CUSTOM_COLUMN_NAME_ID, wheres)
+	}
+
+	return p.readWideMetricRows(p.getCustomColumnProfileTableName(columnType), columnType, TABLE_CUSTOM_COLUMN_NAME_ID, wheres)
+}
+
+//Reads a wide layout profile table where every non id column is a metric
+func (p *ProfileStore) readWideMetricRows(tableName string, columnType string, columnNameIDField string, wheres map[string]interface{}) ([]storedMetricRow, error) {
+	//wide tables are only created once a column of that type is profiled
+	tableExists, _ := p.dbConn.DoesTableExist(tableName)
+	if !tableExists {
+		return []storedMetricRow{}, nil
+	}
+
+	rows, err := p.getStoreRowsWhere(tableName, []string{`*`}, wheres)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	metricNames := p.getMetricNamesByStoredName(columnType)
+
+	metricRows := []storedMetricRow{}
+	for rows.Next() {
+		values := make([]interface{}, len(columnNames))
+		valuePointers := make([]interface{}, len(columnNames))
+		for idx := range values {
+			valuePointers[idx] = &values[idx]
+		}
+		err = rows.Scan(valuePointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := storedMetricRow{
+			metrics: map[string]MetricValue{},
+		}
+		for idx, columnName := range columnNames {
+			switch {
+			case strings.EqualFold(columnName, `id`):
+			case strings.EqualFold(columnName, p.handleNamingConvention(columnNameIDField)):
+				row.columnNameID = int(toFloat64(values[idx]))
+			case strings.EqualFold(columnName, p.handleNamingConvention(PROFILE_RECORD_ID)):
+				row.profileRecordID = int(toFloat64(values[idx]))
+			default:
+				metricName, ok := metricNames[strings.ToLower(columnName)]
+				if !ok {
+					metricName = strings.ToLower(columnName)
+				}
+				row.metrics[metricName] = NewMetricValue(values[idx])
+			}
+		}
+		metricRows = append(metricRows, row)
+	}
+
+	return metricRows, rows.Err()
+}
+
+//Reads a long layout metrics table, grouping the metric rows by column and run
+func (p *ProfileStore) readLongMetricRows(tableName string, columnNameIDField string, wheres map[string]interface{}) ([]storedMetricRow, error) {
+	selects := []string{
+		p.handleNamingConvention(columnNameIDField),
+		p.handleNamingConvention(PROFILE_RECORD_ID),
+		p.handleNamingConvention(METRIC_NAME),
+		p.handleNamingConvention(NUMERIC_VALUE),
+		p.handleNamingConvention(TEXT_VALUE),
+		p.handleNamingConvention(TIMESTAMP_VALUE),
+	}
+
+	rows, err := p.getStoreRowsWhere(tableName, selects, wheres)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rowsByKey := map[string]*storedMetricRow{}
+	keys := []string{}
+	for rows.Next() {
+		var columnNameID, profileRecordID int
+		var metricName string
+		var numericValue, textValue, timestampValue interface{}
+		err = rows.Scan(&columnNameID, &profileRecordID, &metricName, &numericValue, &textValue, &timestampValue)
+		if err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf(`%d:%d`, columnNameID, profileRecordID)
+		row, ok := rowsByKey[key]
+		if !ok {
+			row = &storedMetricRow{
+				columnNameID:    columnNameID,
+				profileRecordID: profileRecordID,
+				metrics:         map[string]MetricValue{},
+			}
+			rowsByKey[key] = row
+			keys = append(keys, key)
+		}
+
+		value := MetricValue{}
+		switch {
+		case numericValue != nil:
+			value = MetricValue{
+				Kind:   METRIC_KIND_NUMERIC,
+				Number: toFloat64(numericValue),
+			}
+		case textValue != nil:
+			value = NewMetricValue(fmt.Sprint(textValue))
+		case timestampValue != nil:
+			value = NewMetricValue(timestampValue)
+		}
+		row.metrics[metricName] = value
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	metricRows := []storedMetricRow{}
+	for _, key := range keys {
+		metricRows = append(metricRows, *rowsByKey[key])
+	}
+
+	return metricRows, nil
+}
+
+//Returns the column types that each need their own metrics table read,
+//the long layout keeps every type in a single table
+func (p *ProfileStore) getMetricTableColumnTypes(columnTypes []string) []string {
+	if p.isLongLayout() && len(columnTypes) > 0 {
+		return columnTypes[:1]
+	}
+	return columnTypes
+}
+
+//Maps the stored column names of the default and quality profiles for the type back to their snake case metric names
+func (p *ProfileStore) getMetricNamesByStoredName(columnType string) map[string]string {
+	metricNames := map[string]string{}
+	for _, profiles := range []map[string]string{p.dbConn.ProfilesByType(columnType), p.dbConn.QualityProfilesByType(columnType)} {
+		for metricName := range profiles {
+			metricNames[strings.ToLower(p.handleNamingConvention(metricName))] = metricName
+		}
+	}
+	return metricNames
+}
+
+//Joins the stored rows up with the registry, keyed by profile record id and then table name
+func (r *storeRegistry) buildTableProfileResults(tableProfiles []TableProfile, tableMetrics []TableMetric, metricRows []storedMetricRow, customMetricRows []storedMetricRow) map[int]map[string]*TableProfileResult {
+	results := map[int]map[string]*TableProfileResult{}
+
+	getResult := func(profileRecordID int, tableNameID int) *TableProfileResult {
+		tableName := r.tableNames[tableNameID]
+		if _, ok := results[profileRecordID]; !ok {
+			results[profileRecordID] = map[string]*TableProfileResult{}
+		}
+		result, ok := results[profileRecordID][tableName]
+		if !ok {
+			result = &TableProfileResult{
+				TableName:       tableName,
+				ProfileRecordID: profileRecordID,
+				ProfileDate:     r.profileDates[profileRecordID],
+				Columns:         map[string]*ColumnProfileResult{},
+				CustomColumns:   map[string]*CustomColumnProfileResult{},
+			}
+			results[profileRecordID][tableName] = result
+		}
+		return result
+	}
+
+	for _, tableProfile := range tableProfiles {
+		rowCount := tableProfile.TableRowCount
+		getResult(tableProfile.ProfileRecordID, tableProfile.TableNameID).RowCount = &rowCount
+	}
+
+	for _, tableMetric := range tableMetrics {
+		result := getResult(tableMetric.ProfileRecordID, tableMetric.TableNameID)
+		if result.Metrics == nil {
+			result.Metrics = map[string]MetricValue{}
+		}
+		result.Metrics[tableMetric.MetricName] = NewMetricValue(tableMetric.MetricValue)
+	}
+
+	for _, row := range metricRows {
+		column, ok := r.columns[row.columnNameID]
+		if !ok {
+			continue
+		}
+		getResult(row.profileRecordID, column.TableNameID).Columns[column.TableColumnName] = &ColumnProfileResult{
+			ColumnName: column.TableColumnName,
+			ColumnType: r.columnTypes[column.TableColumnTypeID],
+			Metrics:    row.metrics,
+		}
+	}
+
+	for _, row := range customMetricRows {
+		column, ok := r.customColumns[row.columnNameID]
+		if !ok {
+			continue
+		}
+		getResult(row.profileRecordID, column.TableNameID).CustomColumns[column.TableColumnName] = &CustomColumnProfileResult{
+			ColumnName:       column.TableColumnName,
+			ColumnType:       r.columnTypes[column.TableColumnTypeID],
+			ColumnDefinition: column.CustomColumnDefinition,
+			Value:            row.metrics[CUSTOM_COLUMN_METRIC_NAME],
+		}
+	}
+
+	return results
+}
+
+//Returns the ids of the columns registered for the tables
+func (r *storeRegistry) getColumnIDs(tableNameIDs map[int]bool) []int {
+	columnIDs := map[int]bool{}
+	for id, column := range r.columns {
+		if tableNameIDs[column.TableNameID] {
+			columnIDs[id] = true
+		}
+	}
+	return getSortedIDs(columnIDs)
+}
+
+//Returns the ids of the custom columns registered for the tables
+func (r *storeRegistry) getCustomColumnIDs(tableNameIDs map[int]bool) []int {
+	columnIDs := map[int]bool{}
+	for id, column := range r.customColumns {
+		if tableNameIDs[column.TableNameID] {
+			columnIDs[id] = true
+		}
+	}
+	return getSortedIDs(columnIDs)
+}
+
+//Returns the ids of a set in sorted order
+func getSortedIDs(ids map[int]bool) []int {
+	sortedIDs := []int{}
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Ints(sortedIDs)
+	return sortedIDs
+}
+
+func (r *storeRegistry) getTableNameID(tableName string) (int, error) {
+	id, ok := r.tableIDs[strings.ToLower(tableName)]
+	if !ok {
+		return 0, fmt.Errorf(`table %s has not been profiled`, tableName)
+	}
+	return id, nil
+}
+
+//Returns whichever of the two runs is more recent, a zero id is treated as no run.
+//Runs with the same date are ordered by record id so the result does not depend on map order.
+func (r *storeRegistry) getLaterProfileID(profileRecordID int, otherProfileRecordID int) int {
+	if profileRecordID == 0 || isProfileRunBefore(
+		ProfileRun{ID: profileRecordID, ProfileDate: r.profileDates[profileRecordID]},
+		ProfileRun{ID: otherProfileRecordID, ProfileDate: r.profileDates[otherProfileRecordID]},
+	) {
+		return otherProfileRecordID
+	}
+	return profileRecordID
+}
+
+//Returns the distinct column types of the registered default profile columns
+func (r *storeRegistry) getColumnTypesInUse() []string {
+	typeIDs := map[int]bool{}
+	for _, column := range r.columns {
+		typeIDs[column.TableColumnTypeID] = true
+	}
+	return r.getColumnTypeNames(typeIDs)
+}
+
+//Returns the distinct column types of the registered custom columns
+func (r *storeRegistry) getCustomColumnTypesInUse() []string {
+	typeIDs := map[int]bool{}
+	for _, column := range r.customColumns {
+		typeIDs[column.TableColumnTypeID] = true
+	}
+	return r.getColumnTypeNames(typeIDs)
+}
+
+func (r *storeRegistry) getColumnTypeNames(typeIDs map[int]bool) []string {
+	columnTypes := []string{}
+	for typeID := range typeIDs {
+		columnTypes = append(columnTypes, r.columnTypes[typeID])
+	}
+	sort.Strings(columnTypes)
+	return columnTypes
+}
+
+//Sorts runs oldest first
+func sortProfileRuns(runs []ProfileRun) {
+	sort.Slice(runs, func(i, j int) bool {
+		return isProfileRunBefore(runs[i], runs[j])
+	})
+}
+
+//Orders runs by date, then by record id for runs with the same date
+func isProfileRunBefore(run ProfileRun, other ProfileRun) bool {
+	if !run.ProfileDate.Equal(other.ProfileDate) {
+		return run.ProfileDate.Before(other.ProfileDate)
+	}
+	return run.ID < other.ID
+}
+
+//Returns the results of up to count runs before the provided run, newest first
+func (p *ProfileStore) getRecentProfileRunResults(beforeProfileRecordID int, count int) ([]map[string]*TableProfileResult, error) {
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := []ProfileRun{}
+	for id, date := range registry.profileDates {
+		if id != beforeProfileRecordID && !date.After(registry.profileDates[beforeProfileRecordID]) {
+			runs = append(runs, ProfileRun{
+				ID:          id,
+				ProfileDate: date,
+			})
+		}
+	}
+	sortProfileRuns(runs)
+
+	results := []map[string]*TableProfileResult{}
+	for idx := len(runs) - 1; idx >= 0 && len(results) < count; idx-- {
+		runResults, err := p.getProfileRunResults(registry, runs[idx].ID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, runResults)
+	}
+
+	return results, nil
+}
+
+//Returns the column snapshot of the table from the latest run before the profile record, going by
+//profile date, along with the id of that run, the id is 0 when the table has no earlier snapshot
+func (p *ProfileStore) getPreviousTableSchema(tableNameID int, beforeProfileRecordID int) ([]db.DBTableColumn, int, error) {
+	tableName, err := p.getTableNameFromStruct(TableColumnSnapshot{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	profileDates, err := p.loadProfileDates()
+	if err != nil {
+		return nil, 0, err
+	}
+	beforeDate, ok := profileDates[beforeProfileRecordID]
+	if !ok {
+		return nil, 0, fmt.Errorf(`profile record %d not found`, beforeProfileRecordID)
+	}
+
+	selects := []string{
+		p.handleNamingConvention(PROFILE_RECORD_ID),
+		p.handleNamingConvention(`column_name`),
+		p.handleNamingConvention(`column_type`),
+		p.handleNamingConvention(`is_nullable`),
+		p.handleNamingConvention(`ordinal_position`),
+	}
+	wheres := p.handleColumnDataNamingConvention(map[string]interface{}{
+		`table_name_id`: tableNameID,
+	})
+
+	rows, err := p.dbConn.GetRowsSelectWhere(tableName, selects, wheres)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	columnsByProfileRecordID := map[int][]db.DBTableColumn{}
+	for rows.Next() {
+		var profileRecordID int
+		column := db.DBTableColumn{}
+		err = rows.Scan(&profileRecordID, &column.ColumnName, &column.DataType, &column.IsNullable, &column.OrdinalPosition)
+		if err != nil {
+			return nil, 0, err
+		}
+		columnsByProfileRecordID[profileRecordID] = append(columnsByProfileRecordID[profileRecordID], column)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	//newest snapshot dated before the run, runs with the same date fall back to the id
+	previousProfileRecordID := 0
+	for profileRecordID := range columnsByProfileRecordID {
+		date, ok := profileDates[profileRecordID]
+		if !ok || profileRecordID == beforeProfileRecordID || isLaterRun(date, profileRecordID, beforeDate, beforeProfileRecordID) {
+			continue
+		}
+		if previousProfileRecordID == 0 || isLaterRun(date, profileRecordID, profileDates[previousProfileRecordID], previousProfileRecordID) {
+			previousProfileRecordID = profileRecordID
+		}
+	}
+
+	columns := columnsByProfileRecordID[previousProfileRecordID]
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+	})
+
+	return columns, previousProfileRecordID, nil
+}
+
+//Orders runs by profile date, then by id for runs with the same date
+func isLaterRun(date time.Time, profileRecordID int, otherDate time.Time, otherProfileRecordID int) bool {
+	if date.Equal(otherDate) {
+		return profileRecordID > otherProfileRecordID
+	}
+	return date.After(otherDate)
+}
@@ -1,6 +1,44 @@
 package profiler
 
-import "time"
+import (
+	"reflect"
+	"time"
+)
+
+//Returns every statically defined profile store table struct
+func getStoreTableStructs() []interface{} {
+	return []interface{}{
+		ProfileRecord{},
+		TableName{},
+		TableProfile{},
+		TableMetric{},
+		TableColumnName{},
+		TableCustomColumnName{},
+		TableColumnType{},
+		TableColumnMetric{},
+		TableCustomColumnMetric{},
+		ProfileAnomaly{},
+		ExpectationResultRecord{},
+		TableColumnSnapshot{},
+		SchemaChangeRecord{},
+		TableFreshness{},
+		CandidateKeyRecord{},
+		TableRelationshipProfile{},
+	}
+}
+
+//Returns the snake case db tag of every field on the table struct
+func getStructColumnNames(tableStruct interface{}) []string {
+	columnNames := []string{}
+	fields := reflect.TypeOf(tableStruct)
+	for i := 0; i < fields.NumField(); i++ {
+		columnName, hasColumnName := fields.Field(i).Tag.Lookup(`db`)
+		if hasColumnName {
+			columnNames = append(columnNames, columnName)
+		}
+	}
+	return columnNames
+}
 
 type TableColumnName struct {
 	ID                int    `db:"id" table:"table_column_names" primaryKey:"true"`
@@ -26,6 +64,15 @@ type TableProfile struct {
 	ProfileRecordID int `db:"profile_record_id"`
 }
 
+//Table level metric other than the row count, such as the freshness lag
+type TableMetric struct {
+	ID              int     `db:"id" table:"table_metrics" primaryKey:"true"`
+	ProfileRecordID int     `db:"profile_record_id"`
+	TableNameID     int     `db:"table_name_id"`
+	MetricName      string  `db:"metric_name"`
+	MetricValue     float64 `db:"metric_value"`
+}
+
 type ProfileRecord struct {
 	ID          int       `db:"id" table:"profile_records" primaryKey:"true"`
 	ProfileDate time.Time `db:"profile_date"`
@@ -38,3 +85,110 @@ type TableCustomColumnName struct {
 	TableColumnTypeID      int    `db:"table_column_type_id"`
 	CustomColumnDefinition string `db:"table_custom_column_definition"`
 }
+
+//Used by the long store layout, one row per metric per column profile
+type TableColumnMetric struct {
+	ID                int       `db:"id" table:"table_column_metrics" primaryKey:"true"`
+	TableColumnNameID int       `db:"table_column_name_id"`
+	ProfileRecordID   int       `db:"profile_record_id"`
+	MetricName        string    `db:"metric_name"`
+	NumericValue      []byte    `db:"numeric_value"`
+	TextValue         string    `db:"text_value"`
+	TimestampValue    time.Time `db:"timestamp_value"`
+}
+
+//Used by the long store layout, one row per custom column profile
+type TableCustomColumnMetric struct {
+	ID                      int       `db:"id" table:"table_custom_column_metrics" primaryKey:"true"`
+	TableCustomColumnNameID int       `db:"table_column_name_id"`
+	ProfileRecordID         int       `db:"profile_record_id"`
+	MetricName              string    `db:"metric_name"`
+	NumericValue            []byte    `db:"numeric_value"`
+	TextValue               string    `db:"text_value"`
+	TimestampValue          time.Time `db:"timestamp_value"`
+}
+
+//An outlier found by anomaly detection, the column name is empty for table level metrics such as row count
+type ProfileAnomaly struct {
+	ID              int      `db:"id" table:"profile_anomalies" primaryKey:"true"`
+	ProfileRecordID int      `db:"profile_record_id"`
+	TableNameID     int      `db:"table_name_id"`
+	ColumnName      string   `db:"column_name"`
+	MetricName      string   `db:"metric_name"`
+	MetricValue     float64  `db:"metric_value"`
+	ExpectedValue   float64  `db:"expected_value"`
+	Spread          float64  `db:"spread"`
+	Score           *float64 `db:"score"`
+	Method          string   `db:"method"`
+	HistorySize     int      `db:"history_size"`
+}
+
+//Pass or fail of a single expectation in a run
+type ExpectationResultRecord struct {
+	ID              int    `db:"id" table:"expectation_results" primaryKey:"true"`
+	ProfileRecordID int    `db:"profile_record_id"`
+	TableNameID     int    `db:"table_name_id"`
+	Expectation     string `db:"expectation"`
+	Passed          bool   `db:"passed"`
+	ObservedValue   string `db:"observed_value"`
+	Message         string `db:"message"`
+}
+
+//Catalog definition of a profiled table column as seen by a run
+type TableColumnSnapshot struct {
+	ID              int    `db:"id" table:"table_column_snapshots" primaryKey:"true"`
+	ProfileRecordID int    `db:"profile_record_id"`
+	TableNameID     int    `db:"table_name_id"`
+	ColumnName      string `db:"column_name"`
+	ColumnType      string `db:"column_type"`
+	IsNullable      bool   `db:"is_nullable"`
+	OrdinalPosition int    `db:"ordinal_position"`
+}
+
+//A column change found by comparing a table's snapshot with the one from its previous run
+type SchemaChangeRecord struct {
+	ID                      int    `db:"id" table:"schema_changes" primaryKey:"true"`
+	ProfileRecordID         int    `db:"profile_record_id"`
+	PreviousProfileRecordID int    `db:"previous_profile_record_id"`
+	TableNameID             int    `db:"table_name_id"`
+	ChangeType              string `db:"change_type"`
+	ColumnName              string `db:"column_name"`
+	PreviousColumnName      string `db:"previous_column_name"`
+	ColumnType              string `db:"column_type"`
+	PreviousColumnType      string `db:"previous_column_type"`
+}
+
+//Lag of the newest row of a table behind the run time, in seconds
+type TableFreshness struct {
+	ID                  int        `db:"id" table:"table_freshness" primaryKey:"true"`
+	ProfileRecordID     int        `db:"profile_record_id"`
+	TableNameID         int        `db:"table_name_id"`
+	ColumnName          string     `db:"column_name"`
+	NewestValue         *time.Time `db:"newest_value"`
+	LagSeconds          *float64   `db:"lag_seconds"`
+	MaxStalenessSeconds *float64   `db:"max_staleness_seconds"`
+	IsStale             bool       `db:"is_stale"`
+}
+
+//Columns found to uniquely identify the rows of a table, the column names are comma separated.
+//Discovery is not a profile run so the keys carry their own timestamp.
+type CandidateKeyRecord struct {
+	ID           int       `db:"id" table:"candidate_keys" primaryKey:"true"`
+	DiscoveredAt time.Time `db:"discovered_at"`
+	TableNameID  int       `db:"table_name_id"`
+	ColumnNames  string    `db:"column_names"`
+	ColumnCount  int       `db:"column_count"`
+}
+
+//Orphan row count of a foreign key on a table, the column names are comma separated
+type TableRelationshipProfile struct {
+	ID                    int    `db:"id" table:"table_relationship_profiles" primaryKey:"true"`
+	ProfileRecordID       int    `db:"profile_record_id"`
+	TableNameID           int    `db:"table_name_id"`
+	RelationshipName      string `db:"relationship_name"`
+	ColumnNames           string `db:"column_names"`
+	ReferencedTableName   string `db:"referenced_table_name"`
+	ReferencedColumnNames string `db:"referenced_column_names"`
+	IsDeclared            bool   `db:"is_declared"`
+	OrphanCount           int    `db:"orphan_count"`
+}
@@ -0,0 +1,238 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//Prometheus metric names
+const PROMETHEUS_TABLE_ROW_COUNT = `profiler_table_row_count`
+const PROMETHEUS_TABLE_METRIC = `profiler_table_metric`
+const PROMETHEUS_COLUMN_METRIC = `profiler_column_metric`
+const PROMETHEUS_TABLE_FRESHNESS_LAG = `profiler_table_freshness_lag_seconds`
+const PROMETHEUS_EXPECTATION_PASSED = `profiler_expectation_passed`
+const PROMETHEUS_RELATIONSHIP_ORPHANS = `profiler_relationship_orphan_count`
+const PROMETHEUS_ANOMALY_COUNT = `profiler_table_anomaly_count`
+const PROMETHEUS_RUN_DURATION = `profiler_last_run_duration_seconds`
+const PROMETHEUS_RUN_SUCCESS = `profiler_last_run_success`
+const PROMETHEUS_RUN_TIMESTAMP = `profiler_last_run_timestamp_seconds`
+
+//A single sample of a gauge
+type prometheusSample struct {
+	labels [][2]string
+	value  float64
+}
+
+//A gauge and its samples, written in the Prometheus text exposition format
+type prometheusGauge struct {
+	name    string
+	help    string
+	samples []prometheusSample
+}
+
+func (g *prometheusGauge) add(value float64, labels ...[2]string) {
+	g.samples = append(g.samples, prometheusSample{
+		labels: labels,
+		value:  value,
+	})
+}
+
+func label(name string, value string) [2]string {
+	return [2]string{name, value}
+}
+
+//Returns the run's results as gauges, a failed run without results only reports its failure.
+//Timestamp metrics are reported as unix seconds, text metrics are left out.
+func getPrometheusGauges(run *RunResult, runErr error) []*prometheusGauge {
+	rowCounts := &prometheusGauge{name: PROMETHEUS_TABLE_ROW_COUNT, help: `Row count of the table in the latest run.`}
+	tableMetrics := &prometheusGauge{name: PROMETHEUS_TABLE_METRIC, help: `Table level metric other than the row count in the latest run, such as the freshness lag.`}
+	columnMetrics := &prometheusGauge{name: PROMETHEUS_COLUMN_METRIC, help: `Profile metric of the column in the latest run, custom columns use the metric value.`}
+	freshness := &prometheusGauge{name: PROMETHEUS_TABLE_FRESHNESS_LAG, help: `Seconds between the newest row of the table and the latest run.`}
+	expectations := &prometheusGauge{name: PROMETHEUS_EXPECTATION_PASSED, help: `1 if the expectation passed in the latest run, 0 if it failed.`}
+	orphans := &prometheusGauge{name: PROMETHEUS_RELATIONSHIP_ORPHANS, help: `Rows with no matching row in the referenced table in the latest run.`}
+	anomalies := &prometheusGauge{name: PROMETHEUS_ANOMALY_COUNT, help: `Anomalies found for the table in the latest run.`}
+	duration := &prometheusGauge{name: PROMETHEUS_RUN_DURATION, help: `Seconds taken by the latest run.`}
+	success := &prometheusGauge{name: PROMETHEUS_RUN_SUCCESS, help: `1 if the latest run finished without error, 0 otherwise.`}
+	timestamp := &prometheusGauge{name: PROMETHEUS_RUN_TIMESTAMP, help: `Unix time the latest run started.`}
+
+	gauges := []*prometheusGauge{rowCounts, tableMetrics, columnMetrics, freshness, expectations, orphans, anomalies, duration, success, timestamp}
+	if runErr != nil {
+		success.add(0)
+	}
+	if run == nil {
+		return gauges
+	}
+
+	tableNames := map[string]bool{}
+	for tableName := range run.Tables {
+		tableNames[tableName] = true
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		result := run.Tables[tableName]
+		if result.RowCount != nil {
+			rowCounts.add(float64(*result.RowCount), label(`table`, tableName))
+		}
+
+		tableMetricNames := map[string]bool{}
+		for metricName := range result.Metrics {
+			tableMetricNames[metricName] = true
+		}
+		for _, metricName := range getSortedKeys(tableMetricNames) {
+			value, ok := getPrometheusValue(result.Metrics[metricName])
+			if ok {
+				tableMetrics.add(value, label(`table`, tableName), label(`metric`, metricName))
+			}
+		}
+
+		columns := getResultMetrics(result)
+		for _, column := range getSortedResultColumnKeys(columns) {
+			metricNames := map[string]bool{}
+			for metricName := range columns[column] {
+				metricNames[metricName] = true
+			}
+			for _, metricName := range getSortedKeys(metricNames) {
+				value, ok := getPrometheusValue(columns[column][metricName])
+				if ok {
+					columnMetrics.add(value, label(`table`, tableName), label(`column`, column.columnName), label(`metric`, metricName))
+				}
+			}
+		}
+	}
+
+	for _, result := range run.Freshness {
+		if result.LagSeconds != nil {
+			freshness.add(*result.LagSeconds, label(`table`, result.TableName), label(`column`, result.ColumnName))
+		}
+	}
+
+	for _, result := range run.ExpectationResults {
+		passed := 0.0
+		if result.Passed {
+			passed = 1
+		}
+		expectations.add(passed, label(`table`, result.TableName), label(`expectation`, result.Expectation))
+	}
+
+	for _, relationship := range run.Relationships {
+		orphans.add(float64(relationship.OrphanCount),
+			label(`table`, relationship.TableName),
+			label(`columns`, strings.Join(relationship.Columns, `,`)),
+			label(`referenced_table`, relationship.ReferencedTable),
+		)
+	}
+
+	anomalyCounts := map[string]int{}
+	for tableName := range run.Tables {
+		anomalyCounts[tableName] = 0
+	}
+	for _, anomaly := range run.Anomalies {
+		anomalyCounts[anomaly.TableName]++
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		anomalies.add(float64(anomalyCounts[tableName]), label(`table`, tableName))
+	}
+
+	if !run.EndTime.IsZero() {
+		duration.add(run.DurationSeconds)
+	}
+	if runErr == nil {
+		success.add(1)
+	}
+	timestamp.add(float64(run.StartTime.UnixNano()) / 1e9)
+
+	return gauges
+}
+
+//Numeric values as is and timestamps as unix seconds
+func getPrometheusValue(value MetricValue) (float64, bool) {
+	switch value.Kind {
+	case METRIC_KIND_NUMERIC:
+		return value.Number, true
+	case METRIC_KIND_TIMESTAMP:
+		return float64(value.Time.UnixNano()) / 1e9, true
+	}
+	return 0, false
+}
+
+//WritePrometheusMetrics writes the run's results in the Prometheus text exposition format.
+//The run error is reported through profiler_last_run_success.
+func WritePrometheusMetrics(w io.Writer, run *RunResult, runErr error) error {
+	b := &strings.Builder{}
+	for _, gauge := range getPrometheusGauges(run, runErr) {
+		if len(gauge.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name)
+		for _, sample := range gauge.samples {
+			b.WriteString(gauge.name)
+			if len(sample.labels) > 0 {
+				labels := []string{}
+				for _, pair := range sample.labels {
+					labels = append(labels, fmt.Sprintf(`%s="%s"`, pair[0], escapePrometheusLabel(pair[1])))
+				}
+				fmt.Fprintf(b, "{%s}", strings.Join(labels, `,`))
+			}
+			fmt.Fprintf(b, " %s\n", formatPrometheusValue(sample.value))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapePrometheusLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+func formatPrometheusValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return `+Inf`
+	case math.IsInf(value, -1):
+		return `-Inf`
+	case math.IsNaN(value):
+		return `NaN`
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+//PrometheusExporter serves the results of the latest run on /metrics.
+//Call Update after every run, the last results are kept when a run fails without any.
+type PrometheusExporter struct {
+	run    *RunResult
+	runErr error
+	mux    sync.RWMutex
+}
+
+func NewPrometheusExporter() *PrometheusExporter {
+	return &PrometheusExporter{}
+}
+
+//Update replaces the served results with the run's, a failed run without results keeps
+//the previous results so only profiler_last_run_success changes
+func (e *PrometheusExporter) Update(run *RunResult, runErr error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.runErr = runErr
+	if run != nil {
+		e.run = run
+	}
+}
+
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+
+	w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+	err := WritePrometheusMetrics(w, e.run, e.runErr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
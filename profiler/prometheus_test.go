@@ -0,0 +1,183 @@
+package profiler
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapePrometheusLabel(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`orders`, `orders`},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\data`, `C:\\data`},
+		{"two\nlines", `two\nlines`},
+		{`\"`, `\\\"`},
+		{`a,b=c d{e}`, `a,b=c d{e}`},
+	}
+
+	for _, test := range tests {
+		if got := escapePrometheusLabel(test.value); got != test.want {
+			t.Errorf("escape %q = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFormatPrometheusValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, `0`},
+		{42, `42`},
+		{-1.5, `-1.5`},
+		{1e21, `1e+21`},
+		{math.Inf(1), `+Inf`},
+		{math.Inf(-1), `-Inf`},
+		{math.NaN(), `NaN`},
+	}
+
+	for _, test := range tests {
+		if got := formatPrometheusValue(test.value); got != test.want {
+			t.Errorf("format %v = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func newPrometheusTestRun() *RunResult {
+	startTime := time.Unix(1714564800, 0)
+	orders := newTestTableResult(`orders`, 3, map[string]map[string]MetricValue{
+		`total`: {
+			`maximum`: NewMetricValue(9.5),
+			`mode`:    NewMetricValue(`text is left out`),
+		},
+		`created_at`: {`maximum`: NewMetricValue(startTime)},
+	}, nil)
+	orders.Metrics = map[string]MetricValue{FRESHNESS_LAG_METRIC_NAME: NewMetricValue(30)}
+
+	return &RunResult{
+		StartTime:       startTime,
+		EndTime:         startTime.Add(2 * time.Second),
+		DurationSeconds: 2,
+		Tables:          map[string]*TableProfileResult{`orders`: orders},
+		ExpectationResults: []ExpectationResult{
+			{TableName: `orders`, Expectation: `maximum(status) == "shipped"`, Passed: false},
+		},
+	}
+}
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         *RunResult
+		runErr      error
+		wantLines   []string
+		unwantLines []string
+	}{
+		{
+			name: "successful run",
+			run:  newPrometheusTestRun(),
+			wantLines: []string{
+				`# TYPE profiler_table_row_count gauge`,
+				`profiler_table_row_count{table="orders"} 3`,
+				`profiler_table_metric{table="orders",metric="freshness_lag_seconds"} 30`,
+				`profiler_column_metric{table="orders",column="total",metric="maximum"} 9.5`,
+				`profiler_column_metric{table="orders",column="created_at",metric="maximum"} 1.7145648e+09`,
+				`profiler_expectation_passed{table="orders",expectation="maximum(status) == \"shipped\""} 0`,
+				`profiler_table_anomaly_count{table="orders"} 0`,
+				`profiler_last_run_duration_seconds 2`,
+				`profiler_last_run_success 1`,
+				`profiler_last_run_timestamp_seconds 1.7145648e+09`,
+			},
+			unwantLines: []string{
+				`metric="mode"`,
+				`# TYPE profiler_relationship_orphan_count gauge`,
+			},
+		},
+		{
+			name:   "failed run with results",
+			run:    newPrometheusTestRun(),
+			runErr: errors.New(`boom`),
+			wantLines: []string{
+				`profiler_table_row_count{table="orders"} 3`,
+				`profiler_last_run_success 0`,
+			},
+			unwantLines: []string{
+				`profiler_last_run_success 1`,
+			},
+		},
+		{
+			name:   "failed run without results",
+			runErr: errors.New(`boom`),
+			wantLines: []string{
+				`# HELP profiler_last_run_success 1 if the latest run finished without error, 0 otherwise.`,
+				`profiler_last_run_success 0`,
+			},
+			unwantLines: []string{
+				`profiler_table_row_count`,
+				`profiler_last_run_timestamp_seconds`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			err := WritePrometheusMetrics(&buffer, test.run, test.runErr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(buffer.String(), "\n")
+			for _, want := range test.wantLines {
+				if !containsLine(lines, want) {
+					t.Errorf("output is missing %s:\n%s", want, buffer.String())
+				}
+			}
+			for _, unwant := range test.unwantLines {
+				for _, line := range lines {
+					//help text is free form so only samples and type lines are checked
+					if !strings.HasPrefix(line, `# HELP`) && strings.Contains(line, unwant) {
+						t.Errorf("output should not contain %s:\n%s", unwant, buffer.String())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPrometheusExporterKeepsResultsOnFailure(t *testing.T) {
+	exporter := NewPrometheusExporter()
+	exporter.Update(newPrometheusTestRun(), nil)
+	exporter.Update(nil, errors.New(`boom`))
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest(`GET`, `/metrics`, nil))
+
+	body := recorder.Body.String()
+	if !strings.HasPrefix(recorder.Header().Get(`Content-Type`), `text/plain; version=0.0.4`) {
+		t.Errorf("content type = %s", recorder.Header().Get(`Content-Type`))
+	}
+	if !strings.Contains(body, `profiler_table_row_count{table="orders"} 3`) {
+		t.Errorf("previous results were dropped:\n%s", body)
+	}
+	if !strings.Contains(body, "profiler_last_run_success 0\n") {
+		t.Errorf("failure was not reported:\n%s", body)
+	}
+}
+
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package profiler
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Prefix of the table metric holding the orphan count of a relationship, followed by the relationship name
+const ORPHAN_COUNT_METRIC_PREFIX = `orphan_count.`
+
+//RelationshipResult is the orphan count of a foreign key, declared in the database or listed in the definition
+type RelationshipResult struct {
+	TableName         string   `json:"TableName"`
+	Name              string   `json:"Name"`
+	Columns           []string `json:"Columns"`
+	ReferencedTable   string   `json:"ReferencedTable"`
+	ReferencedColumns []string `json:"ReferencedColumns"`
+	IsDeclared        bool     `json:"IsDeclared"`
+	OrphanCount       int      `json:"OrphanCount"`
+}
+
+//Checks the relationships in the definition so mismatched columns are caught before profiling
+func validateRelationships(profile ProfileDefinition) error {
+	for _, table := range profile.CustomProfileTables {
+		for _, relationship := range table.Relationships {
+			if relationship.ReferencedTable == `` || len(relationship.Columns) == 0 || len(relationship.Columns) != len(relationship.ReferencedColumns) {
+				return fmt.Errorf(`invalid relationship on table %s, a referenced table and the same number of columns on each side are required`, table.TableName)
+			}
+		}
+	}
+	return nil
+}
+
+//Returns every table in the profile once, with the relationships listed for it
+func getRelationshipTables(profile ProfileDefinition) []TableDefinition {
+	tables := []TableDefinition{}
+	tableIndexes := map[string]int{}
+	addTable := func(tableName string, relationships []RelationshipDefinition) {
+		idx, ok := tableIndexes[tableName]
+		if !ok {
+			tableIndexes[tableName] = len(tables)
+			tables = append(tables, TableDefinition{
+				TableName: tableName,
+			})
+			idx = len(tables) - 1
+		}
+		tables[idx].Relationships = append(tables[idx].Relationships, relationships...)
+	}
+
+	for _, tableName := range profile.FullProfileTables {
+		addTable(tableName, nil)
+	}
+	for _, table := range profile.CustomProfileTables {
+		addTable(table.TableName, table.Relationships)
+	}
+	return tables
+}
+
+//Counts the orphan rows of every declared and defined relationship of the tables,
+//adds the results to the run and records them in the store
+func (p *Profiler) checkRelationships(run *RunResult, tables []TableDefinition) error {
+	for _, table := range tables {
+		relationships, err := p.getTableRelationships(table)
+		if err != nil {
+			return err
+		}
+
+		for _, relationship := range relationships {
+			orphanCount, err := p.targetDBConn.CountOrphanRows(table.TableName, relationship.Columns, relationship.ReferencedTable, relationship.ReferencedColumns)
+			if err != nil {
+				return err
+			}
+			relationship.OrphanCount = orphanCount
+			run.setTableMetric(table.TableName, relationship.getMetricName(), float64(orphanCount))
+
+			if p.hasProfileStore() {
+				err = p.profileStore.RecordRelationshipProfile(run.ProfileRecordID, relationship)
+				if err != nil {
+					return err
+				}
+				err = p.profileStore.RecordTableMetric(table.TableName, run.ProfileRecordID, relationship.getMetricName(), float64(orphanCount))
+				if err != nil {
+					return err
+				}
+			}
+			run.Relationships = append(run.Relationships, relationship)
+		}
+	}
+	return nil
+}
+
+//Returns the foreign keys from the catalog followed by the defined relationships that aren't already declared
+func (p *Profiler) getTableRelationships(table TableDefinition) ([]RelationshipResult, error) {
+	foreignKeys, err := p.targetDBConn.GetForeignKeys(table.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	relationships := []RelationshipResult{}
+	declared := map[string]bool{}
+	for _, foreignKey := range foreignKeys {
+		relationships = append(relationships, RelationshipResult{
+			TableName:         table.TableName,
+			Name:              foreignKey.ConstraintName,
+			Columns:           foreignKey.ColumnNames,
+			ReferencedTable:   foreignKey.ReferencedTableName,
+			ReferencedColumns: foreignKey.ReferencedColumnNames,
+			IsDeclared:        true,
+		})
+		declared[getRelationshipKey(foreignKey.ColumnNames, foreignKey.ReferencedTableName, foreignKey.ReferencedColumnNames)] = true
+	}
+
+	for _, definition := range table.Relationships {
+		//the catalog names are quoted when counting so look up what the defined names resolve to
+		columns, err := p.getDatabaseColumnNames(table.TableName, definition.Columns)
+		if err != nil {
+			return nil, err
+		}
+		referencedColumns, err := p.getDatabaseColumnNames(definition.ReferencedTable, definition.ReferencedColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		if declared[getRelationshipKey(columns, definition.ReferencedTable, referencedColumns)] {
+			continue
+		}
+
+		name := definition.Name
+		if name == `` {
+			//same as the name postgres gives an unnamed foreign key
+			name = fmt.Sprintf(`%s_%s_fkey`, table.TableName, strings.Join(columns, `_`))
+		}
+		relationships = append(relationships, RelationshipResult{
+			TableName:         table.TableName,
+			Name:              name,
+			Columns:           columns,
+			ReferencedTable:   definition.ReferencedTable,
+			ReferencedColumns: referencedColumns,
+		})
+	}
+
+	return relationships, nil
+}
+
+//Returns the names the database gives the columns, the same way columns listed in a table definition are resolved
+func (p *Profiler) getDatabaseColumnNames(tableName string, columns []string) ([]string, error) {
+	rows, err := p.targetDBConn.GetSelectSingle(tableName, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return rows.Columns()
+}
+
+//Name of the table metric holding the relationship's orphan count
+func (r RelationshipResult) getMetricName() string {
+	return ORPHAN_COUNT_METRIC_PREFIX + r.Name
+}
+
+//Identifies a relationship by its columns, case insensitively since unquoted names fold to lower case.
+//The catalog qualifies and quotes referenced table names as needed so the bare table name is used.
+func getRelationshipKey(columns []string, referencedTable string, referencedColumns []string) string {
+	return strings.ToLower(fmt.Sprintf(`%s>%s(%s)`, strings.Join(columns, `,`), getBareTableName(referencedTable), strings.Join(referencedColumns, `,`)))
+}
+
+//Returns the table name without its schema or quotes, dots inside quotes are part of the name
+func getBareTableName(tableName string) string {
+	inQuotes := false
+	start := 0
+	for idx, char := range tableName {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == '.' && !inQuotes:
+			start = idx + 1
+		}
+	}
+	bareName := tableName[start:]
+	if len(bareName) >= 2 && strings.HasPrefix(bareName, `"`) && strings.HasSuffix(bareName, `"`) {
+		bareName = strings.Replace(bareName[1:len(bareName)-1], `""`, `"`, -1)
+	}
+	return bareName
+}
@@ -0,0 +1,94 @@
+package profiler
+
+import (
+	"testing"
+)
+
+func TestGetRelationshipKey(t *testing.T) {
+	definedKey := getRelationshipKey([]string{`customer_id`}, `customers`, []string{`id`})
+
+	tests := []struct {
+		name            string
+		referencedTable string
+		want            bool
+	}{
+		{name: "same name", referencedTable: `customers`, want: true},
+		{name: "schema qualified", referencedTable: `sales.customers`, want: true},
+		{name: "quoted", referencedTable: `"Customers"`, want: true},
+		{name: "schema qualified and quoted", referencedTable: `"Sales"."Customers"`, want: true},
+		{name: "dot inside quotes", referencedTable: `sales."old.customers"`, want: false},
+		{name: "other table", referencedTable: `sales.customer_archive`, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			declaredKey := getRelationshipKey([]string{`customer_id`}, test.referencedTable, []string{`id`})
+			if (declaredKey == definedKey) != test.want {
+				t.Errorf("%s matches %s = %v, want %v", declaredKey, definedKey, declaredKey == definedKey, test.want)
+			}
+		})
+	}
+}
+
+func TestGetBareTableName(t *testing.T) {
+	tests := []struct {
+		tableName string
+		want      string
+	}{
+		{tableName: `customers`, want: `customers`},
+		{tableName: `sales.customers`, want: `customers`},
+		{tableName: `"Sales"."Customers"`, want: `Customers`},
+		{tableName: `sales."old.customers"`, want: `old.customers`},
+		{tableName: `"say ""hi"""`, want: `say "hi"`},
+	}
+
+	for _, test := range tests {
+		if got := getBareTableName(test.tableName); got != test.want {
+			t.Errorf("bare name of %s = %s, want %s", test.tableName, got, test.want)
+		}
+	}
+}
+
+func TestValidateRelationships(t *testing.T) {
+	tests := []struct {
+		name         string
+		relationship RelationshipDefinition
+		wantErr      bool
+	}{
+		{name: "valid", relationship: RelationshipDefinition{Columns: []string{`customer_id`}, ReferencedTable: `customers`, ReferencedColumns: []string{`id`}}},
+		{name: "no referenced table", relationship: RelationshipDefinition{Columns: []string{`customer_id`}, ReferencedColumns: []string{`id`}}, wantErr: true},
+		{name: "no columns", relationship: RelationshipDefinition{ReferencedTable: `customers`}, wantErr: true},
+		{name: "column counts differ", relationship: RelationshipDefinition{Columns: []string{`customer_id`, `region`}, ReferencedTable: `customers`, ReferencedColumns: []string{`id`}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile := ProfileDefinition{
+				CustomProfileTables: []TableDefinition{{TableName: `orders`, Relationships: []RelationshipDefinition{test.relationship}}},
+			}
+			err := validateRelationships(profile)
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want an error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetRelationshipTables(t *testing.T) {
+	relationship := RelationshipDefinition{Columns: []string{`customer_id`}, ReferencedTable: `customers`, ReferencedColumns: []string{`id`}}
+	profile := ProfileDefinition{
+		FullProfileTables: []string{`orders`, `customers`},
+		CustomProfileTables: []TableDefinition{
+			{TableName: `orders`, Relationships: []RelationshipDefinition{relationship}},
+			{TableName: `order_lines`},
+		},
+	}
+
+	tables := getRelationshipTables(profile)
+	if len(tables) != 3 || tables[0].TableName != `orders` || tables[1].TableName != `customers` || tables[2].TableName != `order_lines` {
+		t.Fatalf("tables = %+v", tables)
+	}
+	if len(tables[0].Relationships) != 1 || len(tables[1].Relationships) != 0 {
+		t.Errorf("relationships = %+v", tables)
+	}
+}
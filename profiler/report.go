@@ -0,0 +1,313 @@
+package profiler
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Default number of runs shown in a report
+const DEFAULT_REPORT_MAX_RUNS = 30
+
+//Column metrics summarised in a report when they were gathered
+const NULL_COUNT_METRIC_NAME = `null_count`
+const DISTINCT_COUNT_METRIC_NAME = `distinct_count`
+
+//ReportOptions selects the runs and tables in a report, zero values include everything
+//up to DEFAULT_REPORT_MAX_RUNS runs
+type ReportOptions struct {
+	Since   time.Time
+	MaxRuns int
+	Tables  []string
+}
+
+//Report is the profile history of the selected tables, ready to render
+type Report struct {
+	GeneratedAt time.Time     `json:"GeneratedAt"`
+	Runs        []ProfileRun  `json:"Runs"`
+	Tables      []TableReport `json:"Tables"`
+}
+
+//TableReport is the history of a table over the report's runs along with its latest values
+type TableReport struct {
+	TableName         string        `json:"TableName"`
+	LatestProfileDate time.Time     `json:"LatestProfileDate"`
+	LatestRowCount    *int          `json:"LatestRowCount"`
+	RowCounts         []ReportPoint `json:"RowCounts"`
+	//History of the table level metrics other than the row count, keyed by metric name
+	MetricSeries map[string][]ReportPoint `json:"MetricSeries"`
+	Columns      []ColumnReport           `json:"Columns"`
+}
+
+//ColumnReport is the history of a default or custom column, the series only hold numeric metrics.
+//NullRatio is nil when the null count or row count was not gathered.
+type ColumnReport struct {
+	ColumnName    string                   `json:"ColumnName"`
+	ColumnType    string                   `json:"ColumnType"`
+	IsCustom      bool                     `json:"IsCustom"`
+	Latest        map[string]MetricValue   `json:"Latest"`
+	Series        map[string][]ReportPoint `json:"Series"`
+	NullCount     *float64                 `json:"NullCount"`
+	NullRatio     *float64                 `json:"NullRatio"`
+	DistinctCount *float64                 `json:"DistinctCount"`
+}
+
+//ReportPoint is a numeric value from a single run
+type ReportPoint struct {
+	ProfileDate time.Time `json:"ProfileDate"`
+	Value       float64   `json:"Value"`
+}
+
+//BuildReport reads the profile history for a report
+func (p *ProfileStore) BuildReport(options ReportOptions) (*Report, error) {
+	if options.MaxRuns <= 0 {
+		options.MaxRuns = DEFAULT_REPORT_MAX_RUNS
+	}
+
+	registry, err := p.loadStoreRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	//newest runs within the range, oldest first
+	runs := []ProfileRun{}
+	for id, date := range registry.profileDates {
+		if !date.Before(options.Since) {
+			runs = append(runs, ProfileRun{
+				ID:          id,
+				ProfileDate: date,
+			})
+		}
+	}
+	sortProfileRuns(runs)
+	if len(runs) > options.MaxRuns {
+		runs = runs[len(runs)-options.MaxRuns:]
+	}
+
+	includeTable := map[string]bool{}
+	for _, tableName := range options.Tables {
+		includeTable[tableName] = true
+	}
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Runs:        runs,
+		Tables:      []TableReport{},
+	}
+	tableReports := map[string]*TableReport{}
+	for _, run := range runs {
+		runResults, err := p.getProfileRunResults(registry, run.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for tableName, result := range runResults {
+			if len(includeTable) > 0 && !includeTable[tableName] {
+				continue
+			}
+			tableReport, ok := tableReports[tableName]
+			if !ok {
+				tableReport = &TableReport{
+					TableName:    tableName,
+					RowCounts:    []ReportPoint{},
+					MetricSeries: map[string][]ReportPoint{},
+				}
+				tableReports[tableName] = tableReport
+			}
+			tableReport.addResult(result)
+		}
+	}
+
+	tableNames := map[string]bool{}
+	for tableName := range tableReports {
+		tableNames[tableName] = true
+	}
+	for _, tableName := range getSortedKeys(tableNames) {
+		tableReport := tableReports[tableName]
+		tableReport.summarise()
+		report.Tables = append(report.Tables, *tableReport)
+	}
+
+	return report, nil
+}
+
+//Adds a run's results to the table history, results must be added oldest first
+func (t *TableReport) addResult(result *TableProfileResult) {
+	t.LatestProfileDate = result.ProfileDate
+	t.LatestRowCount = result.RowCount
+	if result.RowCount != nil {
+		t.RowCounts = append(t.RowCounts, ReportPoint{
+			ProfileDate: result.ProfileDate,
+			Value:       float64(*result.RowCount),
+		})
+	}
+
+	for metricName, value := range result.Metrics {
+		if value.IsNumeric() {
+			t.MetricSeries[metricName] = append(t.MetricSeries[metricName], ReportPoint{
+				ProfileDate: result.ProfileDate,
+				Value:       value.Number,
+			})
+		}
+	}
+
+	for column, metrics := range getResultMetrics(result) {
+		columnReport := t.getColumnReport(column.columnName, column.isCustom)
+		columnReport.Latest = metrics
+		for metricName, value := range metrics {
+			if !value.IsNumeric() {
+				continue
+			}
+			columnReport.Series[metricName] = append(columnReport.Series[metricName], ReportPoint{
+				ProfileDate: result.ProfileDate,
+				Value:       value.Number,
+			})
+		}
+	}
+	for columnName, column := range result.Columns {
+		t.getColumnReport(columnName, false).ColumnType = column.ColumnType
+	}
+	for columnName, column := range result.CustomColumns {
+		t.getColumnReport(columnName, true).ColumnType = column.ColumnType
+	}
+}
+
+func (t *TableReport) getColumnReport(columnName string, isCustom bool) *ColumnReport {
+	for idx := range t.Columns {
+		if t.Columns[idx].ColumnName == columnName && t.Columns[idx].IsCustom == isCustom {
+			return &t.Columns[idx]
+		}
+	}
+	t.Columns = append(t.Columns, ColumnReport{
+		ColumnName: columnName,
+		IsCustom:   isCustom,
+		Latest:     map[string]MetricValue{},
+		Series:     map[string][]ReportPoint{},
+	})
+	return &t.Columns[len(t.Columns)-1]
+}
+
+//Works out the null and distinct summaries from the latest values and sorts the columns
+func (t *TableReport) summarise() {
+	for idx := range t.Columns {
+		column := &t.Columns[idx]
+		if nullCount, ok := column.Latest[NULL_COUNT_METRIC_NAME]; ok && nullCount.IsNumeric() {
+			column.NullCount = &nullCount.Number
+			if t.LatestRowCount != nil && *t.LatestRowCount > 0 {
+				nullRatio := nullCount.Number / float64(*t.LatestRowCount)
+				column.NullRatio = &nullRatio
+			}
+		}
+		if distinctCount, ok := column.Latest[DISTINCT_COUNT_METRIC_NAME]; ok && distinctCount.IsNumeric() {
+			column.DistinctCount = &distinctCount.Number
+		}
+	}
+
+	sort.SliceStable(t.Columns, func(i, j int) bool {
+		if t.Columns[i].IsCustom != t.Columns[j].IsCustom {
+			return !t.Columns[i].IsCustom
+		}
+		return t.Columns[i].ColumnName < t.Columns[j].ColumnName
+	})
+}
+
+//Returns the date of the oldest run in the report
+func (r *Report) FirstRunDate() time.Time {
+	if len(r.Runs) == 0 {
+		return time.Time{}
+	}
+	return r.Runs[0].ProfileDate
+}
+
+//Returns the date of the newest run in the report
+func (r *Report) LastRunDate() time.Time {
+	if len(r.Runs) == 0 {
+		return time.Time{}
+	}
+	return r.Runs[len(r.Runs)-1].ProfileDate
+}
+
+//Returns the names of the table level metrics in sorted order
+func (t TableReport) MetricNames() []string {
+	metricNames := map[string]bool{}
+	for metricName := range t.MetricSeries {
+		metricNames[metricName] = true
+	}
+	return getSortedKeys(metricNames)
+}
+
+//Returns the metric names of the column in sorted order
+func (c ColumnReport) MetricNames() []string {
+	metricNames := map[string]bool{}
+	for metricName := range c.Latest {
+		metricNames[metricName] = true
+	}
+	return getSortedKeys(metricNames)
+}
+
+//WriteHTML renders the report as a single HTML page with no external assets
+func (r *Report) WriteHTML(w io.Writer) error {
+	tmpl, err := template.New(`report`).Funcs(template.FuncMap{
+		`sparkline`:     renderSparkline,
+		`formatDate`:    formatReportDate,
+		`formatInt`:     formatOptionalInt,
+		`formatFloat`:   formatOptionalFloat,
+		`formatPercent`: formatReportPercent,
+	}).Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, r)
+}
+
+func formatReportDate(date time.Time) string {
+	if date.IsZero() {
+		return `-`
+	}
+	return date.Format(`2006-01-02 15:04`)
+}
+
+func formatReportPercent(value *float64) string {
+	if value == nil {
+		return `-`
+	}
+	return fmt.Sprintf(`%.2f%%`, *value*100)
+}
+
+//Renders the points as an inline svg line, points are spaced evenly rather than by date
+func renderSparkline(points []ReportPoint, width int, height int) template.HTML {
+	if len(points) == 0 {
+		return template.HTML(``)
+	}
+
+	minimum, maximum := points[0].Value, points[0].Value
+	for _, point := range points {
+		minimum = math.Min(minimum, point.Value)
+		maximum = math.Max(maximum, point.Value)
+	}
+
+	padding := 2.0
+	xStep := 0.0
+	if len(points) > 1 {
+		xStep = (float64(width) - 2*padding) / float64(len(points)-1)
+	}
+	coordinates := []string{}
+	for idx, point := range points {
+		y := float64(height) / 2
+		if maximum > minimum {
+			y = padding + (maximum-point.Value)/(maximum-minimum)*(float64(height)-2*padding)
+		}
+		coordinates = append(coordinates, fmt.Sprintf(`%.1f,%.1f`, padding+float64(idx)*xStep, y))
+	}
+
+	last := coordinates[len(coordinates)-1]
+	lastXY := strings.Split(last, `,`)
+	return template.HTML(fmt.Sprintf(
+		`<svg class="spark" width="%d" height="%d" viewBox="0 0 %d %d"><polyline fill="none" stroke="#2b6cb0" stroke-width="1.5" points="%s"/><circle cx="%s" cy="%s" r="2" fill="#2b6cb0"/><title>min %g, max %g</title></svg>`,
+		width, height, width, height, strings.Join(coordinates, ` `), lastXY[0], lastXY[1], minimum, maximum,
+	))
+}
@@ -0,0 +1,204 @@
+package profiler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTableReportSummarise(t *testing.T) {
+	firstDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	secondDate := firstDate.Add(24 * time.Hour)
+	firstRowCount := 100
+	secondRowCount := 200
+	emptyRowCount := 0
+
+	tests := []struct {
+		name              string
+		rowCount          *int
+		metrics           map[string]MetricValue
+		wantNullCount     *float64
+		wantNullRatio     *float64
+		wantDistinctCount *float64
+	}{
+		{
+			name:     "quality profiles gathered",
+			rowCount: &secondRowCount,
+			metrics: map[string]MetricValue{
+				NULL_COUNT_METRIC_NAME:     NewMetricValue(50),
+				DISTINCT_COUNT_METRIC_NAME: NewMetricValue(120),
+			},
+			wantNullCount:     floatPointer(50),
+			wantNullRatio:     floatPointer(0.25),
+			wantDistinctCount: floatPointer(120),
+		},
+		{
+			name:     "quality profiles not gathered",
+			rowCount: &secondRowCount,
+			metrics:  map[string]MetricValue{`maximum`: NewMetricValue(9)},
+		},
+		{
+			name:          "empty table has no null ratio",
+			rowCount:      &emptyRowCount,
+			metrics:       map[string]MetricValue{NULL_COUNT_METRIC_NAME: NewMetricValue(0)},
+			wantNullCount: floatPointer(0),
+		},
+		{
+			name:          "no row count has no null ratio",
+			metrics:       map[string]MetricValue{NULL_COUNT_METRIC_NAME: NewMetricValue(3)},
+			wantNullCount: floatPointer(3),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := &TableReport{TableName: `orders`, RowCounts: []ReportPoint{}, MetricSeries: map[string][]ReportPoint{}}
+
+			//the summaries come from the latest run only
+			report.addResult(&TableProfileResult{
+				ProfileDate: firstDate,
+				RowCount:    &firstRowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`email`: {ColumnType: `TEXT`, Metrics: map[string]MetricValue{
+						NULL_COUNT_METRIC_NAME:     NewMetricValue(1),
+						DISTINCT_COUNT_METRIC_NAME: NewMetricValue(1),
+					}},
+				},
+			})
+			report.addResult(&TableProfileResult{
+				ProfileDate: secondDate,
+				RowCount:    test.rowCount,
+				Columns: map[string]*ColumnProfileResult{
+					`email`: {ColumnType: `TEXT`, Metrics: test.metrics},
+				},
+			})
+			report.summarise()
+
+			if len(report.Columns) != 1 {
+				t.Fatalf("columns = %+v, want only email", report.Columns)
+			}
+			column := report.Columns[0]
+			if !equalFloatPointers(column.NullCount, test.wantNullCount) {
+				t.Errorf("null count = %s, want %s", formatOptionalFloat(column.NullCount), formatOptionalFloat(test.wantNullCount))
+			}
+			if !equalFloatPointers(column.NullRatio, test.wantNullRatio) {
+				t.Errorf("null ratio = %s, want %s", formatOptionalFloat(column.NullRatio), formatOptionalFloat(test.wantNullRatio))
+			}
+			if !equalFloatPointers(column.DistinctCount, test.wantDistinctCount) {
+				t.Errorf("distinct count = %s, want %s", formatOptionalFloat(column.DistinctCount), formatOptionalFloat(test.wantDistinctCount))
+			}
+		})
+	}
+}
+
+func TestTableReportAddResult(t *testing.T) {
+	firstDate := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	secondDate := firstDate.Add(24 * time.Hour)
+	firstRowCount := 10
+	secondRowCount := 12
+
+	report := &TableReport{TableName: `orders`, RowCounts: []ReportPoint{}, MetricSeries: map[string][]ReportPoint{}}
+	report.addResult(&TableProfileResult{
+		ProfileDate: firstDate,
+		RowCount:    &firstRowCount,
+		Metrics:     map[string]MetricValue{FRESHNESS_LAG_METRIC_NAME: NewMetricValue(60)},
+		Columns: map[string]*ColumnProfileResult{
+			`total`: {ColumnType: `NUMERIC`, Metrics: map[string]MetricValue{`maximum`: NewMetricValue(5)}},
+		},
+		CustomColumns: map[string]*CustomColumnProfileResult{
+			`long_notes`: {ColumnType: `INT8`, Value: NewMetricValue(1)},
+		},
+	})
+	report.addResult(&TableProfileResult{
+		ProfileDate: secondDate,
+		RowCount:    &secondRowCount,
+		Metrics:     map[string]MetricValue{FRESHNESS_LAG_METRIC_NAME: NewMetricValue(30)},
+		Columns: map[string]*ColumnProfileResult{
+			`total`: {ColumnType: `NUMERIC`, Metrics: map[string]MetricValue{`maximum`: NewMetricValue(8), `mode`: NewMetricValue(`text`)}},
+		},
+		CustomColumns: map[string]*CustomColumnProfileResult{
+			`long_notes`: {ColumnType: `INT8`, Value: NewMetricValue(2)},
+		},
+	})
+	report.summarise()
+
+	if !report.LatestProfileDate.Equal(secondDate) || *report.LatestRowCount != 12 {
+		t.Errorf("latest = %v with %d rows", report.LatestProfileDate, *report.LatestRowCount)
+	}
+	if len(report.RowCounts) != 2 || report.RowCounts[0].Value != 10 || report.RowCounts[1].Value != 12 {
+		t.Errorf("row counts = %+v", report.RowCounts)
+	}
+	if lags := report.MetricSeries[FRESHNESS_LAG_METRIC_NAME]; len(lags) != 2 || lags[1].Value != 30 {
+		t.Errorf("freshness lag series = %+v", lags)
+	}
+
+	//default columns are listed before custom ones
+	if len(report.Columns) != 2 || report.Columns[0].ColumnName != `total` || !report.Columns[1].IsCustom {
+		t.Fatalf("columns = %+v", report.Columns)
+	}
+	total := report.Columns[0]
+	if total.ColumnType != `NUMERIC` || len(total.Series[`maximum`]) != 2 {
+		t.Errorf("total = %+v", total)
+	}
+	if _, ok := total.Series[`mode`]; ok {
+		t.Error("text metrics should not have a series")
+	}
+	if strings.Join(total.MetricNames(), `,`) != `maximum,mode` {
+		t.Errorf("metric names = %v", total.MetricNames())
+	}
+}
+
+func TestReportWriteHTML(t *testing.T) {
+	rowCount := 200
+	report := &TableReport{TableName: `orders<script>`, RowCounts: []ReportPoint{}, MetricSeries: map[string][]ReportPoint{}}
+	report.addResult(&TableProfileResult{
+		ProfileDate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		RowCount:    &rowCount,
+		Columns: map[string]*ColumnProfileResult{
+			`email`: {ColumnType: `TEXT`, Metrics: map[string]MetricValue{
+				NULL_COUNT_METRIC_NAME:     NewMetricValue(50),
+				DISTINCT_COUNT_METRIC_NAME: NewMetricValue(120),
+			}},
+			`notes`: {ColumnType: `TEXT`, Metrics: map[string]MetricValue{}},
+		},
+	})
+	report.summarise()
+
+	var buffer bytes.Buffer
+	err := (&Report{Tables: []TableReport{*report}}).WriteHTML(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := buffer.String()
+	for _, want := range []string{`<td class="num">50</td>`, `<td class="num">25.00%</td>`, `<td class="num">120</td>`, `<td class="num">-</td>`, `orders&lt;script&gt;`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("report is missing %s", want)
+		}
+	}
+	if strings.Contains(output, `orders<script>`) {
+		t.Error("table names are not escaped")
+	}
+}
+
+func TestNeedsQualityProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile ProfileDefinition
+		want    bool
+	}{
+		{name: "plain profile", profile: ProfileDefinition{FullProfileTables: []string{`orders`}}, want: false},
+		{name: "quality profiles", profile: ProfileDefinition{QualityProfiles: true}, want: true},
+		{name: "anomaly detection", profile: ProfileDefinition{AnomalyDetection: &AnomalyDetectionDefinition{}}, want: true},
+		{name: "expectations", profile: ProfileDefinition{CustomProfileTables: []TableDefinition{{TableName: `orders`, Expectations: []string{`row_count > 0`}}}}, want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := needsQualityProfiles(test.profile); got != test.want {
+				t.Errorf("needs quality profiles = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
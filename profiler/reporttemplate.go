@@ -0,0 +1,58 @@
+package profiler
+
+//HTML template for WriteHTML, styles are inline so the file stands alone
+const reportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Profile Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1a202c; }
+h1 { margin-bottom: 0.2em; }
+h2 { margin-top: 2em; border-bottom: 1px solid #e2e8f0; padding-bottom: 0.3em; }
+.meta { color: #718096; }
+table { border-collapse: collapse; margin-top: 0.5em; }
+th, td { text-align: left; padding: 0.3em 0.8em; border-bottom: 1px solid #edf2f7; vertical-align: middle; }
+th { background: #f7fafc; font-weight: 600; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.metrics { font-size: 0.9em; }
+.metrics span { display: inline-block; margin-right: 1em; white-space: nowrap; }
+.custom { color: #805ad5; font-size: 0.8em; }
+.spark { vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>Profile Report</h1>
+<p class="meta">Generated {{formatDate .GeneratedAt}} from {{len .Runs}} runs{{if .Runs}}, {{formatDate .FirstRunDate}} to {{formatDate .LastRunDate}}{{end}}</p>
+
+<table>
+<tr><th>Table</th><th>Last Profiled</th><th>Rows</th><th>Row Count Trend</th></tr>
+{{range .Tables}}<tr>
+<td><a href="#table-{{.TableName}}">{{.TableName}}</a></td>
+<td>{{formatDate .LatestProfileDate}}</td>
+<td class="num">{{formatInt .LatestRowCount}}</td>
+<td>{{sparkline .RowCounts 160 28}}</td>
+</tr>
+{{end}}</table>
+
+{{range .Tables}}
+<h2 id="table-{{.TableName}}">{{.TableName}}</h2>
+<p class="meta">Last profiled {{formatDate .LatestProfileDate}}, {{formatInt .LatestRowCount}} rows</p>
+{{if .RowCounts}}<p>{{sparkline .RowCounts 480 60}}</p>{{end}}
+{{if .MetricSeries}}{{$table := .}}<p class="metrics">{{range .MetricNames}}{{$metric := .}}{{with index $table.MetricSeries $metric}}<span>{{$metric}} {{sparkline . 160 28}}</span>{{end}}{{end}}</p>{{end}}
+<table>
+<tr><th>Column</th><th>Type</th><th>Nulls</th><th>Null %</th><th>Distinct</th><th>Latest Values</th><th>Trends</th></tr>
+{{range .Columns}}{{$column := .}}<tr>
+<td>{{.ColumnName}}{{if .IsCustom}} <span class="custom">custom</span>{{end}}</td>
+<td>{{.ColumnType}}</td>
+<td class="num">{{formatFloat .NullCount}}</td>
+<td class="num">{{formatPercent .NullRatio}}</td>
+<td class="num">{{formatFloat .DistinctCount}}</td>
+<td class="metrics">{{range .MetricNames}}<span>{{.}}: {{(index $column.Latest .).String}}</span>{{end}}</td>
+<td class="metrics">{{range .MetricNames}}{{$metric := .}}{{with index $column.Series $metric}}<span>{{$metric}} {{sparkline . 90 20}}</span>{{end}}{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,150 @@
+package profiler
+
+import (
+	"sync"
+	"time"
+)
+
+//RunResult holds everything gathered during a single profile run, keyed by table name.
+//It is filled in as tables are profiled so it is safe for concurrent use.
+type RunResult struct {
+	ProfileRecordID    int                            `json:"ProfileRecordID"`
+	StartTime          time.Time                      `json:"StartTime"`
+	EndTime            time.Time                      `json:"EndTime"`
+	Tables             map[string]*TableProfileResult `json:"Tables"`
+	Anomalies          []Anomaly                      `json:"Anomalies"`
+	ExpectationResults []ExpectationResult            `json:"ExpectationResults"`
+	SchemaChanges      []SchemaChange                 `json:"SchemaChanges"`
+	Freshness          []FreshnessResult              `json:"Freshness"`
+	Relationships      []RelationshipResult           `json:"Relationships"`
+	DurationSeconds    float64                        `json:"DurationSeconds"`
+	//Time spent profiling each table, a table profiled both fully and with custom columns has both summed
+	TableDurationSeconds map[string]float64 `json:"TableDurationSeconds"`
+	schemaCaptured       map[string]bool
+	//gather the quality profiles, such as null_count, along with the default column profiles
+	gatherQualityProfiles bool
+	mux                   sync.Mutex
+}
+
+func newRunResult(profileRecordID int) *RunResult {
+	return &RunResult{
+		ProfileRecordID:      profileRecordID,
+		StartTime:            time.Now(),
+		Tables:               map[string]*TableProfileResult{},
+		Anomalies:            []Anomaly{},
+		ExpectationResults:   []ExpectationResult{},
+		SchemaChanges:        []SchemaChange{},
+		Freshness:            []FreshnessResult{},
+		Relationships:        []RelationshipResult{},
+		TableDurationSeconds: map[string]float64{},
+		schemaCaptured:       map[string]bool{},
+	}
+}
+
+//Returns the expectations that did not pass
+func (r *RunResult) FailedExpectations() []ExpectationResult {
+	failed := []ExpectationResult{}
+	for _, result := range r.ExpectationResults {
+		if !result.Passed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+//Returns the freshness results of tables whose newest row is too old
+func (r *RunResult) StaleTables() []FreshnessResult {
+	stale := []FreshnessResult{}
+	for _, result := range r.Freshness {
+		if result.IsStale {
+			stale = append(stale, result)
+		}
+	}
+	return stale
+}
+
+//Returns the result for the table, creating it if needed, the caller must hold the lock
+func (r *RunResult) getTableResult(tableName string) *TableProfileResult {
+	result, ok := r.Tables[tableName]
+	if !ok {
+		result = &TableProfileResult{
+			TableName:       tableName,
+			ProfileRecordID: r.ProfileRecordID,
+			ProfileDate:     r.StartTime,
+			Columns:         map[string]*ColumnProfileResult{},
+			CustomColumns:   map[string]*CustomColumnProfileResult{},
+		}
+		r.Tables[tableName] = result
+	}
+	return result
+}
+
+func (r *RunResult) setRowCount(tableName string, rowCount int) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.getTableResult(tableName).RowCount = &rowCount
+}
+
+func (r *RunResult) setTableMetric(tableName string, metricName string, value float64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	result := r.getTableResult(tableName)
+	if result.Metrics == nil {
+		result.Metrics = map[string]MetricValue{}
+	}
+	result.Metrics[metricName] = NewMetricValue(value)
+}
+
+func (r *RunResult) addColumnResult(tableName string, columnName string, columnType string, profileResults []ColumnProfileData) {
+	metrics := map[string]MetricValue{}
+	for _, data := range profileResults {
+		metrics[data.name] = NewMetricValue(data.data)
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.getTableResult(tableName).Columns[columnName] = &ColumnProfileResult{
+		ColumnName: columnName,
+		ColumnType: columnType,
+		Metrics:    metrics,
+	}
+}
+
+func (r *RunResult) addCustomColumnResult(tableName string, columnName string, columnType string, columnDefinition string, value interface{}) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.getTableResult(tableName).CustomColumns[columnName] = &CustomColumnProfileResult{
+		ColumnName:       columnName,
+		ColumnType:       columnType,
+		ColumnDefinition: columnDefinition,
+		Value:            NewMetricValue(value),
+	}
+}
+
+//Marks the table schema as captured for the run, returns false if it already was
+func (r *RunResult) markSchemaCaptured(tableName string) bool {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.schemaCaptured[tableName] {
+		return false
+	}
+	r.schemaCaptured[tableName] = true
+	return true
+}
+
+func (r *RunResult) addSchemaChanges(changes []SchemaChange) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.SchemaChanges = append(r.SchemaChanges, changes...)
+}
+
+func (r *RunResult) addTableDuration(tableName string, duration time.Duration) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.TableDurationSeconds[tableName] += duration.Seconds()
+}
+
+func (r *RunResult) setEndTime(endTime time.Time) {
+	r.EndTime = endTime
+	r.DurationSeconds = endTime.Sub(r.StartTime).Seconds()
+}
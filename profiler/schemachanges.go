@@ -0,0 +1,167 @@
+package profiler
+
+import (
+	"sort"
+
+	"github.com/intxlog/profiler/db"
+)
+
+//Kinds of column change found between the schemas of two runs
+const SCHEMA_CHANGE_ADDED = `added`
+const SCHEMA_CHANGE_DROPPED = `dropped`
+const SCHEMA_CHANGE_POSSIBLE_RENAME = `possible_rename`
+const SCHEMA_CHANGE_RETYPED = `retyped`
+const SCHEMA_CHANGE_NULLABILITY = `nullability_changed`
+
+//SchemaChange is a difference in a table's columns since the previous run that profiled it.
+//Possible renames are only a hint, the dropped and added columns are always reported as well.
+type SchemaChange struct {
+	TableName               string `json:"TableName"`
+	PreviousProfileRecordID int    `json:"PreviousProfileRecordID"`
+	ChangeType              string `json:"ChangeType"`
+	ColumnName              string `json:"ColumnName"`
+	PreviousColumnName      string `json:"PreviousColumnName"`
+	ColumnType              string `json:"ColumnType"`
+	PreviousColumnType      string `json:"PreviousColumnType"`
+}
+
+//Snapshots the table's columns for the run and records any changes since the previous snapshot
+func (p *Profiler) recordTableSchema(tableName string, run *RunResult) error {
+	//there is no previous snapshot to compare against without a store
+	if !p.hasProfileStore() {
+		return nil
+	}
+
+	//a table can be both fully and custom profiled in the same run, only capture it once
+	if !run.markSchemaCaptured(tableName) {
+		return nil
+	}
+
+	columns, err := p.targetDBConn.GetTableColumns(tableName)
+	if err != nil {
+		return err
+	}
+
+	tableNameID, err := p.profileStore.RegisterTable(tableName)
+	if err != nil {
+		return err
+	}
+
+	previousColumns, previousProfileRecordID, err := p.profileStore.getPreviousTableSchema(tableNameID, run.ProfileRecordID)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		err = p.profileStore.RecordTableColumnSnapshot(tableNameID, run.ProfileRecordID, column)
+		if err != nil {
+			return err
+		}
+	}
+
+	//nothing to compare against on the first run
+	if previousProfileRecordID == 0 {
+		return nil
+	}
+
+	changes := compareTableSchemas(previousColumns, columns)
+	for idx := range changes {
+		changes[idx].TableName = tableName
+		changes[idx].PreviousProfileRecordID = previousProfileRecordID
+		err = p.profileStore.RecordSchemaChange(run.ProfileRecordID, changes[idx])
+		if err != nil {
+			return err
+		}
+	}
+
+	run.addSchemaChanges(changes)
+	return nil
+}
+
+//Compares two column lists, the table name and previous run are left for the caller to fill in
+func compareTableSchemas(previous []db.DBTableColumn, current []db.DBTableColumn) []SchemaChange {
+	previousByName := map[string]db.DBTableColumn{}
+	for _, column := range previous {
+		previousByName[column.ColumnName] = column
+	}
+	currentByName := map[string]db.DBTableColumn{}
+	for _, column := range current {
+		currentByName[column.ColumnName] = column
+	}
+
+	changes := []SchemaChange{}
+	added := []db.DBTableColumn{}
+	for _, column := range current {
+		previousColumn, ok := previousByName[column.ColumnName]
+		if !ok {
+			added = append(added, column)
+			continue
+		}
+		if previousColumn.DataType != column.DataType {
+			changes = append(changes, newSchemaChange(SCHEMA_CHANGE_RETYPED, previousColumn, column))
+		}
+		if previousColumn.IsNullable != column.IsNullable {
+			changes = append(changes, newSchemaChange(SCHEMA_CHANGE_NULLABILITY, previousColumn, column))
+		}
+	}
+
+	dropped := []db.DBTableColumn{}
+	for _, column := range previous {
+		if _, ok := currentByName[column.ColumnName]; !ok {
+			dropped = append(dropped, column)
+		}
+	}
+
+	for _, droppedColumn := range dropped {
+		changes = append(changes, newSchemaChange(SCHEMA_CHANGE_DROPPED, droppedColumn, db.DBTableColumn{}))
+	}
+	for _, addedColumn := range added {
+		changes = append(changes, newSchemaChange(SCHEMA_CHANGE_ADDED, db.DBTableColumn{}, addedColumn))
+	}
+
+	//a rename can't be told apart from a drop and an add, so only hint at one when a type
+	//has exactly one dropped and one added column
+	droppedByType := groupColumnsByType(dropped)
+	addedByType := groupColumnsByType(added)
+	for dataType, droppedColumns := range droppedByType {
+		addedColumns := addedByType[dataType]
+		if len(droppedColumns) == 1 && len(addedColumns) == 1 {
+			changes = append(changes, newSchemaChange(SCHEMA_CHANGE_POSSIBLE_RENAME, droppedColumns[0], addedColumns[0]))
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].getColumnName() == changes[j].getColumnName() {
+			return changes[i].ChangeType < changes[j].ChangeType
+		}
+		return changes[i].getColumnName() < changes[j].getColumnName()
+	})
+
+	return changes
+}
+
+func groupColumnsByType(columns []db.DBTableColumn) map[string][]db.DBTableColumn {
+	columnsByType := map[string][]db.DBTableColumn{}
+	for _, column := range columns {
+		columnsByType[column.DataType] = append(columnsByType[column.DataType], column)
+	}
+	return columnsByType
+}
+
+func newSchemaChange(changeType string, previous db.DBTableColumn, current db.DBTableColumn) SchemaChange {
+	return SchemaChange{
+		ChangeType:         changeType,
+		ColumnName:         current.ColumnName,
+		PreviousColumnName: previous.ColumnName,
+		ColumnType:         current.DataType,
+		PreviousColumnType: previous.DataType,
+	}
+}
+
+//Returns the current column name, or the previous one for dropped columns
+func (c SchemaChange) getColumnName() string {
+	if c.ColumnName == `` {
+		return c.PreviousColumnName
+	}
+	return c.ColumnName
+}
@@ -0,0 +1,90 @@
+package profiler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/intxlog/profiler/db"
+)
+
+func TestCompareTableSchemas(t *testing.T) {
+	id := db.DBTableColumn{ColumnName: `id`, DataType: `integer`}
+	email := db.DBTableColumn{ColumnName: `email`, DataType: `text`, IsNullable: true}
+
+	tests := []struct {
+		name     string
+		previous []db.DBTableColumn
+		current  []db.DBTableColumn
+		want     []SchemaChange
+	}{
+		{
+			name:     "unchanged",
+			previous: []db.DBTableColumn{id, email},
+			current:  []db.DBTableColumn{email, id},
+			want:     []SchemaChange{},
+		},
+		{
+			name:     "added column",
+			previous: []db.DBTableColumn{id},
+			current:  []db.DBTableColumn{id, email},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_ADDED, ColumnName: `email`, ColumnType: `text`},
+			},
+		},
+		{
+			name:     "dropped column",
+			previous: []db.DBTableColumn{id, email},
+			current:  []db.DBTableColumn{id},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_DROPPED, PreviousColumnName: `email`, PreviousColumnType: `text`},
+			},
+		},
+		{
+			name:     "retyped and nullability changed",
+			previous: []db.DBTableColumn{id, email},
+			current:  []db.DBTableColumn{id, {ColumnName: `email`, DataType: `character varying`}},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_NULLABILITY, ColumnName: `email`, PreviousColumnName: `email`, ColumnType: `character varying`, PreviousColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_RETYPED, ColumnName: `email`, PreviousColumnName: `email`, ColumnType: `character varying`, PreviousColumnType: `text`},
+			},
+		},
+		{
+			name:     "possible rename keeps the drop and add",
+			previous: []db.DBTableColumn{id, email},
+			current:  []db.DBTableColumn{id, {ColumnName: `email_address`, DataType: `text`}},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_DROPPED, PreviousColumnName: `email`, PreviousColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_ADDED, ColumnName: `email_address`, ColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_POSSIBLE_RENAME, ColumnName: `email_address`, PreviousColumnName: `email`, ColumnType: `text`, PreviousColumnType: `text`},
+			},
+		},
+		{
+			name:     "no rename hint across types",
+			previous: []db.DBTableColumn{id, email},
+			current:  []db.DBTableColumn{id, {ColumnName: `signup_date`, DataType: `date`}},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_DROPPED, PreviousColumnName: `email`, PreviousColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_ADDED, ColumnName: `signup_date`, ColumnType: `date`},
+			},
+		},
+		{
+			name:     "no rename hint when ambiguous",
+			previous: []db.DBTableColumn{id, email, {ColumnName: `phone`, DataType: `text`}},
+			current:  []db.DBTableColumn{id, {ColumnName: `email_address`, DataType: `text`}},
+			want: []SchemaChange{
+				{ChangeType: SCHEMA_CHANGE_DROPPED, PreviousColumnName: `email`, PreviousColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_ADDED, ColumnName: `email_address`, ColumnType: `text`},
+				{ChangeType: SCHEMA_CHANGE_DROPPED, PreviousColumnName: `phone`, PreviousColumnType: `text`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := compareTableSchemas(test.previous, test.current)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("changes = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package profiler
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Name the profiler's spans are reported under
+const TRACER_NAME = `github.com/intxlog/profiler`
+
+//Span names
+const SPAN_RUN = `profiler.run`
+const SPAN_TABLE = `profiler.table`
+const SPAN_COLUMN = `profiler.column`
+const SPAN_CUSTOM_COLUMNS = `profiler.custom_columns`
+
+//Span attribute keys
+const ATTRIBUTE_SQL = `db.query.text`
+const ATTRIBUTE_TABLE = `profiler.table`
+const ATTRIBUTE_COLUMN = `profiler.column`
+const ATTRIBUTE_COLUMN_TYPE = `profiler.column_type`
+const ATTRIBUTE_ROW_COUNT = `profiler.row_count`
+const ATTRIBUTE_DURATION = `profiler.duration_seconds`
+const ATTRIBUTE_PROFILE_RECORD_ID = `profiler.profile_record_id`
+
+//Returns the tracer from the options, falling back to the global tracer provider which does nothing unless set
+func getTracer(options ProfilerOptions) trace.Tracer {
+	provider := options.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(TRACER_NAME)
+}
+
+//A started span along with its start time so the duration can be attached when it ends
+type profilerSpan struct {
+	trace.Span
+	start time.Time
+}
+
+func (p *Profiler) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, *profilerSpan) {
+	ctx, span := p.tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+	return ctx, &profilerSpan{
+		Span:  span,
+		start: time.Now(),
+	}
+}
+
+//Ends the span with its duration, marking it as failed when there is an error
+func (s *profilerSpan) end(err error) {
+	s.SetAttributes(attribute.Float64(ATTRIBUTE_DURATION, time.Since(s.start).Seconds()))
+	if err != nil {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+	s.End()
+}
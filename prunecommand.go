@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sort"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runPrune(args []string) {
+	flags := flag.NewFlagSet(`prune`, flag.ExitOnError)
+	store := addStoreFlags(flags)
+
+	olderThan := flags.String("olderThan", "", "Remove profile runs older than this duration, such as 720h or 30d")
+	keepLatest := flags.Int("keepLatest", 0, "Keep only this many of the newest profile runs")
+	dryRun := flags.Bool("dryRun", false, "Report what would be removed without deleting anything")
+
+	flags.Parse(args)
+
+	options := profiler.PruneOptions{
+		KeepLatest: *keepLatest,
+		DryRun:     *dryRun,
+	}
+	if *olderThan != "" {
+		duration, err := profiler.ParseDuration(*olderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.OlderThan = duration
+	}
+
+	profileStore, err := store.getProfileStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := profileStore.PruneProfiles(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	action := `Removed`
+	if result.DryRun {
+		action = `Would remove`
+	}
+
+	log.Printf("%s %d profile runs\n", action, len(result.ProfileRecordIDs))
+
+	tableNames := []string{}
+	for tableName := range result.TableRowCounts {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	for _, tableName := range tableNames {
+		log.Printf("%s %d rows from %s\n", action, result.TableRowCounts[tableName], tableName)
+	}
+}
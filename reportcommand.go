@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/intxlog/profiler/profiler"
+)
+
+func runReport(args []string) {
+	flags := flag.NewFlagSet(`report`, flag.ExitOnError)
+	store := addStoreFlags(flags)
+
+	outputFile := flags.String("outputFile", "report.html", "Path to write the HTML report to")
+	since := flags.String("since", "", "Only include runs newer than this duration, such as 720h or 30d")
+	maxRuns := flags.Int("maxRuns", profiler.DEFAULT_REPORT_MAX_RUNS, "Most runs to include, newest first")
+	tables := flags.String("tables", "", "Comma separated list of tables to include, defaults to every table")
+
+	flags.Parse(args)
+
+	options := profiler.ReportOptions{
+		MaxRuns: *maxRuns,
+	}
+	if *since != "" {
+		duration, err := profiler.ParseDuration(*since)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Since = time.Now().Add(-duration)
+	}
+	for _, tableName := range strings.Split(*tables, `,`) {
+		if strings.TrimSpace(tableName) != `` {
+			options.Tables = append(options.Tables, strings.TrimSpace(tableName))
+		}
+	}
+
+	profileStore, err := store.getProfileStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := profileStore.BuildReport(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Create(*outputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	err = report.WriteHTML(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Report of %d tables over %d runs written to %s\n", len(report.Tables), len(report.Runs), *outputFile)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/intxlog/profiler/db"
+	"github.com/intxlog/profiler/profiler"
+)
+
+//Serves the latest profile results as Prometheus metrics. With a profile database the latest run
+//in the store is served, otherwise the target database is profiled on an interval.
+func runServe(args []string) {
+	flags := flag.NewFlagSet(`serve`, flag.ExitOnError)
+	targetConnDBType := flags.String("targetDBType", db.DB_CONN_POSTGRES, "Target database type")
+	targetConnString := flags.String("targetDB", "", "Target database connection string, only used without a profile database")
+
+	store := addStoreFlags(flags)
+	tracing := addTracingFlags(flags)
+	logging := addLoggingFlags(flags)
+
+	profileDefinitionPath := flags.String("profileDefinition", "", "Path to profile definition JSON file, only used without a profile database")
+	listen := flags.String("listen", ":9187", "Address to serve the /metrics endpoint on")
+	interval := flags.String("interval", "1h", "Time between profile runs without a profile database, such as 15m or 1d")
+	refresh := flags.String("refresh", "1m", "Time between reads of the latest run from the profile database")
+	notifierConfigPath := flags.String("notifierConfig", "", "Path to a notifier config JSON file of webhooks to post run failures, failed checks and anomalies to, only used without a profile database")
+
+	flags.Parse(args)
+
+	logger, err := logging.setupLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exporter := profiler.NewPrometheusExporter()
+
+	//stops the server on an interrupt so the deferred cleanup runs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *store.profileConnString != `` {
+		refreshInterval, err := parseServeInterval(*refresh)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		profileStore, err := store.getProfileStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		//the profile command does the runs, serve only exposes the newest one in the store
+		go runEvery(ctx, refreshInterval, func() {
+			result, err := profileStore.GetLatestRunResult()
+			if err != nil {
+				log.Printf("Reading the latest run failed: %v\n", err)
+			}
+			exporter.Update(result, err)
+		})
+		log.Printf("Serving the latest stored run on %s/metrics, refreshed every %v\n", *listen, refreshInterval)
+	} else {
+		runInterval, err := parseServeInterval(*interval)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		targetCon, err := db.GetDBConnByType(*targetConnDBType, *targetConnString)
+		if err != nil {
+			log.Fatal(fmt.Errorf(`error getting target database connection: %v`, err))
+		}
+
+		//read the definition once so a bad file fails on start
+		profile, err := readProfileDefinition(*profileDefinitionPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		runNotifier, err := loadNotifier(*notifierConfigPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		options := store.getProfilerOptions()
+		options.Logger = logger
+		tracerProvider, shutdownTracing, err := tracing.getTracerProvider()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if tracerProvider != nil {
+			options.TracerProvider = tracerProvider
+		}
+		//flush the spans of the last run on the way out
+		defer shutdownTracing()
+
+		p := profiler.NewProfilerWithOptions(targetCon, nil, options)
+
+		go runEvery(ctx, runInterval, func() {
+			log.Println("Starting profile...")
+			result, err := p.RunProfileWithResults(profile)
+			if err != nil {
+				log.Printf("Profile failed: %v\n", err)
+			} else {
+				log.Printf("Finished... time taken: %v\n", result.EndTime.Sub(result.StartTime))
+			}
+			exporter.Update(result, err)
+			notifyRun(runNotifier, result, err)
+		})
+		log.Printf("Serving metrics on %s/metrics every %v\n", *listen, runInterval)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(`/metrics`, exporter)
+	server := &http.Server{Addr: *listen, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	err = server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Println(err)
+	}
+}
+
+func parseServeInterval(interval string) (time.Duration, error) {
+	duration, err := profiler.ParseDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf(`intervals must be positive`)
+	}
+	return duration, nil
+}
+
+//Calls fn right away and then every interval until the context is done
+func runEvery(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
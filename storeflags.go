@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/intxlog/profiler/db"
+	"github.com/intxlog/profiler/profiler"
+)
+
+//Flags for connecting to the profile database
+type profileDBFlags struct {
+	profileConnDBType *string
+	profileConnString *string
+	storeSchema       *string
+	storeTablePrefix  *string
+}
+
+//Flags shared by every command that reads or writes the profile store
+type storeFlags struct {
+	*profileDBFlags
+	usePascalCase *bool
+	storeLayout   *string
+}
+
+func addProfileDBFlags(flags *flag.FlagSet) *profileDBFlags {
+	return &profileDBFlags{
+		profileConnDBType: flags.String("profileDBType", db.DB_CONN_POSTGRES, "Profile database type"),
+		profileConnString: flags.String("profileDB", "", "Profile store database connection string"),
+		storeSchema:       flags.String("storeSchema", "", "Schema to create the profile store tables in, defaults to the connection's current schema"),
+		storeTablePrefix:  flags.String("storeTablePrefix", "", "Prefix for the name of every profile store table"),
+	}
+}
+
+func addStoreFlags(flags *flag.FlagSet) *storeFlags {
+	return &storeFlags{
+		profileDBFlags: addProfileDBFlags(flags),
+		usePascalCase:  flags.Bool("usePascalCase", false, "Use pascal case for table and column naming in profile database"),
+		storeLayout:    flags.String("storeLayout", profiler.STORE_LAYOUT_WIDE, "Profile store layout, wide or long"),
+	}
+}
+
+func (s *profileDBFlags) getProfileDBConn() (db.DBConn, error) {
+	profileCon, err := db.GetDBConnByType(*s.profileConnDBType, *s.profileConnString)
+	if err != nil {
+		return nil, fmt.Errorf(`error getting profile database connection: %v`, err)
+	}
+	return profileCon, nil
+}
+
+//Connects to the profile database when one was given, returns nil otherwise
+func (s *profileDBFlags) getOptionalProfileDBConn() (db.DBConn, error) {
+	if *s.profileConnString == `` {
+		return nil, nil
+	}
+	return s.getProfileDBConn()
+}
+
+func (s *storeFlags) getProfilerOptions() profiler.ProfilerOptions {
+	return profiler.ProfilerOptions{
+		UsePascalCase:    *s.usePascalCase,
+		StoreLayout:      *s.storeLayout,
+		StoreSchema:      *s.storeSchema,
+		StoreTablePrefix: *s.storeTablePrefix,
+	}
+}
+
+//Connects to an existing profile store without creating or altering any of its tables,
+//used by the commands that only read or remove stored profiles
+func (s *storeFlags) getProfileStore() (*profiler.ProfileStore, error) {
+	profileCon, err := s.getProfileDBConn()
+	if err != nil {
+		return nil, err
+	}
+
+	return profiler.NewProfileStoreWithOptions(profileCon, s.getProfilerOptions()), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+//Trace exporters
+const TRACE_EXPORTER_NONE = `none`
+const TRACE_EXPORTER_OTLP = `otlp`
+const TRACE_EXPORTER_STDOUT = `stdout`
+const TRACE_EXPORTER_FILE = `file`
+
+//Flags for exporting OpenTelemetry spans of profile runs
+type tracingFlags struct {
+	exporter     *string
+	traceFile    *string
+	otlpEndpoint *string
+}
+
+func addTracingFlags(flags *flag.FlagSet) *tracingFlags {
+	return &tracingFlags{
+		exporter:     flags.String("traceExporter", TRACE_EXPORTER_NONE, "Where to export OpenTelemetry spans of the run, none, otlp, stdout (written to stderr) or file"),
+		traceFile:    flags.String("traceFile", "traces.json", "Path to write spans to with the file trace exporter"),
+		otlpEndpoint: flags.String("otlpEndpoint", "", "OTLP HTTP endpoint URL such as http://localhost:4318, defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment variable"),
+	}
+}
+
+//Builds the tracer provider for the chosen exporter, returns nil when tracing is off.
+//Call the returned shutdown function once the run is done to flush the spans.
+func (t *tracingFlags) getTracerProvider() (*sdktrace.TracerProvider, func(), error) {
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+
+	switch *t.exporter {
+	case TRACE_EXPORTER_NONE, ``:
+		return nil, func() {}, nil
+	case TRACE_EXPORTER_OTLP:
+		options := []otlptracehttp.Option{}
+		if *t.otlpEndpoint != `` {
+			options = append(options, otlptracehttp.WithEndpointURL(*t.otlpEndpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case TRACE_EXPORTER_STDOUT:
+		//spans go to stderr so they don't mix with JSON results written to stdout
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	case TRACE_EXPORTER_FILE:
+		var file *os.File
+		file, err = os.Create(*t.traceFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, nil, fmt.Errorf(`unknown trace exporter %v, expected none, otlp, stdout or file`, *t.exporter)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf(`error creating trace exporter: %v`, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String(`service.name`, `profiler`))),
+	)
+
+	shutdown := func() {
+		provider.Shutdown(context.Background())
+		if closer != nil {
+			closer.Close()
+		}
+	}
+	return provider, shutdown, nil
+}